@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/game"
+	"galapb/chess2022/pkg/pgn"
+	"galapb/chess2022/pkg/polyglot"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+func main() {
+	outPath := flag.String("out", "book.bin", "file to write the polyglot book to")
+	reportPath := flag.String("report", "", "file to write a report of the most common lines to (defaults to stdout)")
+	maxPly := flag.Int("ply", 16, "only moves up to this ply are added to the book")
+	minGames := flag.Int("min-games", 3, "moves played in fewer games are left out of the book")
+	minScore := flag.Float64("min-score", 0.0, "moves scoring less for the player making them are left out of the book (0 to 1)")
+	numLines := flag.Int("lines", 20, "number of lines in the report")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] file.pgn...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Replay all games, counting the moves of each position
+	builder := polyglot.NewBuilder(*maxPly)
+	var numGames, numSkipped int
+
+	for _, path := range flag.Args() {
+		games, skipped, err := addGames(builder, path)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %s", path, err)
+		}
+
+		log.Printf("Read %d games from %s (%d skipped)", games, path, skipped)
+		numGames += games
+		numSkipped += skipped
+	}
+
+	// Write the book
+	entries := builder.GetEntries(*minGames, *minScore)
+	if err := polyglot.WriteBookFile(*outPath, entries); err != nil {
+		log.Fatal("Failed to write book: ", err)
+	}
+	log.Printf("Wrote %d entries for %d positions to %s", len(entries), builder.GetNumPositions(), *outPath)
+
+	// Write the report
+	var report io.Writer = os.Stdout
+	if *reportPath != "" {
+		f, err := os.Create(*reportPath)
+		if err != nil {
+			log.Fatal("Failed to write report: ", err)
+		}
+		defer f.Close()
+		report = f
+	}
+
+	fmt.Fprintf(report, "Games: %d (%d skipped), max ply: %d, min games: %d, min score: %.2f\n\n", numGames, numSkipped, *maxPly, *minGames, *minScore)
+	writeReport(report, builder, *maxPly, *minGames, *minScore, *numLines)
+}
+
+/**
+Adds the games of a PGN file to the builder. Games which can't be replayed, or which have no result, are skipped
+*/
+func addGames(builder *polyglot.Builder, path string) (int, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	var numGames, numSkipped int
+	reader := pgn.NewReader(f)
+
+	for {
+		g, err := reader.Next()
+		if err == io.EOF {
+			return numGames, numSkipped, nil
+		}
+		if err != nil {
+			return numGames, numSkipped, err
+		}
+
+		var whiteScore float64
+		switch g.Result {
+		case game.WHITE_WINS:
+			whiteScore = 1
+		case game.BLACK_WINS:
+			whiteScore = 0
+		case game.GAME_DRAWN:
+			whiteScore = 0.5
+		default:
+			numSkipped += 1
+			continue
+		}
+
+		board, err := g.GetStartingBoard()
+		if err != nil {
+			numSkipped += 1
+			continue
+		}
+
+		moves, err := g.GetMoves()
+		if err != nil {
+			log.Printf("Skipping game %s - %s: %s", g.Tags["White"], g.Tags["Black"], err)
+			numSkipped += 1
+			continue
+		}
+
+		builder.AddGame(board, moves, whiteScore)
+		numGames += 1
+	}
+}
+
+type line struct {
+	moves []string
+	games int
+	score float64 // for white
+}
+
+/**
+Writes the most played lines of the book, following book moves from the standard position until the book runs out
+*/
+func writeReport(w io.Writer, builder *polyglot.Builder, maxPly, minGames int, minScore float64, numLines int) {
+	var lines []line
+	collectLines(builder, b.Standard(), nil, maxPly, minGames, minScore, &lines)
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].games > lines[j].games
+	})
+
+	if len(lines) > numLines {
+		lines = lines[:numLines]
+	}
+
+	fmt.Fprintf(w, "%6s  %6s  %s\n", "Games", "White", "Line")
+	for _, l := range lines {
+		fmt.Fprintf(w, "%6d  %5.1f%%  %s\n", l.games, 100*l.score, strings.Join(l.moves, " "))
+	}
+}
+
+func collectLines(builder *polyglot.Builder, board b.Board, moves []string, maxPly, minGames int, minScore float64, lines *[]line) {
+	for _, stats := range builder.GetMoveStats(board, minGames, minScore) {
+		move, err := polyglot.DecodeMove(board, stats.Move)
+		if err != nil || board.IsValidMove(move) != nil {
+			continue
+		}
+
+		san := pgn.ToSAN(board, move)
+		if board.GetTurn() == b.WHITE {
+			san = fmt.Sprintf("%d.%s", board.GetPly()/2+1, san)
+		}
+
+		next := board.Copy()
+		next.Make(move)
+
+		continuation := append(append([]string{}, moves...), san)
+
+		// lines also end at the ply limit of the book, since a repeated position would otherwise be followed forever
+		if next.GetPly() >= maxPly || len(builder.GetMoveStats(next, minGames, minScore)) == 0 {
+			score := stats.GetScore()
+			if board.GetTurn() == b.BLACK {
+				score = 1 - score
+			}
+			*lines = append(*lines, line{continuation, stats.Games, score})
+			continue
+		}
+
+		collectLines(builder, next, continuation, maxPly, minGames, minScore, lines)
+	}
+}
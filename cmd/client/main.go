@@ -1,21 +1,97 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"galapb/chess2022/pkg/evaluation"
 	"galapb/chess2022/pkg/game"
+	"galapb/chess2022/pkg/players/interactive_player"
+	"galapb/chess2022/pkg/players/mcts_player"
 	"galapb/chess2022/pkg/players/minimax_player"
+	neat_player "galapb/chess2022/pkg/players/neat_player/player"
 	"galapb/chess2022/pkg/players/player"
+	"galapb/chess2022/pkg/players/puct_player"
+	"galapb/chess2022/pkg/players/random_player"
 	"galapb/chess2022/pkg/time_control"
+	"log"
+	"strings"
 )
 
+const PLAYERS_USAGE string = "minimax, random, mcts, puct, human or neat:<genome file>"
+
 func main() {
+	white := flag.String("white", "minimax", "white player: "+PLAYERS_USAGE)
+	black := flag.String("black", "minimax", "black player: "+PLAYERS_USAGE)
+	minutes := flag.Uint64("minutes", 3, "minutes on the clock of each player, 0 for an untimed game")
+	depth := flag.Int("depth", 2, "depth of the searches of neat genomes evaluating positions, in untimed games")
+	skill := flag.Int("skill", minimax_player.MAX_SKILL_LEVEL, "skill level of minimax players, from 0 to 20 at full strength")
+	weightsPath := flag.String("weights", "", "file of the evaluation weights of minimax players, such as written by cmd/tune")
+	flag.Parse()
+
+	var weights *evaluation.Weights = evaluation.DefaultWeights()
+	if *weightsPath != "" {
+		var err error
+		if weights, err = evaluation.LoadWeightsFile(*weightsPath); err != nil {
+			log.Fatal("Failed to load weights: ", err)
+		}
+	}
+
 	// build the players
-	var whitePlayer player.Player = minimax_player.New()
-	var blackPlayer player.Player = minimax_player.New()
+	whitePlayer, err := newPlayer(*white, *depth, *skill, weights)
+	if err != nil {
+		log.Fatal("Failed to build the white player: ", err)
+	}
+	blackPlayer, err := newPlayer(*black, *depth, *skill, weights)
+	if err != nil {
+		log.Fatal("Failed to build the black player: ", err)
+	}
 
 	// build the game
-	var timeControl time_control.TimeControl = time_control.Builder().Minutes(3).Build()
+	var timeControl time_control.TimeControl = time_control.Builder().Minutes(*minutes).Build()
 	var g game.Game = game.New(timeControl, whitePlayer, blackPlayer).Build()
 
 	// run the game
 	g.Run()
 }
+
+/**
+Returns the player of the name. Genomes saved by the neat evaluator pick moves themselves, or evaluate positions for a
+minimax search, to the given depth in untimed games, if they have a single output. Minimax players evaluate positions
+with the given weights, and are weakened to the given skill level
+*/
+func newPlayer(name string, depth, skillLevel int, weights *evaluation.Weights) (player.Player, error) {
+	if path := strings.TrimPrefix(name, "neat:"); path != name {
+		genome, err := neat_player.ReadGenomeFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if neat_player.GetNumOutputs(genome) != neat_player.VALUE_OUTPUT_SIZE {
+			return neat_player.Load(path)
+		}
+
+		evaluator, err := neat_player.LoadNetworkEvaluator(path)
+		if err != nil {
+			return nil, err
+		}
+		mp := minimax_player.NewWithEvaluator(evaluator)
+		mp.SetMaxDepth(depth)
+		mp.SetSkillLevel(skillLevel)
+		return mp, nil
+	}
+
+	switch name {
+	case "minimax":
+		mp := minimax_player.NewWithEvaluator(evaluation.NewPositionalEvaluator(weights))
+		mp.SetSkillLevel(skillLevel)
+		return mp, nil
+	case "random":
+		return random_player.New(), nil
+	case "mcts":
+		return mcts_player.New().Build(), nil
+	case "puct":
+		return puct_player.New().Build(), nil
+	case "human":
+		return interactive_player.New(), nil
+	}
+	return nil, fmt.Errorf("unknown player %s, expected %s", name, PLAYERS_USAGE)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/players/neat_player/evaluator"
+	"galapb/chess2022/pkg/players/neat_player/trainer"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// the configuration of an experiment is written to its output directory, with copies of the params and genome files
+const CONFIG_FILE string = "config.yml"
+const PARAMS_COPY_FILE string = "params.yml"
+const GENOME_COPY_FILE string = "startgenes.yml"
+
+/**
+The effective configuration of an experiment, from which it can be reproduced
+*/
+type Config struct {
+	ParamsFile string `yaml:"params_file"`
+	GenomeFile string `yaml:"genome_file"`
+	OutDir     string `yaml:"out_dir"`
+
+	// the seed of the experiment, drawn from the time if not set
+	Seed int64 `yaml:"seed"`
+
+	Workers            int `yaml:"workers"`
+	CheckpointInterval int `yaml:"checkpoint_interval"`
+
+	// opponents in the format of evaluator.ParseOpponents, unless organisms play each other with coevolution
+	Opponents   string `yaml:"opponents"`
+	Coevolution bool   `yaml:"coevolution"`
+	Pairings    int    `yaml:"pairings"`
+	HallOfFame  int    `yaml:"hall_of_fame"`
+
+	GamesPerColor int `yaml:"games_per_color"`
+	PlyLimit      int `yaml:"ply_limit"`
+
+	// FENs of the positions games start from, or none for the standard position
+	Openings []string `yaml:"openings"`
+
+	// weights of the tiebreaks of the fitness of games against the opponents
+	MaterialTiebreak  float64 `yaml:"material_tiebreak"`
+	MoveCountTiebreak float64 `yaml:"move_count_tiebreak"`
+
+	// depth of the searches of organisms evaluating positions, 0 if the outputs of the start genome pick moves
+	SearchDepth int `yaml:"search_depth"`
+}
+
+/**
+Returns the configuration set by the flags of the arguments, and true if the experiment is resumed. A resumed experiment
+keeps the configuration recorded in its output directory and the copies of its files, only the number of workers, which
+doesn't change the results, can be changed
+*/
+func ParseConfig(flags *flag.FlagSet, args []string) (*Config, bool, error) {
+	config := &Config{}
+	flags.StringVar(&config.ParamsFile, "params", NEAT_PARAMS_FILE, "file of the NEAT parameters")
+	flags.StringVar(&config.GenomeFile, "genome", GENOME_FILE, "file of the genome the population starts from, see cmd/startgenes")
+	flags.StringVar(&config.OutDir, "out", OUT_DIR, "directory to write the results, configuration and checkpoints to")
+	flags.Int64Var(&config.Seed, "seed", 0, "seed of the experiment (drawn from the time if 0)")
+	flags.IntVar(&config.Workers, "workers", runtime.NumCPU(), "number of organisms evaluated at the same time")
+	flags.IntVar(&config.CheckpointInterval, "checkpoint", trainer.DEFAULT_CHECKPOINT_INTERVAL, "number of generations between two checkpoints, 0 to only write one when stopped")
+	flags.StringVar(&config.Opponents, "opponents", "random", "opponents with their weights, such as random:1,minimax2:0.5,champion:1")
+	flags.BoolVar(&config.Coevolution, "coevolution", false, "organisms play each other and the hall of fame instead of the opponents")
+	flags.IntVar(&config.Pairings, "pairings", 0, "number of organisms every organism plays with coevolution (all of them if 0)")
+	flags.IntVar(&config.HallOfFame, "hall-of-fame", evaluator.DEFAULT_HALL_OF_FAME_SIZE, "number of champions of past generations kept as opponents")
+	flags.IntVar(&config.GamesPerColor, "games", 2, "number of games every organism plays with each color against the opponents")
+	flags.IntVar(&config.PlyLimit, "plies", evaluator.DEFAULT_PLY_LIMIT, "games are drawn once they reach this ply")
+	flags.IntVar(&config.SearchDepth, "depth", SEARCH_DEPTH, "depth of the searches of organisms evaluating positions")
+	openings := flags.String("openings", "", "file of the FENs games start from, one per line, or \"standard\" to start from the standard position (common openings if empty)")
+	flags.Float64Var(&config.MaterialTiebreak, "material-tiebreak", evaluator.DEFAULT_MATERIAL_TIEBREAK, "weight of the tiebreak rewarding the material left at the end of games")
+	flags.Float64Var(&config.MoveCountTiebreak, "move-count-tiebreak", evaluator.DEFAULT_MOVE_COUNT_TIEBREAK, "weight of the tiebreak rewarding quick wins and slow losses")
+	resume := flags.Bool("resume", false, "resume the experiment from the checkpoint in the output directory, with its recorded configuration (only -out and -workers can be set)")
+	if err := flags.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	if !*resume {
+		var err error
+		switch *openings {
+		case "":
+			config.Openings = evaluator.DEFAULT_OPENINGS
+		case "standard":
+			config.Openings = []string{}
+		default:
+			config.Openings, err = ReadOpenings(*openings)
+		}
+		return config, false, err
+	}
+
+	outDir, workers := config.OutDir, config.Workers
+	path := filepath.Join(outDir, CONFIG_FILE)
+
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		if err == nil && f.Name != "resume" && f.Name != "out" && f.Name != "workers" {
+			err = fmt.Errorf("-%s can't be set when resuming, the experiment keeps the configuration recorded in %s", f.Name, path)
+		}
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if config, err = ReadConfig(path); err != nil {
+		return nil, false, err
+	}
+	config.OutDir = outDir
+	config.Workers = workers
+	config.ParamsFile = filepath.Join(outDir, PARAMS_COPY_FILE)
+	config.GenomeFile = filepath.Join(outDir, GENOME_COPY_FILE)
+	return config, true, nil
+}
+
+func ReadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+/**
+Writes the configuration to the output directory, with copies of its params and genome files
+*/
+func (c *Config) Write() error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(c.OutDir, CONFIG_FILE), data, 0644); err != nil {
+		return err
+	}
+
+	if err := copyFile(c.ParamsFile, filepath.Join(c.OutDir, PARAMS_COPY_FILE)); err != nil {
+		return err
+	}
+	return copyFile(c.GenomeFile, filepath.Join(c.OutDir, GENOME_COPY_FILE))
+}
+
+/**
+Reads the FENs of a file, one per line, skipping blank lines and lines starting with #
+*/
+func ReadOpenings(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var openings []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := b.FromFEN(line); err != nil {
+			return nil, fmt.Errorf("invalid FEN on line %d of %s: %w", i+1, path, err)
+		}
+		openings = append(openings, line)
+	}
+	return openings, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
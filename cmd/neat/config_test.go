@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"galapb/chess2022/pkg/players/neat_player/evaluator"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseTestConfig(args ...string) (*Config, bool, error) {
+	flags := flag.NewFlagSet("neat", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return ParseConfig(flags, args)
+}
+
+/**
+Writes an experiment to a temporary directory, with the given flags, and returns its configuration
+*/
+func writeTestExperiment(t *testing.T, args ...string) *Config {
+	dir := t.TempDir()
+	for _, name := range []string{"params.yml", "genome.yml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	args = append([]string{"-params", filepath.Join(dir, "params.yml"), "-genome", filepath.Join(dir, "genome.yml"),
+		"-out", filepath.Join(dir, "out")}, args...)
+	config, _, err := parseTestConfig(args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(config.OutDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Write(); err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestParseConfigFlags(t *testing.T) {
+	config, resume, err := parseTestConfig()
+	if err != nil || resume {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v %v", "a new experiment", resume, err)
+	}
+	if config.GamesPerColor != 2 || config.SearchDepth != SEARCH_DEPTH ||
+		!reflect.DeepEqual(config.Openings, evaluator.DEFAULT_OPENINGS) {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%+v", "the default configuration", config)
+	}
+
+	// flags override the defaults
+	config, _, err = parseTestConfig("-seed", "42", "-games", "3", "-coevolution", "-openings", "standard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Seed != 42 || config.GamesPerColor != 3 || !config.Coevolution || len(config.Openings) != 0 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%+v", "the configuration of the flags", config)
+	}
+}
+
+func TestConfigYAMLRoundTrip(t *testing.T) {
+	expected := writeTestExperiment(t, "-seed", "42", "-opponents", "random:1,minimax2:0.5", "-plies", "80")
+
+	actual, err := ReadConfig(filepath.Join(expected.OutDir, CONFIG_FILE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("\nExpected: \n%+v\nActual: \n%+v", expected, actual)
+	}
+
+	// the params and genome files are copied along
+	for _, name := range []string{PARAMS_COPY_FILE, GENOME_COPY_FILE} {
+		if _, err := os.Stat(filepath.Join(expected.OutDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResumeConfig(t *testing.T) {
+	recorded := writeTestExperiment(t, "-seed", "42", "-workers", "2")
+
+	// only the output directory and the number of workers can be set
+	config, resume, err := parseTestConfig("-resume", "-out", recorded.OutDir, "-workers", "3")
+	if err != nil || !resume {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v %v", "a resumed experiment", resume, err)
+	}
+	if config.Seed != 42 || config.Workers != 3 || config.ParamsFile != filepath.Join(recorded.OutDir, PARAMS_COPY_FILE) ||
+		config.GenomeFile != filepath.Join(recorded.OutDir, GENOME_COPY_FILE) {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%+v", "the recorded configuration", config)
+	}
+
+	for _, args := range [][]string{{"-seed", "7"}, {"-games", "3"}, {"-openings", "standard"}} {
+		_, _, err := parseTestConfig(append([]string{"-resume", "-out", recorded.OutDir}, args...)...)
+		if err == nil || !strings.Contains(err.Error(), args[0]+" can't be set") {
+			t.Fatalf("\nExpected: \n%s\nActual: \n%v", args[0]+" rejected", err)
+		}
+	}
+
+	if _, _, err := parseTestConfig("-resume", "-out", filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatalf("experiment without a configuration was resumed")
+	}
+}
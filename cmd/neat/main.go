@@ -2,8 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"galapb/chess2022/pkg/players/neat_player/evaluator"
+	neat_player "galapb/chess2022/pkg/players/neat_player/player"
+	"galapb/chess2022/pkg/players/neat_player/trainer"
 	"log"
 	"math/rand"
 	"os"
@@ -13,16 +17,23 @@ import (
 
 	"github.com/yaricom/goNEAT/v2/experiment"
 	"github.com/yaricom/goNEAT/v2/neat"
-	"github.com/yaricom/goNEAT/v2/neat/genetics"
 )
 
 const NEAT_PARAMS_FILE string = "./pkg/players/neat_player/player/config/params.yml"
 const GENOME_FILE string = "./pkg/players/neat_player/player/config/startgenes.yml"
 const OUT_DIR string = "./pkg/players/neat_player/player/data"
 
+// depth of the searches of organisms evaluating positions
+const SEARCH_DEPTH int = 2
+
 func main() {
+	config, resume, err := ParseConfig(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal("Failed to configure the experiment: ", err)
+	}
+
 	// Load Neat Options
-	params, err := os.Open(NEAT_PARAMS_FILE)
+	params, err := os.Open(config.ParamsFile)
 	if err != nil {
 		log.Fatal("Failed to open context configuration file: ", err)
 	}
@@ -33,78 +44,120 @@ func main() {
 	}
 
 	// Load Start Genome
-	genomeFile, err := os.Open(GENOME_FILE)
+	startGenome, err := neat_player.ReadGenomeFile(config.GenomeFile)
 	if err != nil {
-		log.Fatal("Failed to open genome file: ", err)
+		log.Fatal("Failed to read start genome: ", err)
 	}
 
-	r, err := genetics.NewGenomeReader(genomeFile, genetics.YAMLGenomeEncoding)
+	// The encoding of the board is the one producing as many inputs as the start genome has sensors, see cmd/startgenes
+	encoder, err := neat_player.GetInputEncoderFor(startGenome)
 	if err != nil {
-		log.Fatal("Failed to create genome reader: ", err)
+		log.Fatal("Failed to match the start genome with an encoder: ", err)
 	}
 
-	startGenome, err := r.Read()
+	// Genomes with a single output evaluate positions for a search, the others pick moves
+	var searchDepth int = 0
+	if neat_player.GetNumOutputs(startGenome) == neat_player.VALUE_OUTPUT_SIZE {
+		searchDepth = config.SearchDepth
+	}
+	config.SearchDepth = searchDepth
+
+	opponents, err := evaluator.ParseOpponents(config.Opponents)
 	if err != nil {
-		log.Fatal("Failed to read start genome: ", err)
+		log.Fatal("Failed to parse the opponents: ", err)
 	}
 
-	// Check if output dir exists
-	if _, err := os.Stat(OUT_DIR); err == nil {
-		// Backup it
-		backUpDir := fmt.Sprintf("%s-%s", OUT_DIR, time.Now().Format("2006-01-02T15_04_05"))
-		// Clear it
-		err = os.Rename(OUT_DIR, backUpDir)
+	if !resume {
+		// Check if output dir exists
+		if _, err := os.Stat(config.OutDir); err == nil {
+			// Backup it
+			backUpDir := fmt.Sprintf("%s-%s", config.OutDir, time.Now().Format("2006-01-02T15_04_05"))
+			// Clear it
+			err = os.Rename(config.OutDir, backUpDir)
+			if err != nil {
+				log.Fatal("Failed to do previous results backup: ", err)
+			}
+		}
+
+		// Create output dir
+		err = os.MkdirAll(config.OutDir, os.ModePerm)
 		if err != nil {
-			log.Fatal("Failed to do previous results backup: ", err)
+			log.Fatal("Failed to create output directory: ", err)
 		}
-	}
 
-	// Create output dir
-	err = os.MkdirAll(OUT_DIR, os.ModePerm)
-	if err != nil {
-		log.Fatal("Failed to create output directory: ", err)
+		if config.Seed == 0 {
+			config.Seed = time.Now().Unix()
+		}
+
+		if err = config.Write(); err != nil {
+			log.Fatal("Failed to write the configuration of the experiment: ", err)
+		}
 	}
 
-	// Create experiment
-	seed := time.Now().Unix()
-	rand.Seed(seed)
+	// Create experiment, whose seed is replaced by the one of the checkpoint when resuming, and whose games are seeded
+	// by the evaluator
+	rand.Seed(config.Seed)
 	expt := experiment.Experiment{
 		Id:       0,
 		Trials:   make(experiment.Trials, neatOptions.NumRuns),
-		RandSeed: seed,
+		RandSeed: config.Seed,
+	}
+
+	builder := evaluator.NewNeatPlayerGenerationEvaluator(config.OutDir).
+		Encoder(encoder).
+		SearchDepth(searchDepth).
+		Workers(config.Workers).
+		Opponents(opponents...).
+		GamesPerColor(config.GamesPerColor).
+		PlyLimit(config.PlyLimit).
+		Openings(config.Openings).
+		Tiebreaks(config.MaterialTiebreak, config.MoveCountTiebreak).
+		HallOfFame(config.HallOfFame).
+		Seed(config.Seed)
+	if config.Coevolution {
+		builder = builder.Coevolution(config.Pairings)
 	}
+	generationEvaluator := builder.Build()
 
-	var generationEvaluator experiment.GenerationEvaluator = evaluator.NewNeatPlayerGenerationEvaluator(OUT_DIR)
+	experimentTrainer := trainer.NewTrainer(config.OutDir, neatOptions, generationEvaluator).
+		CheckpointInterval(config.CheckpointInterval).
+		Build()
 
 	// Run experiment in the separate goroutine
-	errChan := make(chan error)
+	errChan := make(chan error, 1)
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
-		if err = expt.Execute(neat.NewContext(ctx, neatOptions), startGenome, generationEvaluator, nil); err != nil {
-			errChan <- err
+		if resume {
+			errChan <- experimentTrainer.Resume(ctx, &expt, startGenome)
 		} else {
-			errChan <- nil
+			errChan <- experimentTrainer.Run(ctx, &expt, startGenome)
 		}
 	}()
 
-	// Register handler to wait for termination signals
-	go func(cancel context.CancelFunc) {
+	// Register handler to wait for termination signals, until the experiment is over
+	go func() {
 		fmt.Println("\nPress Ctrl+C to stop")
 
 		signals := make(chan os.Signal, 1)
 		signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+		defer signal.Stop(signals)
+
 		select {
 		case <-signals:
 			// signal to stop test fixture
 			cancel()
-		case err = <-errChan:
+		case <-ctx.Done():
 			// stop waiting
 		}
-	}(cancel)
+	}()
 
 	// Wait for experiment completion
 	err = <-errChan
-	if err != nil {
+	cancel()
+	if errors.Is(err, context.Canceled) {
+		log.Printf("Experiment stopped, run with -resume -out %s to continue it", config.OutDir)
+		return
+	} else if err != nil {
 		// error during execution
 		log.Fatalf("Experiment execution failed: %s", err)
 	}
@@ -112,7 +165,7 @@ func main() {
 	// Print experiment results statistics
 	expt.PrintStatistics()
 
-	expResPath := fmt.Sprintf("%s/%s.dat", OUT_DIR, "results")
+	expResPath := fmt.Sprintf("%s/%s.dat", config.OutDir, "results")
 	if expResFile, err := os.Create(expResPath); err != nil {
 		log.Fatal("Failed to create file for experiment results", err)
 	} else if err = expt.Write(expResFile); err != nil {
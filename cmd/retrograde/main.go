@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"galapb/chess2022/pkg/retrograde"
+	"log"
+	"os"
+	"time"
+)
+
+func main() {
+	outDir := flag.String("out", "tables", "directory to write the tables to")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] signature...\n\nSignatures are like KQvK, KRvK or KPvK\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		log.Fatal("Failed to create output directory: ", err)
+	}
+
+	// start from the tables already solved, since they are needed for captures and promotions
+	tb, err := retrograde.LoadTablebase(*outDir)
+	if err != nil {
+		log.Fatal("Failed to load tables: ", err)
+	}
+
+	for _, signature := range flag.Args() {
+		start := time.Now()
+		if _, err := tb.Solve(signature); err != nil {
+			log.Fatalf("Failed to solve %s: %s", signature, err)
+		}
+		log.Printf("Solved %s in %s", signature, time.Since(start).Round(time.Millisecond))
+	}
+
+	if err := tb.Save(*outDir); err != nil {
+		log.Fatal("Failed to write tables: ", err)
+	}
+
+	for _, signature := range tb.GetSignatures() {
+		t, _ := tb.GetTable(signature)
+		longest, counts := t.GetStats()
+		log.Printf("%s: %d won, %d drawn, %d lost, longest mate %d plies", signature, counts[2], counts[1], counts[0], longest)
+	}
+}
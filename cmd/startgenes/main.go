@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	neat_player "galapb/chess2022/pkg/players/neat_player/player"
+	"log"
+	"os"
+)
+
+const GENOME_FILE string = "./pkg/players/neat_player/player/config/startgenes.yml"
+
+func main() {
+	outPath := flag.String("out", GENOME_FILE, "file to write the start genome to")
+	attackMaps := flag.Bool("attacks", false, "add the squares attacked by each side to the inputs of the network")
+	value := flag.Bool("value", false, "write a genome with a single output evaluating positions for a search, instead of picking moves")
+	flag.Parse()
+
+	encoder := neat_player.NewInputEncoder().AttackMaps(*attackMaps).Build()
+	numOutputs := neat_player.OUTPUT_SIZE
+	if *value {
+		numOutputs = neat_player.VALUE_OUTPUT_SIZE
+	}
+
+	out, err := os.Create(*outPath)
+	if err != nil {
+		log.Fatal("Failed to create genome file: ", err)
+	}
+	defer out.Close()
+
+	if err = neat_player.WriteStartGenome(out, encoder, numOutputs); err != nil {
+		log.Fatal("Failed to write start genome: ", err)
+	}
+
+	log.Printf("Wrote a start genome with %d inputs and %d outputs to %s", encoder.GetInputSize(), numOutputs, *outPath)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"galapb/chess2022/pkg/evaluation"
+	"galapb/chess2022/pkg/tuning"
+	"log"
+	"os"
+	"runtime"
+)
+
+const WEIGHTS_FILE string = "./pkg/evaluation/config/weights.yml"
+
+func main() {
+	dataPath := flag.String("data", "", "file of labelled positions, one fen and game result per line")
+	weightsPath := flag.String("weights", "", "weights to start tuning from (defaults to the engine's weights)")
+	outPath := flag.String("out", WEIGHTS_FILE, "file to write the tuned weights to")
+	method := flag.String("method", "local", "tuning method: local (Texel's local search) or gradient (gradient descent)")
+	iterations := flag.Int("iterations", 100, "maximum number of passes over the weights")
+	step := flag.Float64("step", 1.0, "step size of the local search, in centipawns")
+	learningRate := flag.Float64("lr", 1.0, "learning rate of the gradient descent, in centipawns")
+	k := flag.Float64("k", 0, "sigmoid scaling constant (0 to fit it to the data)")
+	workers := flag.Int("workers", runtime.NumCPU(), "number of goroutines computing the error")
+	flag.Parse()
+
+	if *dataPath == "" {
+		log.Fatal("A data file must be given with -data")
+	}
+
+	// Load positions
+	positions, err := tuning.LoadPositionsFile(*dataPath)
+	if err != nil {
+		log.Fatal("Failed to load positions: ", err)
+	}
+	log.Printf("Loaded %d positions", len(positions))
+
+	// Load starting weights
+	var weights *evaluation.Weights = evaluation.DefaultWeights()
+	if *weightsPath != "" {
+		if weights, err = evaluation.LoadWeightsFile(*weightsPath); err != nil {
+			log.Fatal("Failed to load weights: ", err)
+		}
+	}
+
+	tuner, err := tuning.New(positions, weights, *workers)
+	if err != nil {
+		log.Fatal("Failed to create the tuner: ", err)
+	}
+
+	// Fit the sigmoid to the data, before changing any weights
+	if *k > 0 {
+		tuner.SetK(*k)
+	} else {
+		log.Printf("Fitted K: %f", tuner.FitK())
+	}
+	log.Printf("Initial error: %.8f", tuner.Error())
+
+	// Save the weights after every iteration, so that an interrupted run keeps its progress
+	progress := func(iteration int, e float64) {
+		log.Printf("Iteration %d, error: %.8f", iteration, e)
+		if err := writeWeights(weights, *outPath); err != nil {
+			log.Fatal("Failed to write weights: ", err)
+		}
+	}
+
+	var finalError float64
+	switch *method {
+	case "local":
+		finalError = tuner.LocalSearch(*step, *iterations, progress)
+	case "gradient":
+		finalError = tuner.GradientDescent(*learningRate, *iterations, progress)
+	default:
+		log.Fatalf("Unknown tuning method: %s", *method)
+	}
+
+	if err := writeWeights(weights, *outPath); err != nil {
+		log.Fatal("Failed to write weights: ", err)
+	}
+
+	log.Printf("Final error: %.8f, tuned weights written to %s", finalError, *outPath)
+}
+
+func writeWeights(weights *evaluation.Weights, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return weights.Write(f)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"galapb/chess2022/pkg/evaluation"
+	"galapb/chess2022/pkg/uci"
+	"log"
+	"os"
+)
+
+func main() {
+	weightsPath := flag.String("weights", "", "file of the evaluation weights, such as written by cmd/tune (defaults to the engine's weights)")
+	flag.Parse()
+
+	engine := uci.NewEngine(os.Stdout)
+	if *weightsPath != "" {
+		weights, err := evaluation.LoadWeightsFile(*weightsPath)
+		if err != nil {
+			log.Fatal("Failed to load weights: ", err)
+		}
+		engine.SetWeights(weights)
+	}
+
+	if err := engine.Run(os.Stdin); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -2,7 +2,10 @@ module galapb/chess2022
 
 go 1.18
 
-require github.com/yaricom/goNEAT/v2 v2.9.2
+require (
+	github.com/yaricom/goNEAT/v2 v2.9.2
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
+)
 
 require (
 	github.com/pkg/errors v0.9.1 // indirect
@@ -10,5 +13,4 @@ require (
 	github.com/spf13/cast v1.4.1 // indirect
 	golang.org/x/exp v0.0.0-20191002040644-a1355ae1e2c3 // indirect
 	gonum.org/v1/gonum v0.9.3 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 )
@@ -19,3 +19,15 @@ func init() {
 func (b BitMap) ToSquare() Square {
 	return bitmapToSquare[b]
 }
+
+func (b BitMap) GetSquares() []Square {
+	var squares []Square = make([]Square, 0, NumSetBits(b))
+
+	for b != 0 {
+		lowest := b & -b // isolates the lowest set bit
+		squares = append(squares, lowest.ToSquare())
+		b &^= lowest
+	}
+
+	return squares
+}
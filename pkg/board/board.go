@@ -9,14 +9,22 @@ type Board interface {
 	IsValidMove(Move) error
 	Copy() Board
 	String() string
+	FEN() string
 	GetPieceAt(Square) (*Piece, error)
 	GetPly() int
+	GetHalfmoveClock() int
+	GetEnPassentSquare() (Square, bool)
+	CanCastle(c Color, kingside bool) bool
 	GetStatus() Status
 	GetTurn() Color
 	GetNumOf(c Color, pt PieceType) int
 	GetPieceBitmap(c Color, pt PieceType) BitMap
+	GetOccupancy(c Color) BitMap
 	IsCheckmate() bool
 	IsStalemate() bool
+	IsCheck() bool
+	IsInsufficientMaterial() bool
+	GetValidMoves() []Move
 
 	makeUnsafe(Move)
 	toggleTurn()
@@ -53,6 +61,9 @@ type board struct {
 
 	// ply
 	ply int
+
+	// number of plies since the last capture or pawn move
+	halfmoveClock int
 }
 
 func (b *board) GetPieceBitmap(color Color, pieceType PieceType) BitMap {
@@ -92,10 +103,52 @@ func (b *board) GetPieceBitmap(color Color, pieceType PieceType) BitMap {
 	panic(fmt.Sprintf("Unhandled switch case: %s, %s", color.String(), pieceType.String()))
 }
 
+/**
+Returns the squares occupied by the pieces of the given color
+*/
+func (b *board) GetOccupancy(color Color) BitMap {
+	if color == WHITE {
+		return b.whiteKingBitMap | b.whiteQueenBitMap | b.whiteBishopBitMap | b.whiteKnightBitMap | b.whiteRookBitMap | b.whitePawnBitMap
+	}
+	return b.blackKingBitMap | b.blackQueenBitMap | b.blackBishopBitMap | b.blackKnightBitMap | b.blackRookBitMap | b.blackPawnBitMap
+}
+
 func (b *board) GetPly() int {
 	return b.ply
 }
 
+func (b *board) GetHalfmoveClock() int {
+	return b.halfmoveClock
+}
+
+/**
+Returns the square a pawn may capture onto en-passent, if the last move was a double pawn push
+*/
+func (b *board) GetEnPassentSquare() (Square, bool) {
+	if b.enPassentBitMap == 0 {
+		return 0, false
+	}
+	return b.enPassentBitMap.ToSquare(), true
+}
+
+/**
+Returns true if the given color still has the right to castle on the given side of the board. This does not mean that
+castling is currently a valid move
+*/
+func (b *board) CanCastle(c Color, kingside bool) bool {
+	if c == WHITE {
+		if kingside {
+			return b.whiteKingside
+		}
+		return b.whiteQueenside
+	}
+
+	if kingside {
+		return b.blackKingside
+	}
+	return b.blackQueenSide
+}
+
 func (b *board) setPieceBitmap(color Color, pieceType PieceType, bitmap BitMap) {
 	switch color {
 	case WHITE:
@@ -208,6 +261,8 @@ func (b *board) makeUnsafe(m Move) {
 	srcSquare = m.GetSrcSquare()
 	dstSquare = m.GetDstSquare()
 
+	b.updateHalfmoveClock(srcSquare, dstSquare)
+
 	piece = b.pickUpPieceAt(srcSquare)
 
 	switch {
@@ -247,6 +302,18 @@ func (b *board) makeUnsafe(m Move) {
 	b.incrementPly()
 }
 
+func (b *board) updateHalfmoveClock(srcSquare, dstSquare Square) {
+	piece, _ := b.GetPieceAt(srcSquare)
+	_, err := b.GetPieceAt(dstSquare)
+
+	if piece.GetPieceType() == PAWN || err == nil {
+		b.halfmoveClock = 0 // pawn moves and captures reset the clock
+		return
+	}
+
+	b.halfmoveClock += 1
+}
+
 func (b *board) updateCastlingRights(p *Piece, s Square) {
 	switch p.GetPieceType() {
 	case KING:
@@ -342,6 +409,52 @@ func (b *board) IsStalemate() bool {
 	return !b.isCheck() && !b.isAnyMoveValid()
 }
 
+func (b *board) IsCheck() bool {
+	return b.isCheck()
+}
+
+func (b *board) GetValidMoves() []Move {
+	var moves []Move = make([]Move, 0)
+	var dstSquare Square
+	var move Move
+	var piece *Piece
+
+	for _, pieceType := range []PieceType{KING, QUEEN, KNIGHT, BISHOP, ROOK, PAWN} {
+		piece = GetPiece(b.GetTurn(), pieceType)
+
+		for _, srcSquare := range b.GetPieceBitmap(b.GetTurn(), pieceType).GetSquares() {
+			dstSquare = 1
+
+			for i := 0; i < 64; i++ {
+				if piece.IsValidMovement(srcSquare, dstSquare) != nil && piece.IsValidCapture(srcSquare, dstSquare) != nil {
+					// skip squares the piece can't reach, before doing the more expensive validation
+					dstSquare <<= 1
+					continue
+				}
+
+				if pieceType == PAWN && (dstSquare.GetRank() == 1 || dstSquare.GetRank() == 8) {
+					// pawns entering the 1st or 8th rank generate one move per promotion piece
+					for _, promotionPieceType := range PROMOTION_PIECE_TYPES {
+						move = NewMove(srcSquare, dstSquare).PromotionPieceType(promotionPieceType).Build()
+						if err := b.IsValidMove(move); err == nil {
+							moves = append(moves, move)
+						}
+					}
+				} else {
+					move = NewMove(srcSquare, dstSquare).Build()
+					if err := b.IsValidMove(move); err == nil {
+						moves = append(moves, move)
+					}
+				}
+
+				dstSquare <<= 1
+			}
+		}
+	}
+
+	return moves
+}
+
 func (b *board) GetNumOf(c Color, pt PieceType) int {
 	switch c {
 	case WHITE:
@@ -420,9 +533,12 @@ func (b *board) isInsufficientMaterial() bool {
 	return false
 }
 
+func (b *board) IsInsufficientMaterial() bool {
+	return b.isInsufficientMaterial()
+}
+
 func (b *board) isFiftyMoveRule() bool {
-	// TODO fifty movee rule
-	return false
+	return b.halfmoveClock >= 100
 }
 
 func (b *board) isThreefoldRepetition() bool {
@@ -865,6 +981,8 @@ func (b *board) Copy() Board {
 		turn: b.turn,
 
 		ply: b.ply,
+
+		halfmoveClock: b.halfmoveClock,
 	}
 }
 
@@ -916,5 +1034,7 @@ func Standard() Board {
 		turn: WHITE,
 
 		ply: 0,
+
+		halfmoveClock: 0,
 	}
 }
@@ -18,3 +18,47 @@ func TestBoardStringStandard(t *testing.T) {
 		t.Fatalf("\nExpected: \n%s\nActual: \n%s", STANDARD_BOARD_STRING, b.String())
 	}
 }
+
+func TestGetValidMovesStandard(t *testing.T) {
+	var b Board = Standard()
+	moves := b.GetValidMoves()
+	if len(moves) != 20 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 20, len(moves))
+	}
+}
+
+func TestGetValidMovesAfterReply(t *testing.T) {
+	var b Board = Standard()
+	b.Make(NewMove(GetSquareFromString("E2"), GetSquareFromString("E4")).Build())
+	b.Make(NewMove(GetSquareFromString("D7"), GetSquareFromString("D5")).Build())
+
+	// white gains moves for the queen, bishop and king, plus the capture on D5
+	moves := b.GetValidMoves()
+	if len(moves) != 31 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 31, len(moves))
+	}
+}
+
+func TestGetOccupancy(t *testing.T) {
+	board := Standard()
+	if occupancy := board.GetOccupancy(WHITE); occupancy != 0xFFFF000000000000 {
+		t.Fatalf("\nExpected: \n%x\nActual: \n%x", uint64(0xFFFF000000000000), occupancy)
+	}
+	if occupancy := board.GetOccupancy(BLACK); occupancy != 0xFFFF {
+		t.Fatalf("\nExpected: \n%x\nActual: \n%x", 0xFFFF, occupancy)
+	}
+}
+
+func TestSameMove(t *testing.T) {
+	e7e8 := NewMove(GetSquareFromString("E7"), GetSquareFromString("E8"))
+
+	if !SameMove(e7e8.Build(), NewMove(GetSquareFromString("E7"), GetSquareFromString("E8")).Build()) {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "same moves", "different moves")
+	}
+	if SameMove(e7e8.Build(), NewMove(GetSquareFromString("E7"), GetSquareFromString("E8")).PromotionPieceType(QUEEN).Build()) {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "different promotions", "same moves")
+	}
+	if SameMove(GetEmptyMove(), GetEmptyMove()) || SameMove(nil, e7e8.Build()) {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "empty moves never match", "same moves")
+	}
+}
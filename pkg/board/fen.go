@@ -0,0 +1,214 @@
+package board
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const STANDARD_FEN string = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+/**
+Builds a board from a position in Forsyth-Edwards Notation. The halfmove clock and fullmove number may be omitted, as in EPD
+*/
+func FromFEN(fen string) (Board, error) {
+	var fields []string = strings.Fields(fen)
+	var b *board = &board{}
+
+	if len(fields) != 4 && len(fields) != 6 {
+		return nil, fmt.Errorf("fen must have 4 or 6 fields, has %d: %s", len(fields), fen)
+	}
+
+	// piece placement, from the 8th rank down to the 1st
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return nil, fmt.Errorf("fen piece placement must have 8 ranks, has %d: %s", len(ranks), fields[0])
+	}
+
+	for row, rank := range ranks {
+		col := 0
+		for _, c := range rank {
+			if c >= '1' && c <= '8' {
+				col += int(c - '0')
+				continue
+			}
+
+			piece, err := getPieceFromFENCharacter(c)
+			if err != nil {
+				return nil, err
+			}
+
+			if col >= 8 {
+				return nil, fmt.Errorf("fen rank %d has more than 8 squares: %s", 8-row, rank)
+			}
+
+			b.placePieceAt(piece, GetSquareFromCoord(row, col))
+			col += 1
+		}
+
+		if col != 8 {
+			return nil, fmt.Errorf("fen rank %d must have 8 squares, has %d: %s", 8-row, col, rank)
+		}
+	}
+
+	if NumSetBits(b.whiteKingBitMap) != 1 || NumSetBits(b.blackKingBitMap) != 1 {
+		return nil, fmt.Errorf("fen must have exactly one king per side: %s", fields[0])
+	}
+
+	// side to move
+	switch fields[1] {
+	case "w":
+		b.turn = WHITE
+	case "b":
+		b.turn = BLACK
+	default:
+		return nil, fmt.Errorf("invalid side to move in fen: %s", fields[1])
+	}
+
+	// castling rights
+	if fields[2] != "-" {
+		for _, c := range fields[2] {
+			switch c {
+			case 'K':
+				b.whiteKingside = true
+			case 'Q':
+				b.whiteQueenside = true
+			case 'k':
+				b.blackKingside = true
+			case 'q':
+				b.blackQueenSide = true
+			default:
+				return nil, fmt.Errorf("invalid castling rights in fen: %s", fields[2])
+			}
+		}
+	}
+
+	// en-passent square
+	if fields[3] != "-" {
+		square, ok := GetSquareFromStringNotExistsOkay(strings.ToUpper(fields[3]))
+		if !ok || (square.GetRank() != 3 && square.GetRank() != 6) {
+			return nil, fmt.Errorf("invalid en-passent square in fen: %s", fields[3])
+		}
+		b.enPassentBitMap = square.ToBitMap()
+	}
+
+	// halfmove clock and fullmove number
+	var fullmoveNumber int = 1
+	if len(fields) == 6 {
+		var err error
+
+		if b.halfmoveClock, err = strconv.Atoi(fields[4]); err != nil || b.halfmoveClock < 0 {
+			return nil, fmt.Errorf("invalid halfmove clock in fen: %s", fields[4])
+		}
+
+		if fullmoveNumber, err = strconv.Atoi(fields[5]); err != nil || fullmoveNumber < 1 {
+			return nil, fmt.Errorf("invalid fullmove number in fen: %s", fields[5])
+		}
+	}
+
+	b.ply = 2 * (fullmoveNumber - 1)
+	if b.turn == BLACK {
+		b.ply += 1
+	}
+
+	return b, nil
+}
+
+func (b *board) FEN() string {
+	var fen string = ""
+
+	// piece placement
+	for row := 0; row < 8; row++ {
+		empty := 0
+		for col := 0; col < 8; col++ {
+			piece, err := b.GetPieceAt(GetSquareFromCoord(row, col))
+			if err != nil {
+				empty += 1
+				continue
+			}
+
+			if empty > 0 {
+				fen += strconv.Itoa(empty)
+				empty = 0
+			}
+			fen += piece.String()
+		}
+
+		if empty > 0 {
+			fen += strconv.Itoa(empty)
+		}
+
+		if row != 7 {
+			fen += "/"
+		}
+	}
+
+	// side to move
+	if b.turn == WHITE {
+		fen += " w "
+	} else {
+		fen += " b "
+	}
+
+	// castling rights
+	castling := ""
+	if b.whiteKingside {
+		castling += "K"
+	}
+	if b.whiteQueenside {
+		castling += "Q"
+	}
+	if b.blackKingside {
+		castling += "k"
+	}
+	if b.blackQueenSide {
+		castling += "q"
+	}
+	if castling == "" {
+		castling = "-"
+	}
+	fen += castling
+
+	// en-passent square
+	if b.enPassentBitMap != 0 {
+		fen += " " + strings.ToLower(b.enPassentBitMap.ToSquare().GetName())
+	} else {
+		fen += " -"
+	}
+
+	// halfmove clock and fullmove number
+	fen += fmt.Sprintf(" %d %d", b.halfmoveClock, b.ply/2+1)
+
+	return fen
+}
+
+func getPieceFromFENCharacter(c rune) (*Piece, error) {
+	switch c {
+	case 'K':
+		return WHITE_KING, nil
+	case 'Q':
+		return WHITE_QUEEN, nil
+	case 'B':
+		return WHITE_BISHOP, nil
+	case 'N':
+		return WHITE_KNIGHT, nil
+	case 'R':
+		return WHITE_ROOK, nil
+	case 'P':
+		return WHITE_PAWN, nil
+	case 'k':
+		return BLACK_KING, nil
+	case 'q':
+		return BLACK_QUEEN, nil
+	case 'b':
+		return BLACK_BISHOP, nil
+	case 'n':
+		return BLACK_KNIGHT, nil
+	case 'r':
+		return BLACK_ROOK, nil
+	case 'p':
+		return BLACK_PAWN, nil
+	}
+
+	return nil, fmt.Errorf("no piece associated to fen character: %c", c)
+}
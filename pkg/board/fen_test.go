@@ -0,0 +1,58 @@
+package board
+
+import "testing"
+
+func TestFENStandard(t *testing.T) {
+	var b Board = Standard()
+	if b.FEN() != STANDARD_FEN {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", STANDARD_FEN, b.FEN())
+	}
+}
+
+func TestFromFENStandard(t *testing.T) {
+	b, err := FromFEN(STANDARD_FEN)
+	if err != nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "nil-error", err)
+	}
+
+	if b.String() != STANDARD_BOARD_STRING {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", STANDARD_BOARD_STRING, b.String())
+	}
+}
+
+func TestFENAfterMoves(t *testing.T) {
+	const expected string = "rnbqkbnr/ppp1pppp/8/3p4/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2"
+
+	var b Board = Standard()
+	b.Make(NewMove(GetSquareFromString("E2"), GetSquareFromString("E4")).Build())
+	b.Make(NewMove(GetSquareFromString("D7"), GetSquareFromString("D5")).Build())
+	b.Make(NewMove(GetSquareFromString("G1"), GetSquareFromString("F3")).Build())
+
+	if b.FEN() != expected {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", expected, b.FEN())
+	}
+}
+
+func TestFromFENRoundTrip(t *testing.T) {
+	const fen string = "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"
+
+	b, err := FromFEN(fen)
+	if err != nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "nil-error", err)
+	}
+
+	if b.FEN() != fen {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", fen, b.FEN())
+	}
+
+	// the position has 48 valid moves, including both castling moves
+	if moves := b.GetValidMoves(); len(moves) != 48 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 48, len(moves))
+	}
+}
+
+func TestFromFENInvalid(t *testing.T) {
+	if _, err := FromFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1"); err == nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "non-nil-error", err)
+	}
+}
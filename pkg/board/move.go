@@ -71,6 +71,26 @@ func NewMove(srcSquare, dstSquare Square) MoveBuilder {
 	return &moveBuilder{srcSquare, dstSquare, nil}
 }
 
+/**
+Returns true if both moves are not empty, and have the same squares and promotion piece type
+*/
+func SameMove(m1, m2 Move) bool {
+	if m1 == nil || m2 == nil || m1.IsEmpty() || m2.IsEmpty() {
+		return false
+	}
+
+	if m1.GetSrcSquare() != m2.GetSrcSquare() || m1.GetDstSquare() != m2.GetDstSquare() {
+		return false
+	}
+
+	p1, p2 := m1.GetPromotionPieceType(), m2.GetPromotionPieceType()
+	if p1 == nil || p2 == nil {
+		return p1 == nil && p2 == nil
+	}
+
+	return *p1 == *p2
+}
+
 // empty move
 type emptyMove struct{}
 
@@ -83,6 +83,26 @@ func (s Square) GetFile() int {
 	return col + 1
 }
 
+/**
+Returns the index of the square counting from A1 to H8 rank by rank, so A1 is 0, H1 is 7 and H8 is 63. This is the
+index of square tables, such as the ones of Polyglot, Syzygy and the network encodings. Bitmaps count from A8 instead,
+so the bit of the square is its index xor 56
+*/
+func (s Square) GetIndex() int {
+	return 8*(s.GetRank()-1) + s.GetFile() - 1
+}
+
+/**
+Maps a square index to the index of the same square seen from the given color, flipping the ranks for black, so that
+tables written for white apply to black. The flip is the same whether indices count from A1 or from A8
+*/
+func GetRelativeIndex(c Color, i int) int {
+	if c == WHITE {
+		return i
+	}
+	return i ^ 56
+}
+
 func (s Square) ToBitMap() BitMap {
 	return squareToBitMap[s]
 }
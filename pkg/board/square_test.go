@@ -157,3 +157,24 @@ func TestSquareFile(t *testing.T) {
 		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 1, square.GetFile())
 	}
 }
+
+func TestSquareIndex(t *testing.T) {
+	for name, expected := range map[string]int{"A1": 0, "H1": 7, "A2": 8, "E4": 28, "H8": 63} {
+		square := GetSquareFromString(name)
+		if square.GetIndex() != expected {
+			t.Fatalf("\nExpected: \n%d\nActual: \n%d", expected, square.GetIndex())
+		}
+
+		// the bit of the square in a bitmap is its index xor 56
+		if square.ToBitMap() != BitMap(1)<<(expected^56) {
+			t.Fatalf("\nExpected: \n%d\nActual: \n%d", BitMap(1)<<(expected^56), square.ToBitMap())
+		}
+	}
+
+	if i := GetRelativeIndex(BLACK, GetSquareFromString("E2").GetIndex()); i != GetSquareFromString("E7").GetIndex() {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", GetSquareFromString("E7").GetIndex(), i)
+	}
+	if i := GetRelativeIndex(WHITE, 12); i != 12 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 12, i)
+	}
+}
@@ -0,0 +1,138 @@
+package evaluation
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"math/bits"
+)
+
+// Bit i of a bitmap is the square on row i / 8 (row 0 being the 8th rank) and column i % 8 (column 0 being the A file)
+
+var PIECE_TYPES [6]b.PieceType = [6]b.PieceType{b.KING, b.QUEEN, b.KNIGHT, b.BISHOP, b.ROOK, b.PAWN}
+
+var knightAttacks [64]b.BitMap
+var kingAttacks [64]b.BitMap
+var fileMasks [8]b.BitMap
+
+var ORTHOGONAL_STEPS [4][2]int = [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+var DIAGONAL_STEPS [4][2]int = [4][2]int{{-1, -1}, {-1, 1}, {1, -1}, {1, 1}}
+
+func init() {
+	knightSteps := [8][2]int{{-2, -1}, {-2, 1}, {-1, -2}, {-1, 2}, {1, -2}, {1, 2}, {2, -1}, {2, 1}}
+	kingSteps := [8][2]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+
+	for i := 0; i < 64; i++ {
+		row, col := i/8, i%8
+
+		for _, step := range knightSteps {
+			if isOnBoard(row+step[0], col+step[1]) {
+				knightAttacks[i] |= getBit(row+step[0], col+step[1])
+			}
+		}
+
+		for _, step := range kingSteps {
+			if isOnBoard(row+step[0], col+step[1]) {
+				kingAttacks[i] |= getBit(row+step[0], col+step[1])
+			}
+		}
+
+		fileMasks[col] |= getBit(row, col)
+	}
+}
+
+func isOnBoard(row, col int) bool {
+	return row >= 0 && row < 8 && col >= 0 && col < 8
+}
+
+func getBit(row, col int) b.BitMap {
+	return b.BitMap(1) << (row*8 + col)
+}
+
+func popCount(bm b.BitMap) int {
+	return bits.OnesCount64(uint64(bm))
+}
+
+/**
+Returns the index of the lowest set bit, and the bitmap with that bit cleared
+*/
+func popLowest(bm b.BitMap) (int, b.BitMap) {
+	return bits.TrailingZeros64(uint64(bm)), bm & (bm - 1)
+}
+
+func getSlidingAttacks(i int, occupancy b.BitMap, steps [4][2]int) b.BitMap {
+	var attacks b.BitMap = 0
+
+	for _, step := range steps {
+		row, col := i/8+step[0], i%8+step[1]
+		for isOnBoard(row, col) {
+			attacks |= getBit(row, col)
+			if occupancy&getBit(row, col) != 0 {
+				break // blocked by a piece
+			}
+			row, col = row+step[0], col+step[1]
+		}
+	}
+
+	return attacks
+}
+
+/**
+Returns the squares attacked by a non-pawn piece of the given type on square index i
+*/
+func getAttacks(pt b.PieceType, i int, occupancy b.BitMap) b.BitMap {
+	switch pt {
+	case b.KNIGHT:
+		return knightAttacks[i]
+	case b.KING:
+		return kingAttacks[i]
+	case b.BISHOP:
+		return getSlidingAttacks(i, occupancy, DIAGONAL_STEPS)
+	case b.ROOK:
+		return getSlidingAttacks(i, occupancy, ORTHOGONAL_STEPS)
+	case b.QUEEN:
+		return getSlidingAttacks(i, occupancy, DIAGONAL_STEPS) | getSlidingAttacks(i, occupancy, ORTHOGONAL_STEPS)
+	}
+
+	return 0
+}
+
+/**
+Returns the rank of square index i as seen by the given color (1 being the color's back rank)
+*/
+func getRelativeRank(c b.Color, i int) int {
+	if c == b.WHITE {
+		return 8 - i/8
+	}
+	return i/8 + 1
+}
+
+/**
+Returns the squares attacked by the pieces of the given color, whether they are empty or occupied
+*/
+func GetAttackedSquares(board b.Board, c b.Color) b.BitMap {
+	var attacks b.BitMap = 0
+	occupancy := board.GetOccupancy(b.WHITE) | board.GetOccupancy(b.BLACK)
+
+	for _, pt := range PIECE_TYPES {
+		if pt == b.PAWN {
+			continue
+		}
+
+		for bm := board.GetPieceBitmap(c, pt); bm != 0; {
+			var i int
+			i, bm = popLowest(bm)
+			attacks |= getAttacks(pt, i, occupancy)
+		}
+	}
+
+	return attacks | getPawnAttacks(board.GetPieceBitmap(c, b.PAWN), c)
+}
+
+/**
+Returns the squares attacked by the pawns of the given color, white pawns moving towards row 0
+*/
+func getPawnAttacks(pawns b.BitMap, c b.Color) b.BitMap {
+	if c == b.WHITE {
+		return (pawns>>9)&^fileMasks[7] | (pawns>>7)&^fileMasks[0]
+	}
+	return (pawns<<7)&^fileMasks[7] | (pawns<<9)&^fileMasks[0]
+}
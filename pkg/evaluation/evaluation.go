@@ -0,0 +1,41 @@
+package evaluation
+
+import (
+	b "galapb/chess2022/pkg/board"
+)
+
+type Evaluator interface {
+	// Returns the value of the board from white's perspective, in pawns
+	Evaluate(board b.Board) float64
+}
+
+type materialEvaluator struct{}
+
+/**
+Returns an evaluator which only counts material
+*/
+func NewMaterialEvaluator() Evaluator {
+	return &materialEvaluator{}
+}
+
+func (me *materialEvaluator) Evaluate(board b.Board) float64 {
+	if board.IsInsufficientMaterial() {
+		return 0
+	}
+
+	var h float64 = 0.0
+
+	h += 9.0 * float64(board.GetNumOf(b.WHITE, b.QUEEN))
+	h += 3.2 * float64(board.GetNumOf(b.WHITE, b.BISHOP))
+	h += 3.1 * float64(board.GetNumOf(b.WHITE, b.KNIGHT))
+	h += 5.0 * float64(board.GetNumOf(b.WHITE, b.ROOK))
+	h += 1.0 * float64(board.GetNumOf(b.WHITE, b.PAWN))
+
+	h -= 9.0 * float64(board.GetNumOf(b.BLACK, b.QUEEN))
+	h -= 3.2 * float64(board.GetNumOf(b.BLACK, b.BISHOP))
+	h -= 3.1 * float64(board.GetNumOf(b.BLACK, b.KNIGHT))
+	h -= 5.0 * float64(board.GetNumOf(b.BLACK, b.ROOK))
+	h -= 1.0 * float64(board.GetNumOf(b.BLACK, b.PAWN))
+
+	return h
+}
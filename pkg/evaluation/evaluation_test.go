@@ -0,0 +1,42 @@
+package evaluation
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"testing"
+)
+
+func TestPositionalStandardIsBalanced(t *testing.T) {
+	var e Evaluator = NewPositionalEvaluator(DefaultWeights())
+	if score := e.Evaluate(b.Standard()); score != 0 {
+		t.Fatalf("\nExpected: \n%f\nActual: \n%f", 0.0, score)
+	}
+}
+
+func TestPositionalRewardsCentralPawn(t *testing.T) {
+	var e Evaluator = NewPositionalEvaluator(DefaultWeights())
+	var board b.Board = b.Standard()
+	board.Make(b.NewMove(b.GetSquareFromString("E2"), b.GetSquareFromString("E4")).Build())
+
+	if score := e.Evaluate(board); score <= 0 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%f", "positive score", score)
+	}
+}
+
+func TestGetAttackedSquares(t *testing.T) {
+	board, _ := b.FromFEN("4k3/8/8/8/8/8/P6P/R3K3 w - - 0 1")
+	attacks := GetAttackedSquares(board, b.WHITE)
+
+	for _, name := range []string{"B3", "G3", "B1", "D1", "D2", "F1"} {
+		s := b.GetSquareFromString(name)
+		if attacks&getBit(8-s.GetRank(), s.GetFile()-1) == 0 {
+			t.Fatalf("\nExpected: \n%s attacked\nActual: \n%s", name, "not attacked")
+		}
+	}
+
+	for _, name := range []string{"A3", "H3", "G1", "H1", "E3"} {
+		s := b.GetSquareFromString(name)
+		if attacks&getBit(8-s.GetRank(), s.GetFile()-1) != 0 {
+			t.Fatalf("\nExpected: \n%s not attacked\nActual: \n%s", name, "attacked")
+		}
+	}
+}
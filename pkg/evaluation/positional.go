@@ -0,0 +1,230 @@
+package evaluation
+
+import (
+	b "galapb/chess2022/pkg/board"
+)
+
+// game phase contributed by each piece, the starting position having the maximum phase of 24
+const MAX_PHASE int = 24
+
+type positionalEvaluator struct {
+	weights *Weights
+}
+
+/**
+Returns an evaluator which combines material, piece-square tables, pawn structure, king safety, mobility, the bishop pair
+and rooks on open files, tapered between middlegame and endgame values by the game phase
+*/
+func NewPositionalEvaluator(weights *Weights) Evaluator {
+	return &positionalEvaluator{weights}
+}
+
+func (pe *positionalEvaluator) Evaluate(board b.Board) float64 {
+	if board.IsInsufficientMaterial() {
+		return 0
+	}
+
+	var mg, eg float64
+
+	whiteMg, whiteEg := pe.evaluateColor(board, b.WHITE)
+	blackMg, blackEg := pe.evaluateColor(board, b.BLACK)
+	mg = whiteMg - blackMg
+	eg = whiteEg - blackEg
+
+	phase := GetPhase(board)
+	score := (mg*float64(phase) + eg*float64(MAX_PHASE-phase)) / float64(MAX_PHASE)
+
+	return score / 100 // centipawns to pawns
+}
+
+/**
+Returns the game phase, from MAX_PHASE with all minor and major pieces on the board down to 0 with none of them
+*/
+func GetPhase(board b.Board) int {
+	var phase int = 0
+
+	for _, c := range [2]b.Color{b.WHITE, b.BLACK} {
+		phase += 1 * board.GetNumOf(c, b.KNIGHT)
+		phase += 1 * board.GetNumOf(c, b.BISHOP)
+		phase += 2 * board.GetNumOf(c, b.ROOK)
+		phase += 4 * board.GetNumOf(c, b.QUEEN)
+	}
+
+	if phase > MAX_PHASE {
+		phase = MAX_PHASE // early promotions can push the phase past its maximum
+	}
+
+	return phase
+}
+
+/**
+Returns the middlegame and endgame scores of the given color's pieces, in centipawns
+*/
+func (pe *positionalEvaluator) evaluateColor(board b.Board, c b.Color) (float64, float64) {
+	var mg, eg float64
+	var i int
+	var w *Weights = pe.weights
+
+	var ownOccupancy b.BitMap = board.GetOccupancy(c)
+	var occupancy b.BitMap = ownOccupancy | board.GetOccupancy(c.Opposite())
+	var ownPawns b.BitMap = board.GetPieceBitmap(c, b.PAWN)
+	var enemyPawns b.BitMap = board.GetPieceBitmap(c.Opposite(), b.PAWN)
+
+	var enemyKingIndex, _ = popLowest(board.GetPieceBitmap(c.Opposite(), b.KING))
+	var enemyKingZone b.BitMap = kingAttacks[enemyKingIndex]
+
+	// material, piece-square tables, mobility and attacks on the enemy king
+	for _, pt := range PIECE_TYPES {
+		bm := board.GetPieceBitmap(c, pt)
+		for bm != 0 {
+			i, bm = popLowest(bm)
+			r := b.GetRelativeIndex(c, i)
+
+			mg += w.MaterialMg.Get(pt) + w.PstMg.Get(pt)[r]
+			eg += w.MaterialEg.Get(pt) + w.PstEg.Get(pt)[r]
+
+			if pt == b.PAWN || pt == b.KING {
+				continue
+			}
+
+			attacks := getAttacks(pt, i, occupancy)
+			mobility := float64(popCount(attacks &^ ownOccupancy))
+			mg += w.MobilityMg.Get(pt) * mobility
+			eg += w.MobilityEg.Get(pt) * mobility
+
+			kingZoneAttacks := float64(popCount(attacks & enemyKingZone))
+			mg += w.KingZoneAttack.Mg * kingZoneAttacks
+			eg += w.KingZoneAttack.Eg * kingZoneAttacks
+		}
+	}
+
+	pawnMg, pawnEg := pe.evaluatePawns(c, ownPawns, enemyPawns)
+	mg += pawnMg
+	eg += pawnEg
+
+	kingMg, kingEg := pe.evaluateKingShelter(board, c, ownPawns)
+	mg += kingMg
+	eg += kingEg
+
+	if board.GetNumOf(c, b.BISHOP) >= 2 {
+		mg += w.BishopPair.Mg
+		eg += w.BishopPair.Eg
+	}
+
+	rooks := board.GetPieceBitmap(c, b.ROOK)
+	for rooks != 0 {
+		i, rooks = popLowest(rooks)
+		file := fileMasks[i%8]
+
+		if file&(ownPawns|enemyPawns) == 0 {
+			mg += w.RookOpenFile.Mg
+			eg += w.RookOpenFile.Eg
+		} else if file&ownPawns == 0 {
+			mg += w.RookSemiOpenFile.Mg
+			eg += w.RookSemiOpenFile.Eg
+		}
+	}
+
+	return mg, eg
+}
+
+/**
+Scores doubled, isolated and passed pawns
+*/
+func (pe *positionalEvaluator) evaluatePawns(c b.Color, ownPawns, enemyPawns b.BitMap) (float64, float64) {
+	var mg, eg float64
+	var i int
+	var w *Weights = pe.weights
+
+	for col := 0; col < 8; col++ {
+		if n := popCount(ownPawns & fileMasks[col]); n > 1 {
+			mg += w.DoubledPawn.Mg * float64(n-1)
+			eg += w.DoubledPawn.Eg * float64(n-1)
+		}
+	}
+
+	pawns := ownPawns
+	for pawns != 0 {
+		i, pawns = popLowest(pawns)
+		row, col := i/8, i%8
+
+		if ownPawns&getAdjacentFiles(col) == 0 {
+			mg += w.IsolatedPawn.Mg
+			eg += w.IsolatedPawn.Eg
+		}
+
+		if enemyPawns&getPassedPawnMask(c, row, col) == 0 {
+			rank := getRelativeRank(c, i)
+			mg += w.PassedPawnMg[rank-1]
+			eg += w.PassedPawnEg[rank-1]
+		}
+	}
+
+	return mg, eg
+}
+
+/**
+Scores the pawns sheltering the king, and whether the king sits on a file without own pawns
+*/
+func (pe *positionalEvaluator) evaluateKingShelter(board b.Board, c b.Color, ownPawns b.BitMap) (float64, float64) {
+	var mg, eg float64
+	var w *Weights = pe.weights
+
+	i, _ := popLowest(board.GetPieceBitmap(c, b.KING))
+	row, col := i/8, i%8
+
+	forward := -1 // white pawns shelter the king from the rows above it
+	if c == b.BLACK {
+		forward = 1
+	}
+
+	var shield b.BitMap = 0
+	for _, dRow := range [2]int{forward, 2 * forward} {
+		for dCol := -1; dCol <= 1; dCol++ {
+			if isOnBoard(row+dRow, col+dCol) {
+				shield |= getBit(row+dRow, col+dCol)
+			}
+		}
+	}
+
+	shieldPawns := float64(popCount(ownPawns & shield))
+	mg += w.KingShield.Mg * shieldPawns
+	eg += w.KingShield.Eg * shieldPawns
+
+	if ownPawns&fileMasks[col] == 0 {
+		mg += w.KingOpenFile.Mg
+		eg += w.KingOpenFile.Eg
+	}
+
+	return mg, eg
+}
+
+func getAdjacentFiles(col int) b.BitMap {
+	var mask b.BitMap = 0
+	if col > 0 {
+		mask |= fileMasks[col-1]
+	}
+	if col < 7 {
+		mask |= fileMasks[col+1]
+	}
+	return mask
+}
+
+/**
+Returns the squares in front of a pawn, on its own and adjacent files, which must be free of enemy pawns for it to be passed
+*/
+func getPassedPawnMask(c b.Color, row, col int) b.BitMap {
+	var mask b.BitMap = 0
+
+	for r := 0; r < 8; r++ {
+		if (c == b.WHITE && r < row) || (c == b.BLACK && r > row) {
+			for dCol := -1; dCol <= 1; dCol++ {
+				if isOnBoard(r, col+dCol) {
+					mask |= getBit(r, col+dCol)
+				}
+			}
+		}
+	}
+
+	return mask
+}
@@ -0,0 +1,215 @@
+package evaluation
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+
+	b "galapb/chess2022/pkg/board"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed config/weights.yml
+var defaultWeightsYAML []byte
+
+// Weights of the positional evaluation, in centipawns. Each term has a middlegame (mg) and an endgame (eg) value, which
+// are interpolated by the game phase
+type Weights struct {
+	MaterialMg PieceValues `yaml:"material_mg"`
+	MaterialEg PieceValues `yaml:"material_eg"`
+
+	// piece-square tables, from white's perspective, starting at A8 and ending at H1
+	PstMg PieceSquareTables `yaml:"pst_mg"`
+	PstEg PieceSquareTables `yaml:"pst_eg"`
+
+	// pawn structure
+	DoubledPawn  PhasedValue `yaml:"doubled_pawn"`
+	IsolatedPawn PhasedValue `yaml:"isolated_pawn"`
+	PassedPawnMg []float64   `yaml:"passed_pawn_mg"` // indexed by relative rank - 1
+	PassedPawnEg []float64   `yaml:"passed_pawn_eg"`
+
+	// king safety
+	KingShield     PhasedValue `yaml:"king_shield"`      // per own pawn in front of the king
+	KingOpenFile   PhasedValue `yaml:"king_open_file"`   // king on a file without own pawns
+	KingZoneAttack PhasedValue `yaml:"king_zone_attack"` // per enemy attack on a square next to the king
+
+	// per square a piece attacks, which is not occupied by an own piece
+	MobilityMg PieceValues `yaml:"mobility_mg"`
+	MobilityEg PieceValues `yaml:"mobility_eg"`
+
+	BishopPair       PhasedValue `yaml:"bishop_pair"`
+	RookOpenFile     PhasedValue `yaml:"rook_open_file"`
+	RookSemiOpenFile PhasedValue `yaml:"rook_semi_open_file"`
+}
+
+type PhasedValue struct {
+	Mg float64 `yaml:"mg"`
+	Eg float64 `yaml:"eg"`
+}
+
+type PieceValues struct {
+	King   float64 `yaml:"king"`
+	Queen  float64 `yaml:"queen"`
+	Knight float64 `yaml:"knight"`
+	Bishop float64 `yaml:"bishop"`
+	Rook   float64 `yaml:"rook"`
+	Pawn   float64 `yaml:"pawn"`
+}
+
+type PieceSquareTables struct {
+	King   []float64 `yaml:"king,flow"`
+	Queen  []float64 `yaml:"queen,flow"`
+	Knight []float64 `yaml:"knight,flow"`
+	Bishop []float64 `yaml:"bishop,flow"`
+	Rook   []float64 `yaml:"rook,flow"`
+	Pawn   []float64 `yaml:"pawn,flow"`
+}
+
+func (pv *PieceValues) Get(pt b.PieceType) float64 {
+	switch pt {
+	case b.KING:
+		return pv.King
+	case b.QUEEN:
+		return pv.Queen
+	case b.KNIGHT:
+		return pv.Knight
+	case b.BISHOP:
+		return pv.Bishop
+	case b.ROOK:
+		return pv.Rook
+	case b.PAWN:
+		return pv.Pawn
+	}
+
+	panic(fmt.Sprintf("Unhandled switch case: %s", pt))
+}
+
+func (pst *PieceSquareTables) Get(pt b.PieceType) []float64 {
+	switch pt {
+	case b.KING:
+		return pst.King
+	case b.QUEEN:
+		return pst.Queen
+	case b.KNIGHT:
+		return pst.Knight
+	case b.BISHOP:
+		return pst.Bishop
+	case b.ROOK:
+		return pst.Rook
+	case b.PAWN:
+		return pst.Pawn
+	}
+
+	panic(fmt.Sprintf("Unhandled switch case: %s", pt))
+}
+
+/**
+Returns the weights shipped with the engine
+*/
+func DefaultWeights() *Weights {
+	weights, err := ReadWeights(defaultWeightsYAML)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default weights: %s", err))
+	}
+
+	return weights
+}
+
+func LoadWeights(r io.Reader) (*Weights, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadWeights(data)
+}
+
+func LoadWeightsFile(path string) (*Weights, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadWeights(f)
+}
+
+func ReadWeights(data []byte) (*Weights, error) {
+	var weights Weights
+	if err := yaml.Unmarshal(data, &weights); err != nil {
+		return nil, err
+	}
+
+	if err := weights.validate(); err != nil {
+		return nil, err
+	}
+
+	return &weights, nil
+}
+
+func (w *Weights) Write(out io.Writer) error {
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(w); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+func (w *Weights) validate() error {
+	for _, pt := range PIECE_TYPES {
+		if len(w.PstMg.Get(pt)) != 64 {
+			return fmt.Errorf("middlegame piece-square table for %s must have 64 entries, has %d", pt, len(w.PstMg.Get(pt)))
+		}
+
+		if len(w.PstEg.Get(pt)) != 64 {
+			return fmt.Errorf("endgame piece-square table for %s must have 64 entries, has %d", pt, len(w.PstEg.Get(pt)))
+		}
+	}
+
+	if len(w.PassedPawnMg) != 8 || len(w.PassedPawnEg) != 8 {
+		return fmt.Errorf("passed pawn bonuses must have 8 entries, one per rank")
+	}
+
+	return nil
+}
+
+/**
+Returns pointers to every tunable weight, in a fixed order, so that tuners can treat the weights as a flat vector
+*/
+func (w *Weights) Params() []*float64 {
+	var params []*float64 = make([]*float64, 0)
+
+	// kings have no material value, and kings and pawns have no mobility term
+	for _, pv := range []*PieceValues{&w.MaterialMg, &w.MaterialEg} {
+		params = append(params, &pv.Queen, &pv.Knight, &pv.Bishop, &pv.Rook, &pv.Pawn)
+	}
+
+	for _, pv := range []*PieceValues{&w.MobilityMg, &w.MobilityEg} {
+		params = append(params, &pv.Queen, &pv.Knight, &pv.Bishop, &pv.Rook)
+	}
+
+	for _, pst := range []*PieceSquareTables{&w.PstMg, &w.PstEg} {
+		for _, pt := range PIECE_TYPES {
+			table := pst.Get(pt)
+			for i := range table {
+				params = append(params, &table[i])
+			}
+		}
+	}
+
+	for _, table := range [][]float64{w.PassedPawnMg, w.PassedPawnEg} {
+		for i := range table {
+			params = append(params, &table[i])
+		}
+	}
+
+	for _, pv := range []*PhasedValue{&w.DoubledPawn, &w.IsolatedPawn, &w.KingShield, &w.KingOpenFile, &w.KingZoneAttack, &w.BishopPair, &w.RookOpenFile, &w.RookSemiOpenFile} {
+		params = append(params, &pv.Mg, &pv.Eg)
+	}
+
+	return params
+}
@@ -6,6 +6,7 @@ import (
 	"galapb/chess2022/pkg/players/player"
 	"galapb/chess2022/pkg/time_control"
 	"log"
+	"time"
 )
 
 type Game interface {
@@ -14,6 +15,7 @@ type Game interface {
 	GetBlackPlayer() player.Player
 	GetBoard() board.Board
 	GetResult() (Result, Reason)
+	GetClock(board.Color) time.Duration
 	Run() (Result, Reason)
 }
 
@@ -21,9 +23,15 @@ type GameBuilder interface {
 	Board(board.Board) GameBuilder
 	Verbose(bool) GameBuilder
 	PlyLimit(int) GameBuilder
+	Observer(InfoObserver) GameBuilder
 	Build() Game
 }
 
+/**
+Receives the search info of the player of the given color, from the goroutine of the player
+*/
+type InfoObserver func(c board.Color, info player.SearchInfo)
+
 type game struct {
 	timeControl time_control.TimeControl
 	whitePlayer player.Player
@@ -39,6 +47,13 @@ type game struct {
 	blackQuit chan bool
 	verbose   bool
 	plyLimit  int
+
+	// receives the search info of the players which report it. Verbose games print it if there is no observer
+	observer InfoObserver
+
+	// remaining time on each player's clock, only kept if the time control is not unlimited
+	whiteClock time.Duration
+	blackClock time.Duration
 }
 
 func (g *game) GetTimeControl() time_control.TimeControl {
@@ -72,6 +87,11 @@ func (g *game) PlyLimit(plyLimit int) GameBuilder {
 	return g
 }
 
+func (g *game) Observer(observer InfoObserver) GameBuilder {
+	g.observer = observer
+	return g
+}
+
 func (g *game) Build() Game {
 	return g
 }
@@ -86,12 +106,20 @@ func (g *game) Run() (Result, Reason) {
 	g.whitePlayer.Init(g.whitePrompt, g.whiteResponse)
 	g.blackPlayer.Init(g.blackPrompt, g.blackResponse)
 
+	g.setInfoHandler(board.WHITE, g.whitePlayer)
+	g.setInfoHandler(board.BLACK, g.blackPlayer)
+
 	go g.whitePlayer.Start(b.Copy(), g.whiteQuit)
 	go g.blackPlayer.Start(b.Copy(), g.blackQuit)
 
 	var result Result
 	var reason Reason
+	var ok bool
 	var move board.Move = board.GetEmptyMove()
+
+	g.whiteClock = g.timeControl.GetDuration()
+	g.blackClock = g.timeControl.GetDuration()
+
 	for {
 		if g.verbose {
 			log.Printf("Board:\n%s", g.GetBoard().String())
@@ -109,8 +137,12 @@ func (g *game) Run() (Result, Reason) {
 			break
 		}
 
-		g.whitePrompt <- move
-		move = <-g.whiteResponse
+		if move, ok = g.requestMove(board.WHITE, move); !ok {
+			// white ran out of time
+			result = BLACK_WINS
+			reason = TIME
+			break
+		}
 
 		if err := g.GetBoard().Make(move); err != nil {
 			panic(fmt.Sprintf("invalid move by white: %s", err))
@@ -133,8 +165,12 @@ func (g *game) Run() (Result, Reason) {
 			break
 		}
 
-		g.blackPrompt <- move
-		move = <-g.blackResponse
+		if move, ok = g.requestMove(board.BLACK, move); !ok {
+			// black ran out of time
+			result = WHITE_WINS
+			reason = TIME
+			break
+		}
 
 		if err := g.GetBoard().Make(move); err != nil {
 			panic(fmt.Sprintf("invalid move by black: %s", err))
@@ -145,6 +181,10 @@ func (g *game) Run() (Result, Reason) {
 		}
 	}
 
+	// players may be thinking between prompts, so they are told to stop
+	close(g.whiteQuit)
+	close(g.blackQuit)
+
 	// print results of the game
 	if g.verbose {
 		log.Printf("%s due to %s", result, reason)
@@ -153,6 +193,79 @@ func (g *game) Run() (Result, Reason) {
 	return result, reason
 }
 
+/**
+Passes the search info of the player on to the observer, or prints it in verbose games
+*/
+func (g *game) setInfoHandler(c board.Color, p player.Player) {
+	ip, ok := p.(player.InfoPlayer)
+	if !ok {
+		return
+	}
+
+	switch {
+	case g.observer != nil:
+		ip.SetInfoHandler(func(info player.SearchInfo) {
+			g.observer(c, info)
+		})
+	case g.verbose:
+		ip.SetInfoHandler(player.NewInfoPrinter(log.Writer(), fmt.Sprintf("%s info: ", c)))
+	}
+}
+
+/**
+Sends the opponent's last move to the player of the given color and waits for their response, running their clock.
+Returns false if the player ran out of time before responding
+*/
+func (g *game) requestMove(c board.Color, move board.Move) (board.Move, bool) {
+	var p player.Player
+	var prompt, response chan board.Move
+	var clock *time.Duration
+
+	if c == board.WHITE {
+		p, prompt, response, clock = g.whitePlayer, g.whitePrompt, g.whiteResponse, &g.whiteClock
+	} else {
+		p, prompt, response, clock = g.blackPlayer, g.blackPrompt, g.blackResponse, &g.blackClock
+	}
+
+	if g.timeControl.IsUnlimited() {
+		prompt <- move
+		return <-response, true
+	}
+
+	if tp, ok := p.(player.TimedPlayer); ok {
+		tp.SetClock(time_control.Clock{Remaining: *clock, Increment: g.timeControl.GetIncrementDuration()})
+	}
+
+	start := time.Now()
+	prompt <- move
+
+	timer := time.NewTimer(*clock)
+	defer timer.Stop()
+
+	select {
+	case move = <-response:
+		*clock -= time.Since(start)
+		if *clock <= 0 {
+			return nil, false
+		}
+		*clock += g.timeControl.GetIncrementDuration()
+		return move, true
+	case <-timer.C:
+		*clock = 0
+		return nil, false
+	}
+}
+
+/**
+Returns the time left on the clock of the player of the given color
+*/
+func (g *game) GetClock(c board.Color) time.Duration {
+	if c == board.WHITE {
+		return g.whiteClock
+	}
+	return g.blackClock
+}
+
 func (g *game) GetResult() (Result, Reason) {
 	b := g.GetBoard()
 
@@ -173,7 +286,6 @@ func (g *game) GetResult() (Result, Reason) {
 		return GAME_DRAWN, THREEFOLD_REPETITION
 	}
 
-	// TODO check loss on time
 	// TODO check mutual agreement
 	// TODO check resignation
 
@@ -203,5 +315,8 @@ func New(tc time_control.TimeControl, whitePlayer player.Player, blackPlayer pla
 		blackQuit,
 		true,
 		1000000000,
+		nil,
+		tc.GetDuration(),
+		tc.GetDuration(),
 	}
 }
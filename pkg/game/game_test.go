@@ -0,0 +1,44 @@
+package game
+
+import (
+	"galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/players/minimax_player"
+	"galapb/chess2022/pkg/players/random_player"
+	"galapb/chess2022/pkg/time_control"
+	"testing"
+	"time"
+)
+
+/**
+A minimax player believing it has more time than its clock shows, so that it flags on its first move
+*/
+type slowPlayer struct {
+	*minimax_player.MiniMaxPlayer
+	done chan bool
+}
+
+func (sp *slowPlayer) SetClock(clock time_control.Clock) {
+	sp.MiniMaxPlayer.SetClock(time_control.Clock{Remaining: 3 * time.Second, MovesToGo: 1})
+}
+
+func (sp *slowPlayer) Start(b board.Board, quit chan bool) {
+	sp.MiniMaxPlayer.Start(b, quit)
+	close(sp.done)
+}
+
+func TestSlowPlayerFlagsAndStops(t *testing.T) {
+	white := &slowPlayer{minimax_player.New(), make(chan bool)}
+	var timeControl time_control.TimeControl = time_control.Builder().Seconds(1).Build()
+	g := New(timeControl, white, random_player.New()).Build()
+
+	result, reason := g.Run()
+	if result != BLACK_WINS || reason != TIME {
+		t.Fatalf("\nExpected: \n%s due to %s\nActual: \n%s due to %s", BLACK_WINS, TIME, result, reason)
+	}
+
+	select {
+	case <-white.done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "player stopped after the game", "player still running")
+	}
+}
@@ -0,0 +1,63 @@
+package network
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"math"
+)
+
+// Moves are indexed from the side to move, by source and destination square. Promotions to a queen share the index of
+// the pawn move, while underpromotions are indexed after them by file, direction and piece
+
+const UNDERPROMOTIONS_SIZE int = 8 * 3 * 3
+
+const NUM_MOVES int = 64*64 + UNDERPROMOTIONS_SIZE
+
+var UNDERPROMOTION_PIECE_TYPES [3]b.PieceType = [3]b.PieceType{b.KNIGHT, b.BISHOP, b.ROOK}
+
+/**
+Returns the index of the move, between 0 and NUM_MOVES, for the color making it
+*/
+func GetMoveIndex(move b.Move, turn b.Color) int {
+	src := b.GetRelativeIndex(turn, move.GetSrcSquare().GetIndex())
+	dst := b.GetRelativeIndex(turn, move.GetDstSquare().GetIndex())
+
+	if promotion := move.GetPromotionPieceType(); promotion != nil && *promotion != b.QUEEN {
+		for i, pt := range UNDERPROMOTION_PIECE_TYPES {
+			if pt == *promotion {
+				direction := dst%8 - src%8 + 1 // capture to the left, push, capture to the right
+				return 64*64 + ((src%8)*3+direction)*3 + i
+			}
+		}
+	}
+
+	return src*64 + dst
+}
+
+/**
+Returns the prior probability of each move, from the logits of the policy: moves which aren't valid are masked out,
+and the rest are normalized with a softmax
+*/
+func GetMovePriors(policy []float64, moves []b.Move, turn b.Color) []float64 {
+	priors := make([]float64, len(moves))
+	if len(moves) == 0 {
+		return priors
+	}
+
+	var max float64 = math.Inf(-1)
+	for i, move := range moves {
+		priors[i] = policy[GetMoveIndex(move, turn)]
+		max = math.Max(max, priors[i])
+	}
+
+	var total float64 = 0
+	for i := range priors {
+		priors[i] = math.Exp(priors[i] - max)
+		total += priors[i]
+	}
+
+	for i := range priors {
+		priors[i] /= total
+	}
+
+	return priors
+}
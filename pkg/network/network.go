@@ -0,0 +1,66 @@
+package network
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"math"
+	"math/bits"
+)
+
+// The board is always seen from the side to move: its pieces come first, and the board is flipped vertically when black
+// is to move, so that a network only has to learn to play one side
+
+// 12 planes of 64 squares, the pieces of the side to move first, in the order of PIECE_TYPES
+const PLANES_SIZE int = 12 * 64
+
+// castling rights (own kingside, own queenside, opponent kingside, opponent queenside), en-passant file, halfmove clock
+const INPUT_SIZE int = PLANES_SIZE + 4 + 8 + 1
+
+var PIECE_TYPES [6]b.PieceType = [6]b.PieceType{b.KING, b.QUEEN, b.KNIGHT, b.BISHOP, b.ROOK, b.PAWN}
+
+/**
+A network predicting, for a position, a prior over its moves (the policy) and the expected outcome (the value)
+*/
+type PolicyValueNet interface {
+	// Returns the inputs of the network for the board
+	Encode(board b.Board) []float64
+
+	// Returns the logits of every move index, and the expected outcome for the side to move, between -1 and 1
+	Predict(inputs []float64) (policy []float64, value float64)
+}
+
+/**
+Encodes the board as INPUT_SIZE values seen from the side to move: one-hot piece planes, castling rights, en-passant
+file and halfmove clock
+*/
+func EncodeBoard(board b.Board) []float64 {
+	inputs := make([]float64, INPUT_SIZE)
+	turn := board.GetTurn()
+
+	for side, c := range []b.Color{turn, turn.Opposite()} {
+		for i, pt := range PIECE_TYPES {
+			plane := (side*len(PIECE_TYPES) + i) * 64
+
+			for bm := board.GetPieceBitmap(c, pt); bm != 0; bm &= bm - 1 {
+				inputs[plane+b.GetRelativeIndex(turn, bits.TrailingZeros64(uint64(bm))^56)] = 1
+			}
+		}
+
+		inputs[PLANES_SIZE+side*2] = toFloat(board.CanCastle(c, true))
+		inputs[PLANES_SIZE+side*2+1] = toFloat(board.CanCastle(c, false))
+	}
+
+	if square, ok := board.GetEnPassentSquare(); ok {
+		inputs[PLANES_SIZE+4+square.GetFile()-1] = 1
+	}
+
+	inputs[INPUT_SIZE-1] = math.Min(float64(board.GetHalfmoveClock())/100, 1)
+
+	return inputs
+}
+
+func toFloat(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package network
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"testing"
+)
+
+func fromFEN(t *testing.T, fen string) b.Board {
+	board, err := b.FromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return board
+}
+
+// a position and its mirror with colors swapped are the same position for the side to move
+func TestEncodeBoardIsMirrored(t *testing.T) {
+	tests := [][2]string{
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "rnbqkbnr/pppp1ppp/8/4p3/8/8/PPPPPPPP/RNBQKBNR w KQkq e6 0 1"},
+		{"4k3/8/8/8/8/8/8/R3K3 w Q - 7 40", "r3k3/8/8/8/8/8/8/4K3 b q - 7 40"},
+	}
+
+	for _, test := range tests {
+		white := EncodeBoard(fromFEN(t, test[1]))
+		black := EncodeBoard(fromFEN(t, test[0]))
+
+		for i := range white {
+			if white[i] != black[i] {
+				t.Fatalf("\nFEN: %s\nExpected: \n%v\nActual: \n%v", test[0], white, black)
+			}
+		}
+	}
+}
+
+func TestGetMoveIndexIsUnique(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"1n2k3/P1P5/8/8/8/8/8/4K3 w - - 0 1",
+		"4k3/8/8/8/8/8/p1p5/1N2K3 b - - 0 1",
+	}
+
+	for _, fen := range fens {
+		board := fromFEN(t, fen)
+		indices := make(map[int]b.Move)
+
+		for _, move := range board.GetValidMoves() {
+			i := GetMoveIndex(move, board.GetTurn())
+			if i < 0 || i >= NUM_MOVES {
+				t.Fatalf("\nFEN: %s\nMove %s has index %d out of range", fen, move, i)
+			}
+			if other, ok := indices[i]; ok {
+				t.Fatalf("\nFEN: %s\nMoves %s and %s share index %d", fen, move, other, i)
+			}
+			indices[i] = move
+		}
+	}
+}
+
+func TestUniformNet(t *testing.T) {
+	net := NewUniformNet()
+	board := b.Standard()
+
+	policy, value := net.Predict(net.Encode(board))
+	if value != 0 {
+		t.Fatalf("\nExpected: \n%f\nActual: \n%f", 0.0, value)
+	}
+
+	moves := board.GetValidMoves()
+	for _, prior := range GetMovePriors(policy, moves, board.GetTurn()) {
+		if expected := 1 / float64(len(moves)); prior != expected {
+			t.Fatalf("\nExpected: \n%f\nActual: \n%f", expected, prior)
+		}
+	}
+
+	// black is a queen up, and to move
+	_, value = net.Predict(net.Encode(fromFEN(t, "3qk3/8/8/8/8/8/8/4K3 b - - 0 1")))
+	if value <= 0.9 {
+		t.Fatalf("\nExpected: \n> 0.9\nActual: \n%f", value)
+	}
+}
@@ -0,0 +1,38 @@
+package network
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"math"
+)
+
+// material advantage, in pawns, at which the value of the uniform network is about 0.76
+const MATERIAL_SCALE float64 = 4
+
+var PIECE_VALUES [6]float64 = [6]float64{0, 9.0, 3.1, 3.2, 5.0, 1.0}
+
+type uniformNet struct{}
+
+/**
+Returns a network without weights, which gives the same prior to every move and values positions by material only.
+The search it guides still plays sensibly, which makes it a baseline for trained networks
+*/
+func NewUniformNet() PolicyValueNet {
+	return &uniformNet{}
+}
+
+func (un *uniformNet) Encode(board b.Board) []float64 {
+	return EncodeBoard(board)
+}
+
+func (un *uniformNet) Predict(inputs []float64) ([]float64, float64) {
+	var material float64 = 0
+
+	for i := range PIECE_TYPES {
+		for s := 0; s < 64; s++ {
+			material += PIECE_VALUES[i] * inputs[i*64+s]
+			material -= PIECE_VALUES[i] * inputs[(len(PIECE_TYPES)+i)*64+s]
+		}
+	}
+
+	return make([]float64, NUM_MOVES), math.Tanh(material / MATERIAL_SCALE)
+}
@@ -0,0 +1,260 @@
+package pgn
+
+import (
+	"bufio"
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/game"
+	"io"
+	"os"
+	"strings"
+)
+
+/**
+A game read from a PGN file. Moves are kept in SAN, since they can only be converted to board moves by replaying the
+game from its starting position
+*/
+type Game struct {
+	Tags   map[string]string
+	Moves  []string
+	Result game.Result
+}
+
+/**
+Returns the position the game started from, which is the standard position unless the game has a FEN tag
+*/
+func (g *Game) GetStartingBoard() (b.Board, error) {
+	if fen, ok := g.Tags["FEN"]; ok {
+		return b.FromFEN(fen)
+	}
+	return b.Standard(), nil
+}
+
+/**
+Replays the game, returning its moves as board moves. Stops with an error at the first move which can't be played
+*/
+func (g *Game) GetMoves() ([]b.Move, error) {
+	board, err := g.GetStartingBoard()
+	if err != nil {
+		return nil, err
+	}
+
+	var moves []b.Move = make([]b.Move, 0, len(g.Moves))
+	for _, san := range g.Moves {
+		move, err := ParseSAN(board, san)
+		if err != nil {
+			return moves, fmt.Errorf("move %d: %s", board.GetPly()/2+1, err)
+		}
+
+		board.Make(move)
+		moves = append(moves, move)
+	}
+
+	return moves, nil
+}
+
+/**
+Reads the games of a PGN file one at a time, so that large collections don't have to fit in memory
+*/
+type Reader struct {
+	scanner *bufio.Scanner
+	line    string
+	hasLine bool
+}
+
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	return &Reader{scanner: scanner}
+}
+
+/**
+Returns the next game, or io.EOF once all games have been read
+*/
+func (r *Reader) Next() (*Game, error) {
+	var g *Game = &Game{Tags: make(map[string]string), Result: game.UNDETERMINED}
+	var movetext strings.Builder
+	var started, inMovetext bool
+
+	for r.readLine() {
+		line := strings.TrimSpace(r.line)
+
+		if strings.HasPrefix(line, "%") {
+			continue // escaped line
+		}
+
+		if strings.HasPrefix(line, "[") && !inMovetext {
+			key, value, err := parseTag(line)
+			if err != nil {
+				return nil, err
+			}
+			g.Tags[key] = value
+			started = true
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && inMovetext {
+			r.hasLine = true // the tags of the next game, kept for the next call
+			break
+		}
+
+		if line == "" {
+			continue
+		}
+
+		started, inMovetext = true, true
+		movetext.WriteString(line)
+		movetext.WriteString("\n")
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !started {
+		return nil, io.EOF
+	}
+
+	if err := parseMovetext(movetext.String(), g); err != nil {
+		return nil, err
+	}
+
+	if result, ok := g.Tags["Result"]; ok && g.Result == game.UNDETERMINED {
+		g.Result = parseResult(result)
+	}
+
+	return g, nil
+}
+
+func (r *Reader) readLine() bool {
+	if r.hasLine {
+		r.hasLine = false
+		return true
+	}
+
+	if !r.scanner.Scan() {
+		return false
+	}
+
+	r.line = r.scanner.Text()
+	return true
+}
+
+/**
+Reads all games of a PGN file
+*/
+func ReadGames(r io.Reader) ([]*Game, error) {
+	var games []*Game
+	reader := NewReader(r)
+
+	for {
+		g, err := reader.Next()
+		if err == io.EOF {
+			return games, nil
+		}
+		if err != nil {
+			return games, err
+		}
+		games = append(games, g)
+	}
+}
+
+func ReadGamesFile(path string) ([]*Game, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadGames(f)
+}
+
+func parseTag(line string) (string, string, error) {
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+	i := strings.Index(line, " ")
+	if i < 0 {
+		return "", "", fmt.Errorf("invalid pgn tag: %s", line)
+	}
+
+	return line[:i], strings.Trim(strings.TrimSpace(line[i:]), "\""), nil
+}
+
+/**
+Extracts the moves and result of the movetext, skipping move numbers, comments, variations and annotation glyphs
+*/
+func parseMovetext(movetext string, g *Game) error {
+	var depth int = 0 // nesting depth of variations
+	var token strings.Builder
+
+	flush := func() {
+		s := token.String()
+		token.Reset()
+
+		if s == "" || depth > 0 || strings.HasPrefix(s, "$") {
+			return
+		}
+
+		if s == "1-0" || s == "0-1" || s == "1/2-1/2" || s == "*" {
+			g.Result = parseResult(s)
+			return
+		}
+
+		// move numbers, e.g. "12." or "12...", possibly glued to the move
+		s = strings.TrimLeft(s, "0123456789")
+		s = strings.TrimLeft(s, ".")
+		if s == "" {
+			return
+		}
+
+		g.Moves = append(g.Moves, s)
+	}
+
+	for i := 0; i < len(movetext); i++ {
+		c := movetext[i]
+
+		switch {
+		case c == '{':
+			flush()
+			end := strings.IndexByte(movetext[i:], '}')
+			if end < 0 {
+				return fmt.Errorf("unterminated comment in movetext")
+			}
+			i += end
+		case c == ';':
+			flush()
+			end := strings.IndexByte(movetext[i:], '\n')
+			if end < 0 {
+				end = len(movetext) - i
+			}
+			i += end
+		case c == '(':
+			flush()
+			depth += 1
+		case c == ')':
+			flush()
+			if depth -= 1; depth < 0 {
+				return fmt.Errorf("unbalanced variation in movetext")
+			}
+		case c == ' ' || c == '\n' || c == '\t' || c == '\r':
+			flush()
+		default:
+			token.WriteByte(c)
+		}
+	}
+	flush()
+
+	return nil
+}
+
+func parseResult(s string) game.Result {
+	switch s {
+	case "1-0":
+		return game.WHITE_WINS
+	case "0-1":
+		return game.BLACK_WINS
+	case "1/2-1/2":
+		return game.GAME_DRAWN
+	}
+
+	return game.UNDETERMINED
+}
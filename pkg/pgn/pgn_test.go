@@ -0,0 +1,71 @@
+package pgn
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/game"
+	"strings"
+	"testing"
+)
+
+const TEST_PGN string = `[Event "Test"]
+[White "A"]
+[Black "B"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 {main line} Nc6 (2... d6 3. d4) 3. Bb5 a6 $1 4. Ba4 Nf6
+5. O-O Be7 ; closed
+6. Re1 1-0
+
+[Event "Test"]
+[Result "1/2-1/2"]
+
+1.d4 d5 2.c4 dxc4 1/2-1/2
+`
+
+func TestReadGames(t *testing.T) {
+	games, err := ReadGames(strings.NewReader(TEST_PGN))
+	if err != nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "nil-error", err)
+	}
+
+	if len(games) != 2 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 2, len(games))
+	}
+
+	expected := "e4 e5 Nf3 Nc6 Bb5 a6 Ba4 Nf6 O-O Be7 Re1"
+	if actual := strings.Join(games[0].Moves, " "); actual != expected {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", expected, actual)
+	}
+
+	if games[0].Result != game.WHITE_WINS || games[1].Result != game.GAME_DRAWN {
+		t.Fatalf("\nExpected: \n%s, %s\nActual: \n%s, %s", game.WHITE_WINS, game.GAME_DRAWN, games[0].Result, games[1].Result)
+	}
+
+	if games[0].Tags["White"] != "A" {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "A", games[0].Tags["White"])
+	}
+
+	moves, err := games[0].GetMoves()
+	if err != nil || len(moves) != 11 {
+		t.Fatalf("\nExpected: \n%d moves\nActual: \n%d moves, %s", 11, len(moves), err)
+	}
+}
+
+func TestSAN(t *testing.T) {
+	board, _ := b.FromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+
+	for _, san := range []string{"O-O", "O-O-O", "Nxd7", "Qxf6", "Bxa6", "Rb1", "Kf1", "dxe6", "gxh3", "Nb1"} {
+		move, err := ParseSAN(board, san)
+		if err != nil {
+			t.Fatalf("\nExpected: \n%s\nActual: \n%s", "nil-error", err)
+		}
+
+		if actual := ToSAN(board, move); actual != san {
+			t.Errorf("\nExpected: \n%s\nActual: \n%s", san, actual)
+		}
+	}
+
+	if _, err := ParseSAN(board, "Qd4"); err == nil {
+		t.Fatalf("expected an error for an invalid move")
+	}
+}
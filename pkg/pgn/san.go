@@ -0,0 +1,191 @@
+package pgn
+
+import (
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"strings"
+)
+
+/**
+Converts a move in Standard Algebraic Notation (e.g. "Nbd7", "exd5", "e8=Q+", "O-O") to a valid move on the board
+*/
+func ParseSAN(board b.Board, san string) (b.Move, error) {
+	s := strings.TrimRight(san, "+#!?")
+
+	// castling moves are the king moving two squares
+	if s == "O-O" || s == "0-0" || s == "O-O-O" || s == "0-0-0" {
+		rank := 1
+		if board.GetTurn() == b.BLACK {
+			rank = 8
+		}
+
+		file := 7
+		if len(s) == 5 {
+			file = 3
+		}
+
+		move := b.NewMove(b.GetSquareFromRankAndFile(rank, 5), b.GetSquareFromRankAndFile(rank, file)).Build()
+		if err := board.IsValidMove(move); err != nil {
+			return nil, fmt.Errorf("invalid castling move %s: %s", san, err)
+		}
+		return move, nil
+	}
+
+	// promotion piece, e.g. "e8=Q" or "e8Q"
+	var promotion *b.PieceType
+	if i := strings.IndexAny(s, "=QRBN"); i > 0 && i >= len(s)-2 && s[0] >= 'a' && s[0] <= 'h' {
+		pieceType, err := b.NewPieceTypeFromString(s[len(s)-1:])
+		if err != nil || !pieceType.IsValidPromotionPiece() {
+			return nil, fmt.Errorf("invalid promotion piece in move: %s", san)
+		}
+		promotion = &pieceType
+		s = strings.TrimRight(s[:i], "=")
+	}
+
+	// moving piece, pawns have no letter
+	var pieceType b.PieceType = b.PAWN
+	if len(s) > 0 && strings.ContainsRune("KQRBN", rune(s[0])) {
+		pieceType, _ = b.NewPieceTypeFromString(s[:1])
+		s = s[1:]
+	}
+
+	// destination square, and the optional file and/or rank of the source square
+	s = strings.Replace(s, "x", "", 1)
+	if len(s) < 2 || len(s) > 4 {
+		return nil, fmt.Errorf("invalid move: %s", san)
+	}
+
+	dstSquare, ok := b.GetSquareFromStringNotExistsOkay(strings.ToUpper(s[len(s)-2:]))
+	if !ok {
+		return nil, fmt.Errorf("invalid destination square in move: %s", san)
+	}
+
+	var srcFile, srcRank int = 0, 0
+	for _, c := range s[:len(s)-2] {
+		switch {
+		case c >= 'a' && c <= 'h':
+			srcFile = int(c-'a') + 1
+		case c >= '1' && c <= '8':
+			srcRank = int(c - '0')
+		default:
+			return nil, fmt.Errorf("invalid source square in move: %s", san)
+		}
+	}
+
+	// find the one piece of the given type which can make the move
+	var candidates []b.Move
+	for _, srcSquare := range board.GetPieceBitmap(board.GetTurn(), pieceType).GetSquares() {
+		if (srcFile != 0 && srcSquare.GetFile() != srcFile) || (srcRank != 0 && srcSquare.GetRank() != srcRank) {
+			continue
+		}
+
+		builder := b.NewMove(srcSquare, dstSquare)
+		if promotion != nil {
+			builder = builder.PromotionPieceType(*promotion)
+		}
+
+		if move := builder.Build(); board.IsValidMove(move) == nil {
+			candidates = append(candidates, move)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("no valid move matches: %s", san)
+	case 1:
+		return candidates[0], nil
+	}
+
+	return nil, fmt.Errorf("ambiguous move: %s", san)
+}
+
+/**
+Converts a valid move on the board to Standard Algebraic Notation, the inverse of ParseSAN
+*/
+func ToSAN(board b.Board, move b.Move) string {
+	srcSquare, dstSquare := move.GetSrcSquare(), move.GetDstSquare()
+	piece, _ := board.GetPieceAt(srcSquare)
+	_, err := board.GetPieceAt(dstSquare)
+	isCapture := err == nil
+
+	var san string
+	switch {
+	case piece.GetPieceType() == b.KING && srcSquare.DistanceSquaredTo(dstSquare) == 4:
+		san = "O-O"
+		if dstSquare.GetFile() < srcSquare.GetFile() {
+			san = "O-O-O"
+		}
+	case piece.GetPieceType() == b.PAWN:
+		if srcSquare.GetFile() != dstSquare.GetFile() {
+			san = strings.ToLower(srcSquare.GetName()[:1]) + "x" // pawns only change files when capturing
+		}
+		san += strings.ToLower(dstSquare.GetName())
+		if move.GetPromotionPieceType() != nil {
+			san += "=" + getPieceLetter(*move.GetPromotionPieceType())
+		}
+	default:
+		san = getPieceLetter(piece.GetPieceType()) + getDisambiguation(board, move, piece.GetPieceType())
+		if isCapture {
+			san += "x"
+		}
+		san += strings.ToLower(dstSquare.GetName())
+	}
+
+	after := board.Copy()
+	after.Make(move)
+	if after.IsCheckmate() {
+		san += "#"
+	} else if after.IsCheck() {
+		san += "+"
+	}
+
+	return san
+}
+
+/**
+Returns the file and/or rank needed to tell the moving piece apart from other pieces of the same type which can also
+move to the destination square
+*/
+func getDisambiguation(board b.Board, move b.Move, pieceType b.PieceType) string {
+	srcSquare := move.GetSrcSquare()
+	var sameFile, sameRank, ambiguous bool
+
+	for _, other := range board.GetPieceBitmap(board.GetTurn(), pieceType).GetSquares() {
+		if other == srcSquare || board.IsValidMove(b.NewMove(other, move.GetDstSquare()).Build()) != nil {
+			continue
+		}
+
+		ambiguous = true
+		sameFile = sameFile || other.GetFile() == srcSquare.GetFile()
+		sameRank = sameRank || other.GetRank() == srcSquare.GetRank()
+	}
+
+	name := strings.ToLower(srcSquare.GetName())
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return name[:1]
+	case !sameRank:
+		return name[1:]
+	}
+
+	return name
+}
+
+func getPieceLetter(pt b.PieceType) string {
+	switch pt {
+	case b.KING:
+		return "K"
+	case b.QUEEN:
+		return "Q"
+	case b.KNIGHT:
+		return "N"
+	case b.BISHOP:
+		return "B"
+	case b.ROOK:
+		return "R"
+	}
+
+	return ""
+}
@@ -0,0 +1,123 @@
+package book_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/players/player"
+	"galapb/chess2022/pkg/polyglot"
+	"galapb/chess2022/pkg/time_control"
+)
+
+type BookPlayerBuilder interface {
+	Selection(polyglot.Selection) BookPlayerBuilder
+	MaxDepth(int) BookPlayerBuilder
+	Build() *BookPlayer
+}
+
+/**
+Plays moves from a Polyglot opening book for as long as the position is in the book, and delegates to another player
+once it is out of book. The other player is only started when the book runs out, from the position reached
+*/
+type BookPlayer struct {
+	prompt   chan b.Move
+	response chan b.Move
+
+	book      *polyglot.Book
+	selection polyglot.Selection
+
+	// book moves are only played up to this ply
+	maxDepth int
+
+	fallback         player.Player
+	fallbackPrompt   chan b.Move
+	fallbackResponse chan b.Move
+	fallbackQuit     chan bool
+	inBook           bool
+}
+
+func New(book *polyglot.Book, fallback player.Player) BookPlayerBuilder {
+	return &BookPlayer{
+		book:             book,
+		selection:        polyglot.WEIGHTED_RANDOM,
+		maxDepth:         1000000000,
+		fallback:         fallback,
+		fallbackPrompt:   make(chan b.Move, 1),
+		fallbackResponse: make(chan b.Move, 1),
+		fallbackQuit:     make(chan bool),
+		inBook:           true,
+	}
+}
+
+func (bp *BookPlayer) Selection(selection polyglot.Selection) BookPlayerBuilder {
+	bp.selection = selection
+	return bp
+}
+
+func (bp *BookPlayer) MaxDepth(maxDepth int) BookPlayerBuilder {
+	bp.maxDepth = maxDepth
+	return bp
+}
+
+func (bp *BookPlayer) Build() *BookPlayer {
+	return bp
+}
+
+/**
+Passes the clock on to the fallback player, if it wants to know about it. Book moves are played instantly
+*/
+func (bp *BookPlayer) SetClock(clock time_control.Clock) {
+	if tp, ok := bp.fallback.(player.TimedPlayer); ok {
+		tp.SetClock(clock)
+	}
+}
+
+func (bp *BookPlayer) Init(prompt chan b.Move, response chan b.Move) {
+	bp.prompt = prompt
+	bp.response = response
+}
+
+func (bp *BookPlayer) Start(board b.Board, quit chan bool) {
+	var err error
+
+	for {
+		select {
+		case <-quit:
+			close(bp.fallbackQuit)
+			return
+		case move := <-bp.prompt:
+			if bp.inBook {
+				previous := board.Copy()
+				if err = board.Make(move); err != nil {
+					panic(err)
+				}
+
+				if response, ok := bp.getBookMove(board); ok {
+					board.Make(response)
+					bp.response <- response
+					continue
+				}
+
+				// out of book, the fallback player takes over from the position before the opponent's move
+				bp.inBook = false
+				bp.fallback.Init(bp.fallbackPrompt, bp.fallbackResponse)
+				go bp.fallback.Start(previous, bp.fallbackQuit)
+			}
+
+			bp.fallbackPrompt <- move
+			select {
+			case response := <-bp.fallbackResponse:
+				bp.response <- response
+			case <-quit:
+				close(bp.fallbackQuit)
+				return
+			}
+		}
+	}
+}
+
+func (bp *BookPlayer) getBookMove(board b.Board) (b.Move, bool) {
+	if board.GetPly() >= bp.maxDepth {
+		return nil, false
+	}
+
+	return bp.book.Pick(board, bp.selection)
+}
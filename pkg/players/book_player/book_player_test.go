@@ -0,0 +1,150 @@
+package book_player
+
+import (
+	"bytes"
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/polyglot"
+	"strings"
+	"testing"
+)
+
+func newTestMove(s string) b.Move {
+	s = strings.ToUpper(s)
+	return b.NewMove(b.GetSquareFromString(s[0:2]), b.GetSquareFromString(s[2:4])).Build()
+}
+
+/**
+Returns a book of the given line, playing every move of the line in the position reached by the previous ones
+*/
+func newTestBook(t *testing.T, line ...string) *polyglot.Book {
+	var entries []polyglot.Entry
+	var board b.Board = b.Standard()
+
+	for _, s := range line {
+		move := newTestMove(s)
+		entries = append(entries, polyglot.Entry{Key: polyglot.Hash(board), Move: polyglot.EncodeMove(board, move), Weight: 1})
+		if err := board.Make(move); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := polyglot.WriteBook(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	book, err := polyglot.LoadBook(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return book
+}
+
+/**
+Plays the first valid move of every position, and remembers the position it was started from
+*/
+type firstMovePlayer struct {
+	prompt   chan b.Move
+	response chan b.Move
+
+	start   chan string
+	stopped chan bool
+}
+
+func (fp *firstMovePlayer) Init(prompt chan b.Move, response chan b.Move) {
+	fp.prompt = prompt
+	fp.response = response
+}
+
+func (fp *firstMovePlayer) Start(board b.Board, quit chan bool) {
+	fp.start <- board.FEN()
+	defer close(fp.stopped)
+
+	for {
+		select {
+		case <-quit:
+			return
+		case move := <-fp.prompt:
+			board.Make(move)
+			response := board.GetValidMoves()[0]
+			board.Make(response)
+			fp.response <- response
+		}
+	}
+}
+
+func TestBookHandsOffToFallback(t *testing.T) {
+	book := newTestBook(t, "e2e4", "e7e5", "g1f3")
+	fallback := &firstMovePlayer{start: make(chan string, 1), stopped: make(chan bool)}
+
+	bp := New(book, fallback).Selection(polyglot.BEST_MOVE).Build()
+	prompt, response, quit := make(chan b.Move, 1), make(chan b.Move, 1), make(chan bool)
+	bp.Init(prompt, response)
+	go bp.Start(b.Standard(), quit)
+
+	// in book: the book moves are played
+	board := b.Standard()
+	for _, expected := range []string{"e2e4", "g1f3"} {
+		var opponentMove b.Move = b.GetEmptyMove()
+		if board.GetPly() > 0 {
+			opponentMove = newTestMove("e7e5")
+			board.Make(opponentMove)
+		}
+
+		prompt <- opponentMove
+		move := <-response
+		if move.String() != newTestMove(expected).String() {
+			t.Fatalf("\nExpected: \n%s\nActual: \n%s", expected, move)
+		}
+		board.Make(move)
+	}
+
+	// out of book: the fallback starts from the position before the opponent's move, and is prompted with it
+	expectedStart := board.FEN()
+	prompt <- newTestMove("b8c6")
+	board.Make(newTestMove("b8c6"))
+	move := <-response
+
+	if start := <-fallback.start; start != expectedStart {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", expectedStart, start)
+	}
+	if expected := board.GetValidMoves()[0]; move.String() != expected.String() {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", expected, move)
+	}
+
+	// the fallback keeps playing, and is stopped with the book player
+	board.Make(move)
+	prompt <- board.GetValidMoves()[0]
+	<-response
+
+	close(quit)
+	<-fallback.stopped
+}
+
+func TestBookMaxDepth(t *testing.T) {
+	book := newTestBook(t, "e2e4", "e7e5", "g1f3")
+	fallback := &firstMovePlayer{start: make(chan string, 1), stopped: make(chan bool)}
+
+	bp := New(book, fallback).Selection(polyglot.BEST_MOVE).MaxDepth(1).Build()
+	prompt, response, quit := make(chan b.Move, 1), make(chan b.Move, 1), make(chan bool)
+	bp.Init(prompt, response)
+	go bp.Start(b.Standard(), quit)
+
+	prompt <- b.GetEmptyMove()
+	if move := <-response; move.String() != newTestMove("e2e4").String() {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "e2e4", move)
+	}
+
+	// the book still has a move, but the player is past its depth
+	board := b.Standard()
+	board.Make(newTestMove("e2e4"))
+	expectedStart := board.FEN()
+
+	prompt <- newTestMove("e7e5")
+	<-response
+	if start := <-fallback.start; start != expectedStart {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", expectedStart, start)
+	}
+
+	close(quit)
+	<-fallback.stopped
+}
@@ -29,8 +29,7 @@ func (ip *InteractivePlayer) Start(board b.Board, quit chan bool) {
 		select {
 		case <-quit:
 			return
-		default:
-			move := <-ip.prompt
+		case move := <-ip.prompt:
 			if err = board.Make(move); err != nil {
 				panic(err)
 			}
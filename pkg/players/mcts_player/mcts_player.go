@@ -0,0 +1,259 @@
+package mcts_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/evaluation"
+	"galapb/chess2022/pkg/time_control"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// exploration constant of UCB1, for which it is proven to converge with scores between 0 and 1
+	DEFAULT_EXPLORATION float64 = math.Sqrt2
+
+	DEFAULT_ITERATIONS    int = 200
+	DEFAULT_PLAYOUT_DEPTH int = 16
+
+	// evaluation, in pawns, at which white is expected to score about 91%
+	EVALUATION_SCALE float64 = 4
+)
+
+type MCTSPlayerBuilder interface {
+	Exploration(float64) MCTSPlayerBuilder
+	Policy(PlayoutPolicy) MCTSPlayerBuilder
+	Evaluator(evaluation.Evaluator) MCTSPlayerBuilder
+	PlayoutDepth(int) MCTSPlayerBuilder
+	Iterations(int) MCTSPlayerBuilder
+	MoveTime(time.Duration) MCTSPlayerBuilder
+	TreeReuse(bool) MCTSPlayerBuilder
+	Workers(int) MCTSPlayerBuilder
+	Build() *MCTSPlayer
+}
+
+/**
+Searches with Monte Carlo Tree Search: the tree grows one node per iteration towards the moves with the best upper
+confidence bound (UCT), and every new node is scored by playing the game out. The most visited move is played
+*/
+type MCTSPlayer struct {
+	prompt   chan b.Move
+	response chan b.Move
+
+	exploration float64
+	policy      PlayoutPolicy
+
+	// scores playouts cut at the playout depth
+	evaluator    evaluation.Evaluator
+	playoutDepth int
+
+	// the search stops after this many iterations, unless it is bounded by time
+	iterations int
+	moveTime   time.Duration
+	clock      *time_control.Clock
+
+	// the subtree of the position reached is kept between moves
+	treeReuse bool
+	root      *node
+
+	// number of playouts run in parallel from every new node
+	workers int
+}
+
+func New() MCTSPlayerBuilder {
+	return &MCTSPlayer{
+		exploration:  DEFAULT_EXPLORATION,
+		policy:       NewRandomPolicy(),
+		evaluator:    evaluation.NewPositionalEvaluator(evaluation.DefaultWeights()),
+		playoutDepth: DEFAULT_PLAYOUT_DEPTH,
+		iterations:   DEFAULT_ITERATIONS,
+		treeReuse:    true,
+		workers:      1,
+	}
+}
+
+func (mp *MCTSPlayer) Exploration(exploration float64) MCTSPlayerBuilder {
+	mp.exploration = exploration
+	return mp
+}
+
+func (mp *MCTSPlayer) Policy(policy PlayoutPolicy) MCTSPlayerBuilder {
+	mp.policy = policy
+	return mp
+}
+
+func (mp *MCTSPlayer) Evaluator(evaluator evaluation.Evaluator) MCTSPlayerBuilder {
+	mp.evaluator = evaluator
+	return mp
+}
+
+func (mp *MCTSPlayer) PlayoutDepth(playoutDepth int) MCTSPlayerBuilder {
+	mp.playoutDepth = playoutDepth
+	return mp
+}
+
+func (mp *MCTSPlayer) Iterations(iterations int) MCTSPlayerBuilder {
+	mp.iterations = iterations
+	return mp
+}
+
+/**
+Bounds every search by time instead of iterations, in untimed games
+*/
+func (mp *MCTSPlayer) MoveTime(moveTime time.Duration) MCTSPlayerBuilder {
+	mp.moveTime = moveTime
+	return mp
+}
+
+func (mp *MCTSPlayer) TreeReuse(treeReuse bool) MCTSPlayerBuilder {
+	mp.treeReuse = treeReuse
+	return mp
+}
+
+func (mp *MCTSPlayer) Workers(workers int) MCTSPlayerBuilder {
+	if workers < 1 {
+		workers = 1
+	}
+	mp.workers = workers
+	return mp
+}
+
+func (mp *MCTSPlayer) Build() *MCTSPlayer {
+	return mp
+}
+
+func (mp *MCTSPlayer) SetClock(clock time_control.Clock) {
+	mp.clock = &clock
+}
+
+func (mp *MCTSPlayer) Init(prompt chan b.Move, response chan b.Move) {
+	mp.prompt = prompt
+	mp.response = response
+}
+
+func (mp *MCTSPlayer) Start(board b.Board, quit chan bool) {
+	var err error
+
+	for {
+		select {
+		case <-quit:
+			return
+		case move := <-mp.prompt:
+			if err = board.Make(move); err != nil {
+				panic(err)
+			}
+
+			// the tree after our last move is kept if the opponent's move was searched
+			if mp.treeReuse && mp.root != nil {
+				mp.root = mp.root.getChild(move)
+			} else {
+				mp.root = nil
+			}
+
+			response := mp.getMove(board)
+			board.Make(response)
+
+			if mp.treeReuse && mp.root != nil {
+				mp.root = mp.root.getChild(response)
+			}
+
+			select {
+			case mp.response <- response:
+			case <-quit:
+				return
+			}
+		}
+	}
+}
+
+/**
+Searches for as long as the budget allows: the clock in timed games, otherwise the move time or number of iterations
+*/
+func (mp *MCTSPlayer) getMove(board b.Board) b.Move {
+	var timeManager *time_control.TimeManager
+	switch {
+	case mp.clock != nil:
+		timeManager = time_control.NewTimeManager(*mp.clock)
+	case mp.moveTime > 0:
+		timeManager = time_control.NewFixedTimeManager(mp.moveTime)
+	}
+
+	if mp.root == nil {
+		mp.root = newNode(board, nil, nil)
+	}
+
+	for i := 0; ; i++ {
+		if timeManager != nil && (timeManager.Elapsed() >= timeManager.GetSoftLimit() || timeManager.ShouldStop()) {
+			break
+		}
+		if timeManager == nil && i >= mp.iterations {
+			break
+		}
+
+		mp.iterate(board)
+	}
+
+	best := mp.root.getMostVisited()
+	if len(best) == 0 {
+		moves := board.GetValidMoves() // not a single iteration was run
+		return moves[rand.Intn(len(moves))]
+	}
+
+	return best[rand.Intn(len(best))].move
+}
+
+/**
+Runs one iteration of the search: selects a path down the tree, expands it by one node, plays the game out from that
+node and backpropagates the result
+*/
+func (mp *MCTSPlayer) iterate(board b.Board) {
+	var n *node = mp.root
+	var current b.Board = board.Copy()
+
+	for n.isFullyExpanded() && len(n.children) > 0 {
+		n = n.selectChild(mp.exploration)
+		current.Make(n.move)
+	}
+
+	if !n.isFullyExpanded() {
+		n, current = n.expand(current)
+	}
+
+	whiteScore := mp.runPlayouts(current)
+
+	// the node's score is the score of the player who moved into it
+	score := whiteScore
+	if current.GetTurn() == b.WHITE {
+		score = float64(mp.workers) - whiteScore
+	}
+
+	n.backpropagate(float64(mp.workers), score)
+}
+
+/**
+Returns the total score of white over the playouts of every worker
+*/
+func (mp *MCTSPlayer) runPlayouts(board b.Board) float64 {
+	if mp.workers == 1 {
+		return playout(board.Copy(), mp.policy, mp.evaluator, mp.playoutDepth)
+	}
+
+	var wg sync.WaitGroup
+	scores := make([]float64, mp.workers)
+
+	for w := 0; w < mp.workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			scores[w] = playout(board.Copy(), mp.policy, mp.evaluator, mp.playoutDepth)
+		}(w)
+	}
+	wg.Wait()
+
+	var total float64 = 0
+	for _, s := range scores {
+		total += s
+	}
+	return total
+}
@@ -0,0 +1,53 @@
+package mcts_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"math/rand"
+	"testing"
+)
+
+func TestFindsMateInOne(t *testing.T) {
+	rand.Seed(1)
+	board, _ := b.FromFEN("6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1")
+
+	mp := New().Iterations(300).Build()
+	expected := b.NewMove(b.GetSquareFromString("D1"), b.GetSquareFromString("D8")).Build()
+	if move := mp.getMove(board); !b.SameMove(move, expected) {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", expected, move)
+	}
+}
+
+func TestSelectChild(t *testing.T) {
+	// averages of 0.8, 0.5 and 0.33 after 10 visits of the parent
+	parent := &node{visits: 10}
+	for _, child := range []*node{{visits: 5, score: 4}, {visits: 2, score: 1}, {visits: 3, score: 1}} {
+		child.parent = parent
+		parent.children = append(parent.children, child)
+	}
+
+	// without exploration the best average wins, with it the rarely visited child does
+	if best := parent.selectChild(0); best != parent.children[0] {
+		t.Fatalf("\nExpected: \n%v\nActual: \n%v", parent.children[0], best)
+	}
+	if best := parent.selectChild(DEFAULT_EXPLORATION); best != parent.children[1] {
+		t.Fatalf("\nExpected: \n%v\nActual: \n%v", parent.children[1], best)
+	}
+}
+
+func TestBackpropagate(t *testing.T) {
+	root := &node{}
+	child := &node{parent: root}
+	grandchild := &node{parent: child}
+
+	// the player who moved into the grandchild won both playouts
+	grandchild.backpropagate(2, 2)
+
+	for _, test := range []struct {
+		n     *node
+		score float64
+	}{{grandchild, 2}, {child, 0}, {root, 2}} {
+		if test.n.visits != 2 || test.n.score != test.score {
+			t.Fatalf("\nExpected: \n%f/%f\nActual: \n%f/%f", test.score, 2.0, test.n.score, test.n.visits)
+		}
+	}
+}
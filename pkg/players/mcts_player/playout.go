@@ -0,0 +1,111 @@
+package mcts_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/evaluation"
+	"math"
+	"math/rand"
+)
+
+/**
+Chooses the moves of playouts
+*/
+type PlayoutPolicy interface {
+	SelectMove(board b.Board, moves []b.Move) b.Move
+}
+
+type randomPolicy struct{}
+
+/**
+Returns a policy playing uniformly random moves, the classic and fastest playout policy
+*/
+func NewRandomPolicy() PlayoutPolicy {
+	return &randomPolicy{}
+}
+
+func (rp *randomPolicy) SelectMove(board b.Board, moves []b.Move) b.Move {
+	return moves[rand.Intn(len(moves))]
+}
+
+type evaluatorPolicy struct {
+	evaluator evaluation.Evaluator
+	epsilon   float64
+}
+
+/**
+Returns a policy playing the move with the best evaluation, or a random move with probability epsilon so that playouts
+don't all follow the same line. Playouts are slower, but closer to real games
+*/
+func NewEvaluatorPolicy(evaluator evaluation.Evaluator, epsilon float64) PlayoutPolicy {
+	return &evaluatorPolicy{evaluator, epsilon}
+}
+
+func (ep *evaluatorPolicy) SelectMove(board b.Board, moves []b.Move) b.Move {
+	if rand.Float64() < ep.epsilon {
+		return moves[rand.Intn(len(moves))]
+	}
+
+	var best []b.Move
+	var bestScore float64 = math.Inf(-1)
+
+	for _, move := range moves {
+		next := board.Copy()
+		next.Make(move)
+
+		score := ep.evaluator.Evaluate(next)
+		if board.GetTurn() == b.BLACK {
+			score = -score
+		}
+
+		switch {
+		case score > bestScore:
+			best, bestScore = []b.Move{move}, score
+		case score == bestScore:
+			best = append(best, move)
+		}
+	}
+
+	return best[rand.Intn(len(best))]
+}
+
+/**
+Plays the game out from the board, for at most the given number of plies, and returns the score of white: 1 for a win,
+0.5 for a draw and 0 for a loss. Unfinished playouts are scored by the evaluator
+*/
+func playout(board b.Board, policy PlayoutPolicy, evaluator evaluation.Evaluator, maxPlies int) float64 {
+	for ply := 0; ; ply++ {
+		moves := board.GetValidMoves()
+		if len(moves) == 0 {
+			if !board.IsCheck() {
+				return 0.5 // stalemate
+			}
+			if board.GetTurn() == b.WHITE {
+				return 0
+			}
+			return 1
+		}
+
+		if board.IsInsufficientMaterial() || board.GetHalfmoveClock() >= 100 {
+			return 0.5
+		}
+
+		if ply >= maxPlies {
+			break
+		}
+
+		board.Make(policy.SelectMove(board, moves))
+	}
+
+	if evaluator == nil {
+		return 0.5
+	}
+
+	return getExpectedScore(evaluator.Evaluate(board))
+}
+
+/**
+Returns the expected score of white for an evaluation in pawns
+*/
+func getExpectedScore(pawns float64) float64 {
+	return 1.0 / (1.0 + math.Pow(10, -pawns/EVALUATION_SCALE))
+}
@@ -0,0 +1,117 @@
+package mcts_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"math"
+	"math/rand"
+)
+
+/**
+A node of the search tree, reached by playing its move from its parent. Scores are from the perspective of the player
+who made the move, so that every player picks the child with the best score for themselves
+*/
+type node struct {
+	move     b.Move
+	parent   *node
+	children []*node
+
+	// moves not yet expanded into children, in random order
+	untried []b.Move
+
+	visits float64
+	score  float64
+}
+
+func newNode(board b.Board, move b.Move, parent *node) *node {
+	moves := board.GetValidMoves()
+	rand.Shuffle(len(moves), func(i, j int) {
+		moves[i], moves[j] = moves[j], moves[i]
+	})
+
+	return &node{move: move, parent: parent, untried: moves}
+}
+
+func (n *node) isTerminal() bool {
+	return len(n.untried) == 0 && len(n.children) == 0
+}
+
+func (n *node) isFullyExpanded() bool {
+	return len(n.untried) == 0
+}
+
+/**
+Returns the child maximizing the UCB1 bound, balancing the average score of a child against how rarely it was visited
+*/
+func (n *node) selectChild(exploration float64) *node {
+	var best *node
+	var bestValue float64 = math.Inf(-1)
+
+	logVisits := math.Log(n.visits)
+	for _, child := range n.children {
+		value := child.score/child.visits + exploration*math.Sqrt(logVisits/child.visits)
+		if value > bestValue {
+			best, bestValue = child, value
+		}
+	}
+
+	return best
+}
+
+/**
+Adds a child for one of the untried moves, and returns it along with the board after the move
+*/
+func (n *node) expand(board b.Board) (*node, b.Board) {
+	move := n.untried[len(n.untried)-1]
+	n.untried = n.untried[:len(n.untried)-1]
+
+	next := board.Copy()
+	next.Make(move)
+
+	child := newNode(next, move, n)
+	n.children = append(n.children, child)
+	return child, next
+}
+
+/**
+Adds the results of playouts from the node to it and its ancestors. The score is the total score of the player who
+moved into the node
+*/
+func (n *node) backpropagate(visits, score float64) {
+	for current := n; current != nil; current = current.parent {
+		current.visits += visits
+		current.score += score
+		score = visits - score // the other player's score
+	}
+}
+
+/**
+Returns the children visited the most, which are the moves the search trusts the most
+*/
+func (n *node) getMostVisited() []*node {
+	var best []*node
+	var bestVisits float64 = -1
+
+	for _, child := range n.children {
+		switch {
+		case child.visits > bestVisits:
+			best, bestVisits = []*node{child}, child.visits
+		case child.visits == bestVisits:
+			best = append(best, child)
+		}
+	}
+
+	return best
+}
+
+/**
+Returns the child reached by the move, detached from the rest of the tree, or nil if it was never expanded
+*/
+func (n *node) getChild(move b.Move) *node {
+	for _, child := range n.children {
+		if b.SameMove(child.move, move) {
+			child.parent = nil
+			return child
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package minimax_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/time_control"
+	"math"
+	"sort"
+	"time"
+)
+
+/**
+Bounds of an analysis. Without a depth, clock or move time, the analysis runs until it is stopped
+*/
+type Limits struct {
+	// deepest iteration, or 0 for no limit
+	Depth int
+
+	MoveTime time.Duration
+
+	// state of the clock, to analyse for as long as a move of a timed game would be searched
+	Clock *time_control.Clock
+
+	// number of best moves to report, at least 1
+	MultiPV int
+
+	// closing the channel stops the analysis, which then returns the lines of its deepest completed iteration
+	Stop chan bool
+}
+
+/**
+One of the best moves of an analysis, with its principal variation
+*/
+type PVLine struct {
+	// the move followed by the expected replies
+	Moves []b.Move
+
+	// score of the move from the perspective of the side to move, in pawns
+	Score float64
+
+	// depth of the iteration which scored the move
+	Depth int
+}
+
+/**
+Returns the number of moves to mate if the score of the line is a mate score, negative if the side to move is mated
+*/
+func (l PVLine) GetMate() (int, bool) {
+	if math.Abs(l.Score) < MATE_SCORE-float64(MAX_PLY) {
+		return 0, false
+	}
+
+	plies := int(math.Round(MATE_SCORE - math.Abs(l.Score)))
+	if l.Score < 0 {
+		return -plies / 2, true
+	}
+	return (plies + 1) / 2, true
+}
+
+/**
+Analyses the board and returns the best moves, as many as limits.MultiPV, with their scores and principal variations,
+best first. Below the highest skill level, the move the level picks comes first instead. The player must not be playing
+a game at the same time
+*/
+func (mp *MiniMaxPlayer) Analyze(board b.Board, limits Limits) []PVLine {
+	var timeManager *time_control.TimeManager
+	switch {
+	case limits.Clock != nil:
+		timeManager = time_control.NewTimeManager(*limits.Clock)
+	case limits.MoveTime > 0:
+		timeManager = time_control.NewFixedTimeManager(limits.MoveTime)
+	default:
+		timeManager = time_control.NewUnlimitedTimeManager()
+	}
+
+	maxDepth := limits.Depth
+	if maxDepth <= 0 || maxDepth > MAX_SEARCH_DEPTH {
+		maxDepth = MAX_SEARCH_DEPTH
+	}
+
+	multiPV := limits.MultiPV
+	if multiPV < 1 {
+		multiPV = 1
+	}
+
+	done := make(chan bool)
+	defer close(done)
+	go func() {
+		select {
+		case <-limits.Stop:
+			timeManager.Stop()
+		case <-done:
+		}
+	}()
+
+	if mp.skill != nil {
+		return mp.analyzeWithSkill(board, maxDepth, timeManager, multiPV)
+	}
+
+	wait := mp.startHelpers(board, maxDepth, timeManager)
+	lines := mp.searcher.analyze(board, maxDepth, timeManager, multiPV)
+	timeManager.Stop()
+	wait()
+
+	return lines
+}
+
+type rootMove struct {
+	move  b.Move
+	score float64
+}
+
+/**
+Runs iterative deepening like iterativeDeepening, but keeps the scores of the given number of best moves exact, and
+returns them with their principal variations
+*/
+func (s *searcher) analyze(board b.Board, maxDepth int, timeManager *time_control.TimeManager, multiPV int) []PVLine {
+	var lines []PVLine
+	var previousBest b.Move
+	var previousScore float64
+
+	s.timeManager = timeManager
+	s.newSearch()
+
+	moves := board.GetValidMoves()
+	if len(moves) == 0 {
+		return nil
+	}
+	s.orderMoves(board, moves, 0)
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		rootMoves, completed := s.searchRootLines(board, moves, depth, multiPV)
+		if !completed && lines != nil {
+			break
+		}
+
+		lines = s.getLines(board, rootMoves, depth, multiPV)
+		if !completed || len(lines) == 0 {
+			break // not even the first iteration finished, so settle for the moves that were searched
+		}
+
+		if s.info != nil {
+			for i, line := range lines {
+				s.info(s.getInfo(i+1, line))
+			}
+		}
+
+		// the moves are searched in the order of this iteration in the next one
+		for i, rm := range rootMoves {
+			moves[i] = rm.move
+		}
+
+		bestMoveChanged := previousBest != nil && !b.SameMove(lines[0].Moves[0], previousBest)
+		failedLow := depth > 1 && lines[0].Score < previousScore-FAIL_LOW_MARGIN
+		previousBest, previousScore = lines[0].Moves[0], lines[0].Score
+
+		timeManager.OnIteration(bestMoveChanged, failedLow)
+		if !timeManager.ShouldStartIteration() {
+			break
+		}
+	}
+
+	return lines
+}
+
+/**
+Searches every move of the board to the given depth, with a window keeping the scores of the best multiPV moves exact,
+and returns the moves searched sorted by score. Returns false if the search was stopped before it completed
+*/
+func (s *searcher) searchRootLines(board b.Board, moves []b.Move, depth, multiPV int) ([]rootMove, bool) {
+	var rootMoves []rootMove
+
+	for _, move := range moves {
+		bCopy := board.Copy()
+		bCopy.Make(move)
+
+		// moves scoring below the worst of the best moves so far only need to be proven worse
+		var alpha float64 = -INFINITY
+		if len(rootMoves) >= multiPV {
+			alpha = rootMoves[multiPV-1].score - TIE_MARGIN
+		}
+
+		score := -s.search(bCopy, depth-1, 1, -INFINITY, -alpha)
+
+		if s.isStopped() {
+			return rootMoves, false
+		}
+
+		rootMoves = append(rootMoves, rootMove{move, score})
+		sort.SliceStable(rootMoves, func(i, j int) bool {
+			return rootMoves[i].score > rootMoves[j].score
+		})
+	}
+
+	return rootMoves, true
+}
+
+func (s *searcher) getLines(board b.Board, rootMoves []rootMove, depth, multiPV int) []PVLine {
+	var lines []PVLine
+
+	for i := 0; i < multiPV && i < len(rootMoves); i++ {
+		next := board.Copy()
+		next.Make(rootMoves[i].move)
+
+		pv := append([]b.Move{rootMoves[i].move}, s.getPrincipalVariation(next, depth-1)...)
+		lines = append(lines, PVLine{Moves: pv, Score: rootMoves[i].score, Depth: depth})
+	}
+
+	return lines
+}
@@ -2,38 +2,109 @@ package minimax_player
 
 import (
 	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/evaluation"
+	"galapb/chess2022/pkg/players/player"
+	"galapb/chess2022/pkg/syzygy"
+	"galapb/chess2022/pkg/time_control"
 	"math/rand"
+	"sync"
 )
 
 type MiniMaxPlayer struct {
-	prompt   chan b.Move
-	response chan b.Move
-	maxDepth int
+	prompt    chan b.Move
+	response  chan b.Move
+	maxDepth  int
+	evaluator evaluation.Evaluator
+	searcher  *searcher
+
+	// state of the clock for the next move, or nil if the game is not timed
+	clock *time_control.Clock
+
+	// helper threads of Lazy SMP, searching along the main searcher
+	helpers []*searcher
+
+	// plays the first of the best moves instead of a random one, so that games can be replayed
+	deterministic bool
+
+	// keeps searching on the opponent's time, on the move it is expected to play
+	ponder    bool
+	pondering *ponderSearch
+
+	// weakens the player below the highest skill level, or nil for full strength
+	skill *skill
 }
 
-var ALL_POSSIBLE_MOVES []b.Move = make([]b.Move, 0)
+func New() *MiniMaxPlayer {
+	return NewWithEvaluator(evaluation.NewPositionalEvaluator(evaluation.DefaultWeights()))
+}
+
+func NewWithEvaluator(evaluator evaluation.Evaluator) *MiniMaxPlayer {
+	return NewWithTableSize(evaluator, TT_SIZE)
+}
 
-func init() {
-	var srcSquare b.Square = 1
-	var dstSquare b.Square = 1
-	var move b.Move
+/**
+Returns a player whose transposition table has the given number of entries instead of TT_SIZE, such as a smaller table
+for shallow searches, which don't need TT_SIZE entries
+*/
+func NewWithTableSize(evaluator evaluation.Evaluator, size int) *MiniMaxPlayer {
+	mp := &MiniMaxPlayer{maxDepth: 2, evaluator: evaluator}
+	mp.searcher = newSearcher(evaluator, newTranspositionTable(size), 0)
+	return mp
+}
 
-	for i := 0; i < 64; i++ {
-		dstSquare = 1
+/**
+Sets the depth of the searches in untimed games
+*/
+func (mp *MiniMaxPlayer) SetMaxDepth(depth int) {
+	mp.maxDepth = depth
+}
 
-		for j := 0; j < 64; j++ {
-			move = b.NewMove(srcSquare, dstSquare).Build()
-			ALL_POSSIBLE_MOVES = append(ALL_POSSIBLE_MOVES, move)
+func (mp *MiniMaxPlayer) SetClock(clock time_control.Clock) {
+	mp.clock = &clock
+}
 
-			dstSquare <<= 1
-		}
+/**
+Makes the player probe the tablebase in endgames: at the root, only moves keeping the best result are played, and in
+the search, positions reached by captures or pawn moves are scored by their tablebase result. Players don't probe
+any tablebase until one is set
+*/
+func (mp *MiniMaxPlayer) SetTablebase(tablebase *syzygy.Tablebase) {
+	mp.searcher.tablebase = tablebase
+	for _, helper := range mp.helpers {
+		helper.tablebase = tablebase
+	}
+}
 
-		srcSquare <<= 1
+/**
+Searches with the given number of threads (Lazy SMP): helper threads search the same position at staggered depths and
+share the transposition table with the main thread, whose result is played unless a helper completed a deeper iteration
+*/
+func (mp *MiniMaxPlayer) SetThreads(threads int) {
+	mp.helpers = nil
+	for id := 1; id < threads; id++ {
+		helper := newSearcher(mp.searcher.evaluator, mp.searcher.tt, id)
+		helper.tablebase = mp.searcher.tablebase
+		mp.helpers = append(mp.helpers, helper)
 	}
+	mp.searcher.helpers = mp.helpers
 }
 
-func New() *MiniMaxPlayer {
-	return &MiniMaxPlayer{nil, nil, 2}
+/**
+Reports the iterations of the player's searches to the handler, from the goroutine of the search. Searches on the
+opponent's time are not reported
+*/
+func (mp *MiniMaxPlayer) SetInfoHandler(handler player.InfoHandler) {
+	mp.searcher.info = handler
+}
+
+/**
+Makes the player search with a single thread and always play the same move in the same position, for tests
+*/
+func (mp *MiniMaxPlayer) SetDeterministic(deterministic bool) {
+	mp.deterministic = deterministic
+	if deterministic {
+		mp.SetThreads(1)
+	}
 }
 
 func (mp *MiniMaxPlayer) Init(prompt chan b.Move, response chan b.Move) {
@@ -47,166 +118,123 @@ func (mp *MiniMaxPlayer) Start(board b.Board, quit chan bool) {
 	for {
 		select {
 		case <-quit:
+			mp.stopPondering(nil)
 			return
-		default:
-			move := <-mp.prompt
+		case move := <-mp.prompt:
+			ponderMoves := mp.stopPondering(move)
+
 			if err = board.Make(move); err != nil {
 				panic(err)
 			}
 
-			response := mp.getMove(board)
+			response := mp.getMove(board, ponderMoves)
 			board.Make(response)
 
-			mp.response <- response
-		}
-	}
-}
-
-func (rp *MiniMaxPlayer) getMove(board b.Board) b.Move {
-	if board.GetTurn() == b.WHITE {
-		move, _ := rp.max(board, rp.maxDepth)
-		return move
-	} else {
-		move, _ := rp.min(board, rp.maxDepth)
-		return move
-	}
-}
+			// started before responding, since the game may set the clock again as soon as it has the response
+			if mp.ponder {
+				mp.startPondering(board)
+			}
 
-func (rp *MiniMaxPlayer) heuristic(board b.Board) float64 {
-	switch board.GetStatus() {
-	case b.CHECKMATE:
-		if board.GetTurn() == b.BLACK {
-			return 1000 // black is checkmated
-		} else {
-			return -1000 // white is checkmated
+			// the game stops reading responses once the player flagged
+			select {
+			case mp.response <- response:
+			case <-quit:
+				mp.stopPondering(nil)
+				return
+			}
 		}
-	case b.STALEMATE:
-		return 0
-	case b.INSUFFICIENT_MATERIAL:
-		return 0
-	case b.FIFTY_MOVE_RULE:
-		return 0
-	case b.THREEFOLD_REPETITION:
-		return 0
 	}
-
-	var h float64 = 0.0
-
-	h += 9.0 * float64(board.GetNumOf(b.WHITE, b.QUEEN))
-	h += 3.2 * float64(board.GetNumOf(b.WHITE, b.BISHOP))
-	h += 3.1 * float64(board.GetNumOf(b.WHITE, b.KNIGHT))
-	h += 5.0 * float64(board.GetNumOf(b.WHITE, b.ROOK))
-	h += 1.0 * float64(board.GetNumOf(b.WHITE, b.PAWN))
-
-	h -= 9.0 * float64(board.GetNumOf(b.BLACK, b.QUEEN))
-	h -= 3.2 * float64(board.GetNumOf(b.BLACK, b.BISHOP))
-	h -= 3.1 * float64(board.GetNumOf(b.BLACK, b.KNIGHT))
-	h -= 5.0 * float64(board.GetNumOf(b.BLACK, b.ROOK))
-	h -= 1.0 * float64(board.GetNumOf(b.BLACK, b.PAWN))
-
-	return h
 }
 
 /**
-Returns a move that minimizes the heuristic
+Searches to the player's depth in untimed games. In timed games, the search deepens for as long as the clock allows.
+The best moves found by pondering are played instead of searching again, unless the tablebase has the position
 */
-func (rp *MiniMaxPlayer) min(board b.Board, depth int) (b.Move, float64) {
-	var bCopy b.Board
-	var err error
-	var h float64
-	var p *b.Piece
+func (mp *MiniMaxPlayer) getMove(board b.Board, ponderMoves []b.Move) b.Move {
+	var timeManager *time_control.TimeManager = time_control.NewUnlimitedTimeManager()
+	var maxDepth int = mp.maxDepth
 
-	var bestH float64 = 1000
-	var bestMoves []b.Move = make([]b.Move, 0)
-
-	for _, move := range ALL_POSSIBLE_MOVES {
-		bCopy = board.Copy()
-
-		if p, err = bCopy.GetPieceAt(move.GetSrcSquare()); err != nil {
-			continue // ignore moves which do not move a piece
-		}
+	if mp.clock != nil {
+		timeManager = time_control.NewTimeManager(*mp.clock)
+		maxDepth = MAX_SEARCH_DEPTH
+	}
 
-		if p.GetPieceType() == b.PAWN && (move.GetDstSquare().GetRank() == 1 || move.GetDstSquare().GetRank() == 8) {
-			// always promote to a queen
-			move = move.AddPromotionPieceType(b.QUEEN)
-		}
+	if mp.skill != nil {
+		return mp.getSkillMove(board, maxDepth, timeManager)
+	}
 
-		if err = bCopy.Make(move); err != nil {
-			continue // ignore invalid moves
+	if tb := mp.searcher.tablebase; tb != nil && tb.CanProbe(board) {
+		// the moves of the tablebase are perfect, and the shortest wins are certain to convert before the fifty-move rule
+		if moves, _, err := tb.FilterRootMoves(board, board.GetValidMoves()); err == nil && len(moves) > 0 {
+			return GetRandomMove(moves)
 		}
+	}
 
-		if bCopy.IsCheckmate() {
-			return move, -1000
-		}
+	if len(ponderMoves) > 0 {
+		return mp.pickMove(ponderMoves)
+	}
 
-		if bCopy.IsStalemate() {
-			h = 0
-		} else if depth == 1 {
-			h = rp.heuristic(bCopy)
-		} else {
-			_, h = rp.max(bCopy, depth-1)
-		}
+	moves, _ := mp.search(board, maxDepth, timeManager)
+	return mp.pickMove(moves)
+}
 
-		if h < bestH {
-			bestH = h
-			bestMoves = []b.Move{move}
-		} else if h == bestH {
-			bestMoves = append(bestMoves, move)
-		}
+func (mp *MiniMaxPlayer) pickMove(moves []b.Move) b.Move {
+	if mp.deterministic {
+		return moves[0]
 	}
-
-	return GetRandomMove(bestMoves), bestH
+	return GetRandomMove(moves)
 }
 
 /**
-Returns a move that maximizes the heuristic
+Runs the main searcher and the helpers in parallel, until the main searcher is done, and returns the best moves of the
+deepest completed iteration along with its depth
 */
-func (rp *MiniMaxPlayer) max(board b.Board, depth int) (b.Move, float64) {
-	var bCopy b.Board
-	var err error
-	var h float64
-	var p *b.Piece
+func (mp *MiniMaxPlayer) search(board b.Board, maxDepth int, timeManager *time_control.TimeManager) ([]b.Move, int) {
+	wait := mp.startHelpers(board, maxDepth, timeManager)
 
-	var bestH float64 = -1000
-	var bestMoves []b.Move = make([]b.Move, 0)
+	moves, _, depth := mp.searcher.iterativeDeepening(board, maxDepth, timeManager)
+	timeManager.Stop()
 
-	for _, move := range ALL_POSSIBLE_MOVES {
-		bCopy = board.Copy()
+	if helperMoves, helperDepth := wait(); helperDepth > depth {
+		moves, depth = helperMoves, helperDepth
+	}
 
-		if p, err = bCopy.GetPieceAt(move.GetSrcSquare()); err != nil {
-			continue // ignore moves which do not move a piece
-		}
+	return moves, depth
+}
 
-		if p.GetPieceType() == b.PAWN && (move.GetDstSquare().GetRank() == 1 || move.GetDstSquare().GetRank() == 8) {
-			// always promote to a queen
-			move = move.AddPromotionPieceType(b.QUEEN)
-		}
+/**
+Starts the helper threads on their own copies of the board, and returns a function waiting for them to be stopped by
+the time manager, which returns the best moves of the deepest iteration a helper completed along with its depth
+*/
+func (mp *MiniMaxPlayer) startHelpers(board b.Board, maxDepth int, timeManager *time_control.TimeManager) func() ([]b.Move, int) {
+	type result struct {
+		moves []b.Move
+		depth int
+	}
 
-		if err = bCopy.Make(move); err != nil {
-			continue // ignore invalid moves
-		}
+	var wg sync.WaitGroup
+	results := make([]result, len(mp.helpers))
 
-		if bCopy.IsCheckmate() {
-			return move, 1000
-		}
+	for i, helper := range mp.helpers {
+		wg.Add(1)
+		go func(i int, helper *searcher, board b.Board) {
+			defer wg.Done()
+			moves, _, depth := helper.iterativeDeepening(board, maxDepth, timeManager)
+			results[i] = result{moves, depth}
+		}(i, helper, board.Copy())
+	}
 
-		if bCopy.IsStalemate() {
-			h = 0
-		} else if depth == 1 {
-			h = rp.heuristic(bCopy)
-		} else {
-			_, h = rp.min(bCopy, depth-1)
-		}
+	return func() ([]b.Move, int) {
+		wg.Wait()
 
-		if h > bestH {
-			bestH = h
-			bestMoves = []b.Move{move}
-		} else if h == bestH {
-			bestMoves = append(bestMoves, move)
+		var best result
+		for _, r := range results {
+			if r.depth > best.depth {
+				best = r
+			}
 		}
+		return best.moves, best.depth
 	}
-
-	return GetRandomMove(bestMoves), bestH
 }
 
 func GetRandomMove(moves []b.Move) b.Move {
@@ -0,0 +1,153 @@
+package minimax_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"sort"
+	"sync/atomic"
+)
+
+// ordering bonuses, chosen so that captures and promotions are tried first, then killers, then quiet moves by history
+const (
+	CAPTURE_BONUS   float64 = 1000000
+	PROMOTION_BONUS float64 = 900000
+	KILLER_BONUS    float64 = 800000
+)
+
+var PIECE_TYPES [6]b.PieceType = [6]b.PieceType{b.KING, b.QUEEN, b.KNIGHT, b.BISHOP, b.ROOK, b.PAWN}
+
+// squares on the 1st and 8th rank
+const PROMOTION_RANKS b.BitMap = 0xFF000000000000FF
+
+/**
+Prepares the node count and the ordering heuristics for a new search. Killers are only valid for the position they were
+found in, and history scores are halved so that older searches have less influence
+*/
+func (s *searcher) newSearch() {
+	atomic.StoreInt64(&s.nodes, 0)
+	s.selDepth = 0
+	s.killers = [MAX_PLY][2]b.Move{}
+
+	for c := range s.history {
+		for i := range s.history[c] {
+			for j := range s.history[c][i] {
+				s.history[c][i][j] /= 2
+			}
+		}
+	}
+}
+
+func (s *searcher) orderMoves(board b.Board, moves []b.Move, ply int) {
+	scores := make(map[b.Move]float64, len(moves))
+	for _, move := range moves {
+		scores[move] = s.scoreMove(board, move, ply)
+	}
+
+	sort.SliceStable(moves, func(i, j int) bool {
+		return scores[moves[i]] > scores[moves[j]]
+	})
+}
+
+func (s *searcher) scoreMove(board b.Board, move b.Move, ply int) float64 {
+	// captures are ordered by most valuable victim, least valuable attacker (MVV-LVA)
+	if victim, ok := getCapturedPieceType(board, move); ok {
+		attacker, _ := board.GetPieceAt(move.GetSrcSquare())
+		return CAPTURE_BONUS + 10*getPieceValue(victim) - getPieceValue(attacker.GetPieceType())
+	}
+
+	if move.GetPromotionPieceType() != nil {
+		return PROMOTION_BONUS + getPieceValue(*move.GetPromotionPieceType())
+	}
+
+	if ply < MAX_PLY {
+		if b.SameMove(s.killers[ply][0], move) {
+			return KILLER_BONUS + 1
+		}
+
+		if b.SameMove(s.killers[ply][1], move) {
+			return KILLER_BONUS
+		}
+	}
+
+	return s.history[board.GetTurn()][move.GetSrcSquare().GetIndex()][move.GetDstSquare().GetIndex()]
+}
+
+/**
+Remembers a quiet move which caused a beta cutoff, so it can be tried early in sibling positions
+*/
+func (s *searcher) storeKiller(move b.Move, ply int) {
+	if ply >= MAX_PLY || b.SameMove(s.killers[ply][0], move) {
+		return
+	}
+
+	s.killers[ply][1] = s.killers[ply][0]
+	s.killers[ply][0] = move
+}
+
+/**
+Rewards a quiet move which caused a beta cutoff, weighted so that cutoffs deeper in the tree count for more
+*/
+func (s *searcher) storeHistory(c b.Color, move b.Move, depth int) {
+	s.history[c][move.GetSrcSquare().GetIndex()][move.GetDstSquare().GetIndex()] += float64(depth * depth)
+}
+
+func getPieceValue(pt b.PieceType) float64 {
+	switch pt {
+	case b.KING:
+		return 100.0
+	case b.QUEEN:
+		return 9.0
+	case b.BISHOP:
+		return 3.2
+	case b.KNIGHT:
+		return 3.1
+	case b.ROOK:
+		return 5.0
+	case b.PAWN:
+		return 1.0
+	}
+
+	return 0
+}
+
+/**
+Returns the type of the piece captured by the move, if the move is a capture (including en-passent)
+*/
+func getCapturedPieceType(board b.Board, move b.Move) (b.PieceType, bool) {
+	if victim, err := board.GetPieceAt(move.GetDstSquare()); err == nil {
+		return victim.GetPieceType(), true
+	}
+
+	attacker, err := board.GetPieceAt(move.GetSrcSquare())
+	if err == nil && attacker.GetPieceType() == b.PAWN && move.GetSrcSquare().GetFile() != move.GetDstSquare().GetFile() {
+		return b.PAWN, true // pawns only change files when capturing, so an empty destination means en-passent
+	}
+
+	return 0, false
+}
+
+func isNoisy(board b.Board, move b.Move) bool {
+	_, isCapture := getCapturedPieceType(board, move)
+	return isCapture || move.GetPromotionPieceType() != nil
+}
+
+/**
+Moves the given move to the front of the moves, keeping the order of the others
+*/
+func moveToFront(moves []b.Move, move b.Move) {
+	for i, m := range moves {
+		if b.SameMove(m, move) {
+			copy(moves[1:i+1], moves[:i])
+			moves[0] = m
+			return
+		}
+	}
+}
+
+func containsMove(moves []b.Move, move b.Move) bool {
+	for _, m := range moves {
+		if b.SameMove(m, move) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package minimax_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/players/player"
+	"galapb/chess2022/pkg/time_control"
+)
+
+/**
+A search running on the opponent's time, in the position after the move the opponent is expected to play
+*/
+type ponderSearch struct {
+	move        b.Move
+	timeManager *time_control.TimeManager
+	done        chan bool
+
+	// the info handler of the player, restored once pondering stops
+	info player.InfoHandler
+
+	// result of the search, only read once it is done
+	moves []b.Move
+	depth int
+}
+
+/**
+Makes the player keep searching after its move, on the opponent's expected reply from the principal variation
+*/
+func (mp *MiniMaxPlayer) SetPonder(ponder bool) {
+	mp.ponder = ponder
+}
+
+/**
+Starts searching, in the background, the board after the opponent's expected move. The board is the position after the
+player's own move, and is not modified
+*/
+func (mp *MiniMaxPlayer) startPondering(board b.Board) {
+	if mp.skill != nil {
+		return // pondering would make a weakened player stronger than its level
+	}
+
+	pv := mp.searcher.getPrincipalVariation(board, 1)
+	if len(pv) == 0 {
+		return
+	}
+
+	next := board.Copy()
+	next.Make(pv[0])
+	if len(next.GetValidMoves()) == 0 {
+		return // the expected move ends the game
+	}
+
+	maxDepth := mp.maxDepth
+	if mp.clock != nil {
+		maxDepth = MAX_SEARCH_DEPTH
+	}
+
+	ponder := &ponderSearch{move: pv[0], timeManager: time_control.NewUnlimitedTimeManager(), done: make(chan bool)}
+	ponder.info, mp.searcher.info = mp.searcher.info, nil
+
+	go func() {
+		defer close(ponder.done)
+		ponder.moves, ponder.depth = mp.search(next, maxDepth, ponder.timeManager)
+	}()
+
+	mp.pondering = ponder
+}
+
+/**
+Stops pondering once the opponent played the given move. On a ponder hit, the best moves are returned if the search
+already reached the player's depth in an untimed game. Otherwise, and in timed games, the results are left in the
+transposition table, so that the next search quickly gets back to the depth pondering reached. On a miss they are
+discarded
+*/
+func (mp *MiniMaxPlayer) stopPondering(move b.Move) []b.Move {
+	ponder := mp.pondering
+	if ponder == nil {
+		return nil
+	}
+
+	mp.pondering = nil
+	ponder.timeManager.Stop()
+	<-ponder.done
+	mp.searcher.info = ponder.info
+
+	if !b.SameMove(move, ponder.move) || mp.clock != nil || ponder.depth < mp.maxDepth {
+		return nil
+	}
+
+	return ponder.moves
+}
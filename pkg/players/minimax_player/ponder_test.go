@@ -0,0 +1,126 @@
+package minimax_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/syzygy"
+	"galapb/chess2022/pkg/time_control"
+	"testing"
+	"time"
+)
+
+/**
+Returns a deterministic player pondering at the given depth, after playing its move in the position, along with the
+board after its move
+*/
+func newPonderingPlayer(t *testing.T, fen string, depth int) (*MiniMaxPlayer, b.Board) {
+	mp := New()
+	mp.SetDeterministic(true)
+	mp.SetMaxDepth(depth)
+	mp.SetPonder(true)
+
+	board, err := b.FromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	board.Make(mp.getMove(board, nil))
+
+	mp.startPondering(board)
+	if mp.pondering == nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "pondering", "no expected move")
+	}
+	return mp, board
+}
+
+func TestPonderHit(t *testing.T) {
+	mp, board := newPonderingPlayer(t, "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", 2)
+
+	// the search is done before the opponent plays the expected move
+	expected := mp.pondering.move
+	<-mp.pondering.done
+
+	moves := mp.stopPondering(expected)
+	if len(moves) == 0 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v", "the best moves of pondering", moves)
+	}
+	board.Make(expected)
+	if err := board.Make(mp.getMove(board, moves)); err != nil {
+		t.Fatal(err)
+	}
+	if mp.pondering != nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "pondering stopped", "still pondering")
+	}
+}
+
+func TestPonderMiss(t *testing.T) {
+	mp, board := newPonderingPlayer(t, "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", 2)
+	<-mp.pondering.done
+
+	// another move than the expected one
+	var other b.Move
+	for _, move := range board.GetValidMoves() {
+		if !b.SameMove(move, mp.pondering.move) {
+			other = move
+			break
+		}
+	}
+
+	if moves := mp.stopPondering(other); moves != nil {
+		t.Fatalf("\nExpected: \n%v\nActual: \n%v", nil, moves)
+	}
+}
+
+func TestPonderHitFiltersTablebaseMoves(t *testing.T) {
+	tb, err := syzygy.Open("../../syzygy/testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mp := New()
+	mp.SetDeterministic(true)
+	mp.SetTablebase(tb)
+
+	// pondering found a quiet queen move, but the tablebase mates at once
+	board, _ := b.FromFEN("k7/8/1K6/8/8/8/8/6Q1 w - - 0 1")
+	move := mp.getMove(board, []b.Move{newTestMove("G1", "G2")})
+
+	if err := board.Make(move); err != nil || !board.IsCheckmate() {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "a mate in one", move)
+	}
+}
+
+func TestQuitWhilePondering(t *testing.T) {
+	// in timed games, the player ponders until the opponent moves
+	mp := New()
+	mp.SetPonder(true)
+	mp.SetClock(time_control.Clock{Remaining: 2 * time.Second, MovesToGo: 10})
+
+	prompt, response, quit := make(chan b.Move), make(chan b.Move), make(chan bool)
+	mp.Init(prompt, response)
+
+	done := make(chan bool)
+	go func() {
+		mp.Start(b.Standard(), quit)
+		close(done)
+	}()
+
+	prompt <- newTestMove("E2", "E4")
+	<-response
+	ponder := mp.pondering
+	if ponder == nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "pondering", "no expected move")
+	}
+
+	close(quit)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "the player stopped", "still running")
+	}
+
+	// the search on the opponent's time is over too
+	select {
+	case <-ponder.done:
+	default:
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "pondering stopped", "still pondering")
+	}
+}
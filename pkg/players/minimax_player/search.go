@@ -0,0 +1,484 @@
+package minimax_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/evaluation"
+	"galapb/chess2022/pkg/players/player"
+	"galapb/chess2022/pkg/polyglot"
+	"galapb/chess2022/pkg/syzygy"
+	"galapb/chess2022/pkg/time_control"
+	"math"
+	"sync/atomic"
+)
+
+const (
+	MATE_SCORE float64 = 1000
+	INFINITY   float64 = 100000
+
+	// score of positions drawn by the fifty-move rule or insufficient material
+	DRAW_SCORE float64 = 0
+
+	// score of positions won according to the tablebase, below mate scores since the mate is still to be found
+	TB_WIN_SCORE float64 = 500
+
+	// deepest ply the search (including quiescence) is allowed to reach
+	MAX_PLY int = 64
+
+	// deepest iteration of iterative deepening, when the search is bounded by time instead of depth
+	MAX_SEARCH_DEPTH int = 32
+
+	// a score this much lower than the previous iteration's counts as a fail-low, earning the search more time
+	FAIL_LOW_MARGIN float64 = 0.3
+
+	// margin used at the root so that moves scoring equal to the best move are searched exactly, keeping random tie-breaking
+	TIE_MARGIN float64 = 1e-9
+)
+
+// depths skipped by the helper threads of Lazy SMP, so that they don't all search the same iteration at the same time
+var SKIP_SIZE [20]int = [20]int{1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 3, 3, 4, 4, 4, 4, 4, 4, 4, 4}
+var SKIP_PHASE [20]int = [20]int{0, 1, 0, 1, 2, 3, 0, 1, 2, 3, 4, 5, 0, 1, 2, 3, 4, 5, 6, 7}
+
+type searcher struct {
+	evaluator   evaluation.Evaluator
+	timeManager *time_control.TimeManager
+	tablebase   *syzygy.Tablebase
+
+	// shared by all the threads of a search
+	tt *transpositionTable
+
+	// the main thread is 0, helper threads only fill the transposition table for it
+	id int
+
+	// positions visited by the current search, which stops at the limit unless it is 0. Read by the main thread to
+	// report the nodes of all threads
+	nodes     int64
+	nodeLimit int64
+
+	// deepest ply reached by the current search
+	selDepth int
+
+	// the main thread reports its iterations to the handler, if not nil, counting the nodes of the helpers too
+	info    player.InfoHandler
+	helpers []*searcher
+
+	// move ordering heuristics
+	killers [MAX_PLY][2]b.Move
+	history [2][64][64]float64
+}
+
+func newSearcher(evaluator evaluation.Evaluator, tt *transpositionTable, id int) *searcher {
+	return &searcher{evaluator: evaluator, tt: tt, id: id}
+}
+
+/**
+Runs iterative deepening up to the given depth, stopping early when the time manager runs out of time. Returns all moves
+sharing the best score of the deepest completed iteration, along with that score and depth
+*/
+func (s *searcher) iterativeDeepening(board b.Board, maxDepth int, timeManager *time_control.TimeManager) ([]b.Move, float64, int) {
+	var bestMoves []b.Move
+	var bestScore float64
+	var bestDepth int
+	var previousBest b.Move
+
+	s.timeManager = timeManager
+	s.newSearch()
+
+	moves := board.GetValidMoves()
+	s.orderMoves(board, moves, 0)
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		if s.isSkipped(depth, board.GetPly()) {
+			continue
+		}
+
+		iterationMoves, iterationScore, completed := s.searchRoot(board, moves, depth, previousBest)
+
+		if !completed {
+			if len(bestMoves) == 0 {
+				// not even the first iteration finished, so settle for the best of the moves that were searched
+				bestMoves, bestScore = iterationMoves, iterationScore
+			}
+			break
+		}
+
+		bestMoveChanged := previousBest != nil && !containsMove(iterationMoves, previousBest)
+		failedLow := depth > 1 && iterationScore < bestScore-FAIL_LOW_MARGIN
+
+		bestMoves, bestScore, bestDepth = iterationMoves, iterationScore, depth
+		previousBest = bestMoves[0]
+
+		if s.id > 0 {
+			continue // helpers search until the main thread stops them
+		}
+
+		if s.info != nil {
+			lines := s.getLines(board, []rootMove{{bestMoves[0], bestScore}}, depth, 1)
+			s.info(s.getInfo(1, lines[0]))
+		}
+
+		timeManager.OnIteration(bestMoveChanged, failedLow)
+		if !timeManager.ShouldStartIteration() {
+			break
+		}
+	}
+
+	if len(bestMoves) == 0 {
+		bestMoves = moves // stopped before any move was searched
+	}
+
+	return bestMoves, bestScore, bestDepth
+}
+
+/**
+Returns true if a helper thread skips the iteration of the given depth, staggering the depths searched by the threads
+*/
+func (s *searcher) isSkipped(depth, gamePly int) bool {
+	if s.id == 0 {
+		return false
+	}
+
+	i := (s.id - 1) % len(SKIP_SIZE)
+	return ((depth+gamePly+SKIP_PHASE[i])/SKIP_SIZE[i])%2 != 0
+}
+
+/**
+Searches every move of the board to the given depth, trying the previous iteration's best move first, and returns all
+moves sharing the best score along with that score. Returns false if the search was stopped before it completed
+*/
+func (s *searcher) searchRoot(board b.Board, moves []b.Move, depth int, previousBest b.Move) ([]b.Move, float64, bool) {
+	var bCopy b.Board
+	var score float64
+
+	var bestScore float64 = -INFINITY
+	var bestMoves []b.Move = make([]b.Move, 0)
+
+	moveToFront(moves, previousBest)
+
+	for _, move := range moves {
+		bCopy = board.Copy()
+		bCopy.Make(move)
+
+		score = -s.search(bCopy, depth-1, 1, -INFINITY, -(bestScore - TIE_MARGIN))
+
+		if s.isStopped() {
+			return bestMoves, bestScore, false
+		}
+
+		if score > bestScore {
+			bestScore = score
+			bestMoves = []b.Move{move}
+		} else if score == bestScore {
+			bestMoves = append(bestMoves, move)
+		}
+	}
+
+	return bestMoves, bestScore, true
+}
+
+func (s *searcher) isStopped() bool {
+	if s.nodeLimit > 0 && atomic.LoadInt64(&s.nodes) >= s.nodeLimit {
+		return true
+	}
+	return s.timeManager != nil && s.timeManager.ShouldStop()
+}
+
+func (s *searcher) visit(ply int) {
+	atomic.AddInt64(&s.nodes, 1)
+	if ply > s.selDepth {
+		s.selDepth = ply
+	}
+}
+
+/**
+Returns the nodes searched by this searcher and its helpers
+*/
+func (s *searcher) getTotalNodes() int64 {
+	nodes := atomic.LoadInt64(&s.nodes)
+	for _, helper := range s.helpers {
+		nodes += atomic.LoadInt64(&helper.nodes)
+	}
+	return nodes
+}
+
+/**
+Returns the info reported for a line of the current search
+*/
+func (s *searcher) getInfo(multiPV int, line PVLine) player.SearchInfo {
+	info := player.SearchInfo{
+		Depth:    line.Depth,
+		SelDepth: s.selDepth,
+		MultiPV:  multiPV,
+		Nodes:    s.getTotalNodes(),
+		Time:     s.timeManager.Elapsed(),
+		HashFull: s.tt.getHashFull(),
+		PV:       line.Moves,
+	}
+
+	if mate, ok := line.GetMate(); ok {
+		info.Mate = mate
+	} else {
+		info.Score = int(math.Round(line.Score * 100))
+	}
+
+	return info
+}
+
+/**
+Returns the score of the board from the perspective of the side to move, using a fail-soft alpha-beta search
+*/
+func (s *searcher) search(board b.Board, depth, ply int, alpha, beta float64) float64 {
+	var bCopy b.Board
+	var score float64
+
+	if s.isStopped() {
+		return 0 // the result is discarded
+	}
+	s.visit(ply)
+
+	if isDraw(board) {
+		return DRAW_SCORE
+	}
+
+	if score, ok := s.probeTablebase(board, ply); ok {
+		return score
+	}
+
+	if depth <= 0 || ply >= MAX_PLY {
+		return s.quiescence(board, ply, alpha, beta)
+	}
+
+	key := polyglot.Hash(board)
+	entry, found := s.tt.probe(key, ply)
+	if found && entry.depth >= depth {
+		switch {
+		case entry.bound == EXACT_BOUND,
+			entry.bound == LOWER_BOUND && entry.score >= beta,
+			entry.bound == UPPER_BOUND && entry.score <= alpha:
+			return entry.score
+		}
+	}
+
+	moves := board.GetValidMoves()
+	if len(moves) == 0 {
+		if board.IsCheck() {
+			return -MATE_SCORE + float64(ply) // checkmated, prefer being mated later
+		}
+		return 0 // stalemate
+	}
+
+	s.orderMoves(board, moves, ply)
+	if found {
+		moveToFront(moves, entry.move)
+	}
+
+	var originalAlpha float64 = alpha
+	var bestScore float64 = -INFINITY
+	var bestMove b.Move
+	for _, move := range moves {
+		bCopy = board.Copy()
+		bCopy.Make(move)
+
+		score = -s.search(bCopy, depth-1, ply+1, -beta, -alpha)
+
+		if score > bestScore {
+			bestScore = score
+			bestMove = move
+		}
+
+		if score > alpha {
+			alpha = score
+		}
+
+		if alpha >= beta {
+			if !isNoisy(board, move) {
+				s.storeKiller(move, ply)
+				s.storeHistory(board.GetTurn(), move, depth)
+			}
+			break
+		}
+	}
+
+	if s.isStopped() {
+		return 0 // the result is discarded, and must not be stored
+	}
+
+	bound := EXACT_BOUND
+	switch {
+	case bestScore <= originalAlpha:
+		bound = UPPER_BOUND
+	case bestScore >= beta:
+		bound = LOWER_BOUND
+	}
+	s.tt.store(key, ply, ttEntry{bestScore, depth, bound, bestMove})
+
+	return bestScore
+}
+
+/**
+Returns the principal variation of the board, up to the given length, by following the best moves stored in the
+transposition table
+*/
+func (s *searcher) getPrincipalVariation(board b.Board, maxLength int) []b.Move {
+	var pv []b.Move
+	var current b.Board = board.Copy()
+	var seen map[uint64]bool = make(map[uint64]bool)
+
+	for len(pv) < maxLength {
+		key := polyglot.Hash(current)
+		entry, found := s.tt.probe(key, 0)
+		if !found || entry.move == nil || seen[key] {
+			break
+		}
+
+		if err := current.Make(entry.move); err != nil {
+			break // a collision of keys
+		}
+
+		seen[key] = true
+		pv = append(pv, entry.move)
+	}
+
+	return pv
+}
+
+/**
+Returns the tablebase score of the board from the perspective of the side to move. Only positions right after a capture
+or pawn move are probed, since the result then doesn't depend on the moves played before
+*/
+func (s *searcher) probeTablebase(board b.Board, ply int) (float64, bool) {
+	if s.tablebase == nil || board.GetHalfmoveClock() != 0 || !s.tablebase.CanProbe(board) {
+		return 0, false
+	}
+
+	wdl, err := s.tablebase.ProbeWDL(board)
+	if err != nil {
+		return 0, false
+	}
+
+	switch wdl {
+	case syzygy.WIN:
+		return TB_WIN_SCORE - float64(ply), true
+	case syzygy.LOSS:
+		return -TB_WIN_SCORE + float64(ply), true
+	}
+
+	return 0, true // draws, including wins and losses drawn by the fifty-move rule
+}
+
+/**
+Resolves captures and promotions until the position is quiet, so that the evaluation is never taken in the middle of an exchange
+*/
+func (s *searcher) quiescence(board b.Board, ply int, alpha, beta float64) float64 {
+	var bCopy b.Board
+	var score float64
+	var moves []b.Move
+
+	var bestScore float64 = -INFINITY
+	var inCheck bool = board.IsCheck()
+
+	if s.isStopped() {
+		return 0 // the result is discarded
+	}
+	s.visit(ply)
+
+	if isDraw(board) {
+		return DRAW_SCORE
+	}
+
+	if inCheck {
+		// the side to move can't stand pat while in check, so every evasion is searched
+		moves = board.GetValidMoves()
+		if len(moves) == 0 {
+			return -MATE_SCORE + float64(ply)
+		}
+	} else {
+		standPat := s.evaluateRelative(board)
+		if standPat >= beta || ply >= MAX_PLY {
+			return standPat
+		}
+
+		if standPat > alpha {
+			alpha = standPat
+		}
+
+		bestScore = standPat
+		moves = getNoisyMoves(board)
+	}
+
+	s.orderMoves(board, moves, ply)
+
+	for _, move := range moves {
+		if !inCheck && move.GetPromotionPieceType() == nil && see(board, move) < 0 {
+			continue // prune captures which lose material
+		}
+
+		bCopy = board.Copy()
+		bCopy.Make(move)
+
+		score = -s.quiescence(bCopy, ply+1, -beta, -alpha)
+
+		if score > bestScore {
+			bestScore = score
+		}
+
+		if score > alpha {
+			alpha = score
+		}
+
+		if alpha >= beta {
+			break
+		}
+	}
+
+	return bestScore
+}
+
+/**
+Returns whether the game is drawn by the fifty-move rule or insufficient material. Checked before the transposition table
+is probed, since the keys don't include the halfmove clock
+*/
+func isDraw(board b.Board) bool {
+	return board.GetHalfmoveClock() >= 100 || board.IsInsufficientMaterial()
+}
+
+func (s *searcher) evaluateRelative(board b.Board) float64 {
+	if board.GetTurn() == b.WHITE {
+		return s.evaluator.Evaluate(board)
+	} else {
+		return -s.evaluator.Evaluate(board)
+	}
+}
+
+/**
+Returns the valid captures and queen promotions of the side to move. En-passent captures are not included
+*/
+func getNoisyMoves(board b.Board) []b.Move {
+	var move b.Move
+	var moves []b.Move = make([]b.Move, 0)
+	var us b.Color = board.GetTurn()
+	var them b.Color = us.Opposite()
+
+	var targets b.BitMap = board.GetOccupancy(them)
+
+	for _, pieceType := range PIECE_TYPES {
+		for _, srcSquare := range board.GetPieceBitmap(us, pieceType).GetSquares() {
+			dstSquares := targets
+			if pieceType == b.PAWN {
+				dstSquares |= PROMOTION_RANKS // pawns also make noise by pushing to the last rank
+			}
+
+			for _, dstSquare := range dstSquares.GetSquares() {
+				move = b.NewMove(srcSquare, dstSquare).Build()
+				if pieceType == b.PAWN && (dstSquare.GetRank() == 1 || dstSquare.GetRank() == 8) {
+					move = move.AddPromotionPieceType(b.QUEEN)
+				}
+
+				if err := board.IsValidMove(move); err == nil {
+					moves = append(moves, move)
+				}
+			}
+		}
+	}
+
+	return moves
+}
@@ -0,0 +1,95 @@
+package minimax_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/evaluation"
+	"galapb/chess2022/pkg/time_control"
+	"testing"
+)
+
+func newTestSearcher() *searcher {
+	return newSearcher(evaluation.NewPositionalEvaluator(evaluation.DefaultWeights()), newTranspositionTable(1<<12), 0)
+}
+
+func newTestMove(src, dst string) b.Move {
+	return b.NewMove(b.GetSquareFromString(src), b.GetSquareFromString(dst)).Build()
+}
+
+/**
+Returns the board after the moves, given as pairs of squares, are played from the standard position
+*/
+func playTestMoves(t *testing.T, squares ...string) b.Board {
+	board := b.Standard()
+	for i := 0; i+1 < len(squares); i += 2 {
+		if err := board.Make(newTestMove(squares[i], squares[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return board
+}
+
+func TestSearchFindsMate(t *testing.T) {
+	// 1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7#
+	board := playTestMoves(t, "E2", "E4", "E7", "E5", "F1", "C4", "B8", "C6", "D1", "H5", "G8", "F6")
+
+	moves, score, _ := newTestSearcher().iterativeDeepening(board, 2, time_control.NewUnlimitedTimeManager())
+	if score != MATE_SCORE-1 {
+		t.Fatalf("\nExpected: \n%f\nActual: \n%f", MATE_SCORE-1, score)
+	}
+	if len(moves) != 1 || !b.SameMove(moves[0], newTestMove("H5", "F7")) {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v", "h5f7", moves)
+	}
+}
+
+func TestQuiescenceResolvesRecapture(t *testing.T) {
+	// the pawn on e5 is defended by the knight, so taking it loses the queen just past the horizon of a depth 1 search
+	board := playTestMoves(t, "E2", "E4", "E7", "E5", "D1", "H5", "B8", "C6")
+
+	moves, _, _ := newTestSearcher().iterativeDeepening(board, 1, time_control.NewUnlimitedTimeManager())
+	if containsMove(moves, newTestMove("H5", "E5")) {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v", "h5e5 not among the best moves", moves)
+	}
+}
+
+func TestSEESign(t *testing.T) {
+	board := playTestMoves(t, "E2", "E4", "E7", "E5", "D1", "H5", "B8", "C6")
+	if score := see(board, newTestMove("H5", "E5")); score >= 0 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%f", "negative exchange", score)
+	}
+
+	// the queen on g4 is undefended
+	board = playTestMoves(t, "E2", "E4", "D7", "D5", "D1", "G4")
+	if score := see(board, newTestMove("C8", "G4")); score <= 0 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%f", "positive exchange", score)
+	}
+}
+
+func TestSearchWithTranspositionTableHitsMatchesColdSearch(t *testing.T) {
+	board, _ := b.FromFEN("r1bqkbnr/pppp1ppp/2n5/4p3/2B1P3/5Q2/PPPP1PPP/RNB1K1NR w KQkq - 2 3")
+
+	cold, coldScore, _ := newTestSearcher().iterativeDeepening(board, 3, time_control.NewUnlimitedTimeManager())
+
+	s := newTestSearcher()
+	s.iterativeDeepening(board, 3, time_control.NewUnlimitedTimeManager())
+	warm, warmScore, _ := s.iterativeDeepening(board, 3, time_control.NewUnlimitedTimeManager())
+
+	if warmScore != coldScore {
+		t.Fatalf("\nExpected: \n%f\nActual: \n%f", coldScore, warmScore)
+	}
+	if !containsMove(warm, cold[0]) || !containsMove(cold, warm[0]) {
+		t.Fatalf("\nExpected: \n%v\nActual: \n%v", cold, warm)
+	}
+}
+
+func TestSearchScoresDraws(t *testing.T) {
+	// the queen would win, but the fifty-move rule already drew the game
+	board, _ := b.FromFEN("4k3/8/8/8/8/8/8/Q3K3 b - - 100 80")
+	if score := newTestSearcher().search(board, 2, 1, -INFINITY, INFINITY); score != DRAW_SCORE {
+		t.Fatalf("\nExpected: \n%f\nActual: \n%f", DRAW_SCORE, score)
+	}
+
+	board, _ = b.FromFEN("4k3/8/8/8/8/8/8/1N2K3 b - - 0 1")
+	if score := newTestSearcher().quiescence(board, 1, -INFINITY, INFINITY); score != DRAW_SCORE {
+		t.Fatalf("\nExpected: \n%f\nActual: \n%f", DRAW_SCORE, score)
+	}
+}
@@ -0,0 +1,80 @@
+package minimax_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+)
+
+/**
+Static exchange evaluation: returns the material the side to move gains (or loses, if negative) by making the capture and
+letting both sides keep recapturing on the destination square with their least valuable attacker, where either side may
+stop recapturing when it is no longer favourable
+*/
+func see(board b.Board, move b.Move) float64 {
+	var gain float64 = 0
+
+	if victim, ok := getCapturedPieceType(board, move); ok {
+		gain = getPieceValue(victim)
+	}
+
+	attacker, err := board.GetPieceAt(move.GetSrcSquare())
+	if err != nil {
+		return 0
+	}
+
+	var attackerValue float64 = getPieceValue(attacker.GetPieceType())
+	if move.GetPromotionPieceType() != nil {
+		attackerValue = getPieceValue(*move.GetPromotionPieceType())
+		gain += attackerValue - getPieceValue(b.PAWN)
+	}
+
+	bCopy := board.Copy()
+	if err = bCopy.Make(move); err != nil {
+		return 0
+	}
+
+	return gain - seeSquare(bCopy, move.GetDstSquare(), attackerValue)
+}
+
+/**
+Returns the material the side to move gains by recapturing on the square, which holds a piece worth the given value
+*/
+func seeSquare(board b.Board, square b.Square, victimValue float64) float64 {
+	move, attackerValue, ok := getLeastValuableAttacker(board, square)
+	if !ok {
+		return 0
+	}
+
+	bCopy := board.Copy()
+	if err := bCopy.Make(move); err != nil {
+		return 0
+	}
+
+	gain := victimValue - seeSquare(bCopy, square, attackerValue)
+	if gain < 0 {
+		return 0 // the side to move is not forced to recapture
+	}
+
+	return gain
+}
+
+/**
+Returns the valid capture onto the square made by the cheapest piece of the side to move, along with that piece's value
+*/
+func getLeastValuableAttacker(board b.Board, square b.Square) (b.Move, float64, bool) {
+	var move b.Move
+
+	for _, pieceType := range [6]b.PieceType{b.PAWN, b.KNIGHT, b.BISHOP, b.ROOK, b.QUEEN, b.KING} {
+		for _, srcSquare := range board.GetPieceBitmap(board.GetTurn(), pieceType).GetSquares() {
+			move = b.NewMove(srcSquare, square).Build()
+			if pieceType == b.PAWN && (square.GetRank() == 1 || square.GetRank() == 8) {
+				move = move.AddPromotionPieceType(b.QUEEN)
+			}
+
+			if err := board.IsValidMove(move); err == nil {
+				return move, getPieceValue(pieceType), true
+			}
+		}
+	}
+
+	return nil, 0, false
+}
@@ -0,0 +1,180 @@
+package minimax_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/evaluation"
+	"galapb/chess2022/pkg/polyglot"
+	"galapb/chess2022/pkg/time_control"
+	"math"
+	"math/rand"
+)
+
+const (
+	// the player plays at full strength at the highest level
+	MIN_SKILL_LEVEL int = 0
+	MAX_SKILL_LEVEL int = 20
+
+	// rough ratings of the lowest and highest levels against humans, the levels in between being spread evenly
+	MIN_ELO int = 800
+	MAX_ELO int = 2000
+
+	// number of best moves a weakened player chooses from
+	SKILL_MULTI_PV int = 4
+
+	// standard deviation of the evaluation noise at the lowest level, in pawns
+	MAX_EVALUATION_NOISE float64 = 1.0
+)
+
+/**
+The ways a player below the highest skill level is weakened, all scaled by the level
+*/
+type skill struct {
+	level int
+
+	// deepest iteration, and positions searched per move
+	depth     int
+	nodeLimit int64
+}
+
+/**
+Weakens the player to the given skill level, between MIN_SKILL_LEVEL and MAX_SKILL_LEVEL. Lower levels search shallower
+and fewer positions, evaluate positions with more noise and more often play a move which is not the best among
+SKILL_MULTI_PV
+*/
+func (mp *MiniMaxPlayer) SetSkillLevel(level int) {
+	if level < MIN_SKILL_LEVEL {
+		level = MIN_SKILL_LEVEL
+	}
+
+	if level >= MAX_SKILL_LEVEL {
+		mp.skill = nil
+		mp.setEvaluator(mp.evaluator)
+		return
+	}
+
+	mp.skill = &skill{
+		level:     level,
+		depth:     1 + level/4,
+		nodeLimit: 100 << (level / 2),
+	}
+
+	noise := MAX_EVALUATION_NOISE * float64(MAX_SKILL_LEVEL-level) / float64(MAX_SKILL_LEVEL)
+	mp.setEvaluator(newNoisyEvaluator(mp.evaluator, noise))
+}
+
+/**
+Weakens the player to about the given rating, by choosing the matching skill level
+*/
+func (mp *MiniMaxPlayer) SetElo(elo int) {
+	level := math.Round(float64((elo-MIN_ELO)*MAX_SKILL_LEVEL) / float64(MAX_ELO-MIN_ELO))
+	mp.SetSkillLevel(int(level))
+}
+
+func (mp *MiniMaxPlayer) setEvaluator(evaluator evaluation.Evaluator) {
+	mp.searcher.evaluator = evaluator
+	for _, helper := range mp.helpers {
+		helper.evaluator = evaluator
+	}
+}
+
+/**
+Searches the best moves within the limits of the skill level, and picks one of them
+*/
+func (mp *MiniMaxPlayer) getSkillMove(board b.Board, maxDepth int, timeManager *time_control.TimeManager) b.Move {
+	lines := mp.analyzeWithSkill(board, maxDepth, timeManager, 1)
+	if len(lines) == 0 {
+		return GetRandomMove(board.GetValidMoves()) // stopped before any move was searched
+	}
+	return lines[0].Moves[0]
+}
+
+/**
+Analyses the board within the limits of the skill level, with the main thread only so that the node limit holds, and
+returns the given number of best lines with the one the skill level picks first
+*/
+func (mp *MiniMaxPlayer) analyzeWithSkill(board b.Board, maxDepth int, timeManager *time_control.TimeManager, multiPV int) []PVLine {
+	if maxDepth > mp.skill.depth {
+		maxDepth = mp.skill.depth
+	}
+
+	// the picked move is chosen among the lines reported too
+	count := SKILL_MULTI_PV
+	if multiPV > count {
+		count = multiPV
+	}
+
+	mp.searcher.nodeLimit = mp.skill.nodeLimit
+	lines := mp.searcher.analyze(board, maxDepth, timeManager, count)
+	mp.searcher.nodeLimit = 0
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	for i := mp.skill.pickLine(lines); i > 0; i-- {
+		lines[i-1], lines[i] = lines[i], lines[i-1]
+	}
+
+	if len(lines) > multiPV {
+		lines = lines[:multiPV]
+	}
+	return lines
+}
+
+/**
+Picks one of the best lines, favoring lines scoring close to the best one and more so at higher levels. Ported from
+the skill level of Stockfish: every move is pushed up by a random amount, bounded by the spread of the scores (at most
+a pawn), while moves far below the best one are pushed up by their distance to it scaled by the weakness of the level
+*/
+func (sk *skill) pickLine(lines []PVLine) int {
+	weakness := float64(120 - 2*sk.level)
+	top := lines[0].Score
+	delta := math.Min(top-lines[len(lines)-1].Score, 1.0)
+
+	var best int
+	var bestScore float64 = math.Inf(-1)
+
+	for i, line := range lines {
+		push := (weakness*(top-line.Score) + delta*rand.Float64()*weakness) / 128
+		if line.Score+push >= bestScore {
+			best, bestScore = i, line.Score+push
+		}
+	}
+
+	return best
+}
+
+type noisyEvaluator struct {
+	evaluator evaluation.Evaluator
+	noise     float64
+
+	// positions get different noise in every game of the player
+	seed uint64
+}
+
+/**
+Returns an evaluator adding gaussian noise with the given standard deviation, in pawns, to the evaluation. The noise
+only depends on the position, so that searches stay consistent with the transposition table
+*/
+func newNoisyEvaluator(evaluator evaluation.Evaluator, noise float64) evaluation.Evaluator {
+	return &noisyEvaluator{evaluator, noise, rand.Uint64()}
+}
+
+func (ne *noisyEvaluator) Evaluate(board b.Board) float64 {
+	x := polyglot.Hash(board) ^ ne.seed
+
+	// two uniform values from the hash of the position, turned into a gaussian one with the Box-Muller transform
+	u1 := (float64(splitMix64(&x)>>11) + 0.5) / (1 << 53)
+	u2 := float64(splitMix64(&x)>>11) / (1 << 53)
+	gaussian := math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+
+	return ne.evaluator.Evaluate(board) + ne.noise*gaussian
+}
+
+func splitMix64(x *uint64) uint64 {
+	*x += 0x9E3779B97F4A7C15
+	z := *x
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
@@ -0,0 +1,70 @@
+package minimax_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"testing"
+)
+
+func TestLowSkillSpreadsMoves(t *testing.T) {
+	mp := New()
+	mp.SetSkillLevel(MIN_SKILL_LEVEL)
+	board := b.Standard()
+
+	// the weakest level often plays another move than its best one
+	moves := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		move := mp.getMove(board, nil)
+		if !containsMove(board.GetValidMoves(), move) {
+			t.Fatalf("\nExpected: \n%s\nActual: \n%s", "a valid move", move)
+		}
+		moves[move.String()] = true
+	}
+
+	if len(moves) < 2 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v", "several moves", moves)
+	}
+}
+
+func TestFullStrengthIsDeterministic(t *testing.T) {
+	board, _ := b.FromFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+
+	getMove := func(skillLevel int) b.Move {
+		mp := New()
+		mp.SetDeterministic(true)
+		mp.SetMaxDepth(3)
+
+		// weakening the player and restoring it leaves no noise in the evaluation
+		mp.SetSkillLevel(skillLevel)
+		mp.SetSkillLevel(MAX_SKILL_LEVEL)
+		if mp.skill != nil {
+			t.Fatalf("\nExpected: \n%s\nActual: \n%v", "full strength", mp.skill)
+		}
+		return mp.getMove(board, nil)
+	}
+
+	expected := getMove(MAX_SKILL_LEVEL)
+	for level := MIN_SKILL_LEVEL; level < MAX_SKILL_LEVEL; level += 5 {
+		if actual := getMove(level); !b.SameMove(actual, expected) {
+			t.Fatalf("\nExpected: \n%s\nActual: \n%s", expected, actual)
+		}
+	}
+}
+
+func TestSetElo(t *testing.T) {
+	mp := New()
+
+	mp.SetElo(MIN_ELO - 100)
+	if mp.skill == nil || mp.skill.level != MIN_SKILL_LEVEL {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%v", MIN_SKILL_LEVEL, mp.skill)
+	}
+
+	mp.SetElo((MIN_ELO + MAX_ELO) / 2)
+	if mp.skill == nil || mp.skill.level != (MIN_SKILL_LEVEL+MAX_SKILL_LEVEL)/2 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%v", (MIN_SKILL_LEVEL+MAX_SKILL_LEVEL)/2, mp.skill)
+	}
+
+	mp.SetElo(MAX_ELO)
+	if mp.skill != nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v", "full strength", mp.skill)
+	}
+}
@@ -0,0 +1,149 @@
+package minimax_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"math"
+	"sync/atomic"
+)
+
+// number of entries of the transposition table, 16 bytes each
+const TT_SIZE int = 1 << 18
+
+// kinds of scores stored in the transposition table, as in a fail-soft alpha-beta search
+const (
+	EXACT_BOUND uint64 = iota
+	LOWER_BOUND        // the search failed high, the score is at least this
+	UPPER_BOUND        // the search failed low, the score is at most this
+)
+
+// layout of the data of an entry: score (32 bits), depth (8), bound (2), move (16)
+const (
+	DEPTH_SHIFT = 32
+	BOUND_SHIFT = 40
+	MOVE_SHIFT  = 42
+)
+
+/**
+Remembers the results of searches by position, shared by all the threads of a search without locks: every entry is
+stored as two words, the key xor the data and the data, so that an entry torn by concurrent writes doesn't match its
+key and is ignored
+*/
+type transpositionTable struct {
+	entries []uint64
+}
+
+type ttEntry struct {
+	score float64
+	depth int
+	bound uint64
+	move  b.Move
+}
+
+func newTranspositionTable(size int) *transpositionTable {
+	return &transpositionTable{make([]uint64, 2*size)}
+}
+
+func (tt *transpositionTable) probe(key uint64, ply int) (ttEntry, bool) {
+	i := 2 * int(key%uint64(len(tt.entries)/2))
+
+	check := atomic.LoadUint64(&tt.entries[i])
+	data := atomic.LoadUint64(&tt.entries[i+1])
+	if check^data != key || data == 0 {
+		return ttEntry{}, false
+	}
+
+	return ttEntry{
+		score: fromTTScore(float64(math.Float32frombits(uint32(data))), ply),
+		depth: int(data >> DEPTH_SHIFT & 0xFF),
+		bound: data >> BOUND_SHIFT & 0x3,
+		move:  decodeTTMove(data >> MOVE_SHIFT & 0xFFFF),
+	}, true
+}
+
+/**
+Stores the result of a search, unless a deeper search of the same position is already stored
+*/
+func (tt *transpositionTable) store(key uint64, ply int, entry ttEntry) {
+	i := 2 * int(key%uint64(len(tt.entries)/2))
+
+	check := atomic.LoadUint64(&tt.entries[i])
+	data := atomic.LoadUint64(&tt.entries[i+1])
+	if check^data == key && int(data>>DEPTH_SHIFT&0xFF) > entry.depth {
+		return
+	}
+
+	data = uint64(math.Float32bits(float32(toTTScore(entry.score, ply))))
+	data |= uint64(entry.depth) << DEPTH_SHIFT
+	data |= entry.bound << BOUND_SHIFT
+	data |= encodeTTMove(entry.move) << MOVE_SHIFT
+
+	atomic.StoreUint64(&tt.entries[i], key^data)
+	atomic.StoreUint64(&tt.entries[i+1], data)
+}
+
+/**
+Returns the fill rate of the table in permille, estimated from its first entries
+*/
+func (tt *transpositionTable) getHashFull() int {
+	var full int = 0
+	for i := 0; i < 1000 && 2*i < len(tt.entries); i++ {
+		if atomic.LoadUint64(&tt.entries[2*i+1]) != 0 {
+			full++
+		}
+	}
+	return full
+}
+
+/**
+Converts mate and tablebase scores, which count plies from the root, to count plies from the stored position instead,
+since the same position can be reached at different plies
+*/
+func toTTScore(score float64, ply int) float64 {
+	switch {
+	case score >= TB_WIN_SCORE-float64(MAX_PLY):
+		return score + float64(ply)
+	case score <= -TB_WIN_SCORE+float64(MAX_PLY):
+		return score - float64(ply)
+	}
+	return score
+}
+
+func fromTTScore(score float64, ply int) float64 {
+	switch {
+	case score >= TB_WIN_SCORE-float64(MAX_PLY):
+		return score - float64(ply)
+	case score <= -TB_WIN_SCORE+float64(MAX_PLY):
+		return score + float64(ply)
+	}
+	return score
+}
+
+/**
+Packs the move in 16 bits: a flag for the presence of a move, the source and destination squares, and the promotion
+*/
+func encodeTTMove(move b.Move) uint64 {
+	if move == nil || move.IsEmpty() {
+		return 0
+	}
+
+	encoded := uint64(1) | uint64(move.GetSrcSquare().GetIndex())<<1 | uint64(move.GetDstSquare().GetIndex())<<7
+	if promotion := move.GetPromotionPieceType(); promotion != nil {
+		encoded |= uint64(*promotion) << 13
+	}
+
+	return encoded
+}
+
+func decodeTTMove(encoded uint64) b.Move {
+	if encoded&1 == 0 {
+		return nil
+	}
+
+	src, dst := int(encoded>>1&0x3F), int(encoded>>7&0x3F)
+	builder := b.NewMove(b.GetSquareFromRankAndFile(src/8+1, src%8+1), b.GetSquareFromRankAndFile(dst/8+1, dst%8+1))
+	if promotion := b.PieceType(encoded >> 13 & 0x7); promotion != 0 {
+		builder = builder.PromotionPieceType(promotion)
+	}
+
+	return builder.Build()
+}
@@ -0,0 +1,119 @@
+package evaluator
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"math/rand"
+
+	"github.com/yaricom/goNEAT/v2/neat/genetics"
+)
+
+/**
+Two players playing a game with each color from the same opening, by their indices among the organisms of the
+population followed by the hall of fame
+*/
+type pairing struct {
+	a, b int
+}
+
+/**
+Pairs the organisms with each other and with the hall of fame, plays the games of the pairings, counting them in the
+stats, and sets the fitness of the organisms from the ratings fit to the games
+*/
+func (ne *neatPlayerEvaluator) coevolve(pop *genetics.Population, source func() int64, stats *generationStats) error {
+	n := len(pop.Organisms)
+	players := append(append([]*genetics.Organism{}, pop.Organisms...), ne.champions...)
+	pairings := ne.getPairings(n, len(ne.champions), rand.New(rand.NewSource(source())))
+
+	results := make([]gameResult, 2*len(pairings))
+	err := ne.runJobs(len(pairings), "pairings", source, func(k int, r *rand.Rand) error {
+		return ne.playPairing(players, pairings[k], n, r, results[2*k:2*k+2], stats)
+	})
+	if err != nil {
+		return err
+	}
+
+	ratings := fitRatings(len(players), results)
+	for i, org := range pop.Organisms {
+		org.Fitness = getExpectedScore(ratings[i])
+	}
+
+	return nil
+}
+
+/**
+Returns the pairings of the organisms: every organism with every other one if pairings is 0, otherwise with that many
+organisms drawn from the others, and every organism with every member of the hall of fame
+*/
+func (ne *neatPlayerEvaluator) getPairings(n, hallOfFameSize int, r *rand.Rand) []pairing {
+	var pairings []pairing
+
+	for i := 0; i < n; i++ {
+		if ne.pairings <= 0 || ne.pairings >= n-1 {
+			for j := i + 1; j < n; j++ {
+				pairings = append(pairings, pairing{i, j})
+			}
+		} else {
+			// the others, shuffled, so that an organism is never paired twice with the same one
+			others := r.Perm(n - 1)
+			for _, j := range others[:ne.pairings] {
+				if j >= i {
+					j++
+				}
+				pairings = append(pairings, pairing{i, j})
+			}
+		}
+
+		for h := 0; h < hallOfFameSize; h++ {
+			pairings = append(pairings, pairing{i, n + h})
+		}
+	}
+
+	return pairings
+}
+
+/**
+Plays a game with each color between the players of the pairing, from the same opening, and writes their results. The
+games are counted in the stats from the perspective of the first player, which is always an organism of the population
+(the first n players)
+*/
+func (ne *neatPlayerEvaluator) playPairing(players []*genetics.Organism, p pairing, n int, r *rand.Rand, results []gameResult, stats *generationStats) error {
+	opponent := POPULATION_OPPONENT
+	if p.b >= n {
+		opponent = HALL_OF_FAME_OPPONENT
+	}
+
+	opening := ne.drawOpening(r)
+
+	for k, colors := range [2][2]int{{p.a, p.b}, {p.b, p.a}} {
+		// organisms play several games at the same time, so every game gets its own copies of their networks
+		white, err := cloneOrganism(players[colors[0]])
+		if err != nil {
+			return err
+		}
+		black, err := cloneOrganism(players[colors[1]])
+		if err != nil {
+			return err
+		}
+
+		whitePlayer, whiteCounter := ne.newPlayer(white, newRand(r))
+		blackPlayer, blackCounter := ne.newPlayer(black, newRand(r))
+		g, result, err := ne.playGame(whitePlayer, blackPlayer, opening)
+		if err != nil {
+			return err
+		}
+		stats.addActivations(whiteCounter)
+		stats.addActivations(blackCounter)
+
+		c := b.WHITE
+		if k == 1 {
+			c = b.BLACK
+		}
+		stats.addGame(opponent, g, result, c)
+
+		var rec record
+		rec.addResult(result, b.WHITE)
+		results[k] = gameResult{white: colors[0], black: colors[1], score: rec.getScore()}
+	}
+
+	return nil
+}
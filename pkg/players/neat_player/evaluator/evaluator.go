@@ -1,76 +1,526 @@
 package evaluator
 
 import (
+	"fmt"
+	b "galapb/chess2022/pkg/board"
 	"galapb/chess2022/pkg/game"
+	"galapb/chess2022/pkg/players/minimax_player"
 	neat_player "galapb/chess2022/pkg/players/neat_player/player"
 	"galapb/chess2022/pkg/players/player"
-	"galapb/chess2022/pkg/players/random_player"
 	"galapb/chess2022/pkg/time_control"
 	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
 
 	"github.com/yaricom/goNEAT/v2/experiment"
 	"github.com/yaricom/goNEAT/v2/neat"
 	"github.com/yaricom/goNEAT/v2/neat/genetics"
 )
 
+// progress is logged every time this fraction of the population has been evaluated
+const PROGRESS_STEP float64 = 0.1
+
+// the best organism of every generation is saved to this directory of the output path, see neat_player.Load
+const CHAMPIONS_DIR string = "champions"
+
+type NeatPlayerEvaluatorBuilder interface {
+	Encoder(*neat_player.InputEncoder) NeatPlayerEvaluatorBuilder
+	SearchDepth(int) NeatPlayerEvaluatorBuilder
+	Workers(int) NeatPlayerEvaluatorBuilder
+	Verbose(bool) NeatPlayerEvaluatorBuilder
+	GamesPerColor(int) NeatPlayerEvaluatorBuilder
+	PlyLimit(int) NeatPlayerEvaluatorBuilder
+	Opponents(...Opponent) NeatPlayerEvaluatorBuilder
+	Openings([]string) NeatPlayerEvaluatorBuilder
+	Tiebreaks(material, moveCount float64) NeatPlayerEvaluatorBuilder
+	Seed(int64) NeatPlayerEvaluatorBuilder
+	Coevolution(pairings int) NeatPlayerEvaluatorBuilder
+	HallOfFame(size int) NeatPlayerEvaluatorBuilder
+	Build() NeatPlayerEvaluator
+}
+
+/**
+Evaluates generations of organisms playing chess. Its hall of fame is the only state kept from a generation to the next,
+so it can be saved and restored to resume an experiment, whose generations past the checkpoint are removed from the
+metrics before they are evaluated again
+*/
+type NeatPlayerEvaluator interface {
+	experiment.GenerationEvaluator
+	GetHallOfFame() []*genetics.Genome
+	SetHallOfFame([]*genetics.Genome) error
+	TruncateMetrics(trial, generation int) error
+}
+
 type neatPlayerEvaluator struct {
 	// The output path to store execution results
 	OutputPath string
+
+	// Turns boards into the inputs of the organisms' networks
+	encoder *neat_player.InputEncoder
+
+	// If not 0, the organisms evaluate positions for a minimax search to this depth instead of picking moves
+	searchDepth int
+
+	// number of organisms evaluated at the same time
+	workers int
+
+	// logs the games and every organism's results, instead of a summary of the generation
+	verbose bool
+
+	// every organism plays this many pairs of games, one with each color, against an opponent drawn from the pool and
+	// from an opening drawn from the list
+	gamesPerColor int
+	opponents     []Opponent
+	openings      []string
+
+	// games are drawn once they reach this ply
+	plyLimit int
+
+	// weights of the tiebreaks added to the score of every game
+	materialWeight  float64
+	moveCountWeight float64
+
+	// the games of a generation only depend on this seed if it is not 0, otherwise on the global source
+	seed int64
+
+	// organisms play each other and the hall of fame instead of the opponent pool, and their fitness comes from ratings
+	// fit to the games of the generation. Every organism is paired with this many others, or all of them if 0
+	coevolution bool
+	pairings    int
+
+	// copies of the best organisms of the last generations (the hall of fame), for the champion opponents and coevolution
+	champions      []*genetics.Organism
+	hallOfFameSize int
+}
+
+/**
+Returns an evaluator playing 2 games per color against the random player from the default openings, with the default
+encoder and as many workers as CPUs
+*/
+func NewNeatPlayerGenerationEvaluator(outputPath string) NeatPlayerEvaluatorBuilder {
+	return &neatPlayerEvaluator{
+		OutputPath:      outputPath,
+		encoder:         neat_player.NewInputEncoder().Build(),
+		workers:         runtime.NumCPU(),
+		gamesPerColor:   2,
+		opponents:       []Opponent{{Kind: RANDOM_OPPONENT, Weight: 1}},
+		openings:        DEFAULT_OPENINGS,
+		plyLimit:        DEFAULT_PLY_LIMIT,
+		materialWeight:  DEFAULT_MATERIAL_TIEBREAK,
+		moveCountWeight: DEFAULT_MOVE_COUNT_TIEBREAK,
+		hallOfFameSize:  DEFAULT_HALL_OF_FAME_SIZE,
+	}
 }
 
-func NewNeatPlayerGenerationEvaluator(outputPath string) experiment.GenerationEvaluator {
-	return &neatPlayerEvaluator{OutputPath: outputPath}
+func (ne *neatPlayerEvaluator) Encoder(encoder *neat_player.InputEncoder) NeatPlayerEvaluatorBuilder {
+	ne.encoder = encoder
+	return ne
+}
+
+func (ne *neatPlayerEvaluator) SearchDepth(searchDepth int) NeatPlayerEvaluatorBuilder {
+	ne.searchDepth = searchDepth
+	return ne
+}
+
+func (ne *neatPlayerEvaluator) Workers(workers int) NeatPlayerEvaluatorBuilder {
+	ne.workers = workers
+	return ne
+}
+
+func (ne *neatPlayerEvaluator) Verbose(verbose bool) NeatPlayerEvaluatorBuilder {
+	ne.verbose = verbose
+	return ne
+}
+
+func (ne *neatPlayerEvaluator) GamesPerColor(gamesPerColor int) NeatPlayerEvaluatorBuilder {
+	ne.gamesPerColor = gamesPerColor
+	return ne
+}
+
+func (ne *neatPlayerEvaluator) PlyLimit(plyLimit int) NeatPlayerEvaluatorBuilder {
+	ne.plyLimit = plyLimit
+	return ne
+}
+
+func (ne *neatPlayerEvaluator) Opponents(opponents ...Opponent) NeatPlayerEvaluatorBuilder {
+	ne.opponents = opponents
+	return ne
+}
+
+/**
+Sets the positions games start from, as FENs with white to move, or the standard position if empty
+*/
+func (ne *neatPlayerEvaluator) Openings(openings []string) NeatPlayerEvaluatorBuilder {
+	ne.openings = openings
+	return ne
+}
+
+/**
+Sets the weights of the material and move count tiebreaks, which separate organisms with the same results
+*/
+func (ne *neatPlayerEvaluator) Tiebreaks(material, moveCount float64) NeatPlayerEvaluatorBuilder {
+	ne.materialWeight = material
+	ne.moveCountWeight = moveCount
+	return ne
+}
+
+/**
+Makes the games of every generation reproducible from the seed
+*/
+func (ne *neatPlayerEvaluator) Seed(seed int64) NeatPlayerEvaluatorBuilder {
+	ne.seed = seed
+	return ne
+}
+
+/**
+Makes organisms play each other instead of the opponent pool: every organism plays a pair of games against the given
+number of organisms drawn from the population, or against all of them if 0 (a round robin), and against every member of
+the hall of fame. Their fitness is their expected score against a player rated BASE_RATING, from Elo ratings fit to all
+the games of the generation
+*/
+func (ne *neatPlayerEvaluator) Coevolution(pairings int) NeatPlayerEvaluatorBuilder {
+	ne.coevolution = true
+	ne.pairings = pairings
+	return ne
+}
+
+/**
+Sets the number of champions of past generations kept as opponents
+*/
+func (ne *neatPlayerEvaluator) HallOfFame(size int) NeatPlayerEvaluatorBuilder {
+	ne.hallOfFameSize = size
+	return ne
+}
+
+func (ne *neatPlayerEvaluator) Build() NeatPlayerEvaluator {
+	if len(ne.opponents) == 0 {
+		ne.opponents = []Opponent{{Kind: RANDOM_OPPONENT, Weight: 1}}
+	}
+	if ne.plyLimit <= 0 {
+		ne.plyLimit = DEFAULT_PLY_LIMIT
+	}
+	return ne
+}
+
+/**
+Returns the genomes of the hall of fame, from the oldest champion to the latest
+*/
+func (ne *neatPlayerEvaluator) GetHallOfFame() []*genetics.Genome {
+	genomes := make([]*genetics.Genome, len(ne.champions))
+	for i, champion := range ne.champions {
+		genomes[i] = champion.Genotype
+	}
+	return genomes
+}
+
+/**
+Replaces the hall of fame with organisms of the given genomes, from the oldest champion to the latest
+*/
+func (ne *neatPlayerEvaluator) SetHallOfFame(genomes []*genetics.Genome) error {
+	champions := make([]*genetics.Organism, 0, len(genomes))
+	for _, genome := range genomes {
+		genesisMutex.Lock()
+		champion, err := genetics.NewOrganism(0, genome, 1)
+		genesisMutex.Unlock()
+		if err != nil {
+			return err
+		}
+		champions = append(champions, champion)
+	}
+
+	ne.champions = champions
+	return nil
+}
+
+/**
+The results of the games of an organism, from its perspective
+*/
+type record struct {
+	wins, draws, losses int
+}
+
+func (r *record) add(other record) {
+	r.wins += other.wins
+	r.draws += other.draws
+	r.losses += other.losses
+}
+
+/**
+Returns the average score of the games, 0.5 if there are none
+*/
+func (r *record) getScore() float64 {
+	games := r.wins + r.draws + r.losses
+	if games == 0 {
+		return 0.5
+	}
+	return (float64(r.wins) + float64(r.draws)/2) / float64(games)
+}
+
+/**
+Counts the result of a game for the player of the given color
+*/
+func (r *record) addResult(result game.Result, c b.Color) {
+	switch {
+	case result == game.GAME_DRAWN:
+		r.draws++
+	case (result == game.WHITE_WINS) == (c == b.WHITE):
+		r.wins++
+	default:
+		r.losses++
+	}
 }
 
 // This method evaluates one epoch for given population and prints results into output directory if any
 func (ne *neatPlayerEvaluator) GenerationEvaluate(pop *genetics.Population, epoch *experiment.Generation, context *neat.Options) (err error) {
-	for i, org := range pop.Organisms {
-		log.Printf("Evaluating Organism %d out of %d", i, len(pop.Organisms))
-
-		tc := time_control.Builder().Minutes(3).Build()
-
-		var whitePlayer player.Player
-		var blackPlayer player.Player
-		var result game.Result
-		var g game.Game
-		var score float64 = 0
-
-		// play a game as white
-		whitePlayer = neat_player.New(org)
-		blackPlayer = random_player.New()
-		g = game.New(tc, whitePlayer, blackPlayer).Verbose(true).PlyLimit(1000).Build()
-		result, _ = g.Run()
-		switch result {
-		case game.BLACK_WINS:
-			log.Println("Organism lost as white")
-			score += 0.0
-		case game.WHITE_WINS:
-			log.Println("Organism won as white")
-			score += 0.5
-		case game.GAME_DRAWN:
-			log.Println("Organism drew as white")
-			score += 0.25
-		}
+	start := time.Now()
+
+	// the seeds of the games are drawn from this source before the games start, so that the games only depend on it
+	source := rand.Int63
+	if ne.seed != 0 {
+		source = rand.New(rand.NewSource(ne.seed + int64(epoch.TrialId)<<32 + int64(epoch.Id))).Int63
+	}
 
-		// ... and as black
-		whitePlayer = random_player.New()
-		blackPlayer = neat_player.New(org)
-		g = game.New(tc, whitePlayer, blackPlayer).Verbose(true).PlyLimit(1000).Build()
-		result, _ = g.Run()
-		switch result {
-		case game.BLACK_WINS:
-			log.Println("Organism won as black")
-			score += 0.5
-		case game.WHITE_WINS:
-			log.Println("Organism lost as black")
-			score += 0.0
-		case game.GAME_DRAWN:
-			log.Println("Organism drew as black")
-			score += 0.25
+	stats := newGenerationStats()
+	if ne.coevolution {
+		err = ne.coevolve(pop, source, stats)
+	} else {
+		err = ne.playTournaments(pop, source, stats)
+	}
+	if err != nil {
+		return err
+	}
+
+	epoch.FillPopulationStatistics(pop)
+
+	if err := ne.writeChampion(epoch); err != nil {
+		return err
+	}
+
+	if champion, err := cloneOrganism(epoch.Best); err == nil {
+		ne.champions = append(ne.champions, champion)
+		if len(ne.champions) > ne.hallOfFameSize {
+			ne.champions = ne.champions[len(ne.champions)-ne.hallOfFameSize:]
 		}
+	}
+
+	metrics := ne.getMetrics(pop, epoch, stats, time.Since(start).Seconds())
+	if err := ne.writeMetrics(metrics); err != nil {
+		return err
+	}
+
+	total := stats.getOpponentsRecord()
+	log.Printf(
+		"Generation %d: %d organisms played %d games in %s (%.1f games/s), +%d =%d -%d against opponents, best fitness %.3f, mean %.3f, median %.3f, %d species",
+		epoch.Id, len(pop.Organisms), stats.games, time.Since(start).Round(time.Millisecond), float64(stats.games)/time.Since(start).Seconds(),
+		total.wins, total.draws, total.losses, metrics.BestFitness, metrics.MeanFitness, metrics.MedianFitness, metrics.Species,
+	)
+	if stats.failedActivations > 0 {
+		log.Printf("Generation %d: networks could not be activated %d times out of %d, and played at random or scored positions even", epoch.Id, stats.failedActivations, stats.activations)
+	}
+
+	return nil
+}
+
+/**
+Saves the genome of the best organism of the generation to CHAMPIONS_DIR, unless the output path is empty
+*/
+func (ne *neatPlayerEvaluator) writeChampion(epoch *experiment.Generation) error {
+	if ne.OutputPath == "" {
+		return nil
+	}
+
+	dir := filepath.Join(ne.OutputPath, CHAMPIONS_DIR)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("trial_%d_generation_%d.yml", epoch.TrialId, epoch.Id))
+	return neat_player.WriteGenomeFile(path, epoch.Best.Genotype)
+}
 
-		org.Fitness = score
+/**
+Runs the jobs with the workers, every job with its own source of random numbers seeded from the given source, and logs
+the progress. Returns the first error of the jobs
+*/
+func (ne *neatPlayerEvaluator) runJobs(n int, name string, source func() int64, job func(i int, r *rand.Rand) error) error {
+	start := time.Now()
+
+	seeds := make([]int64, n)
+	for i := range seeds {
+		seeds[i] = source()
+	}
+
+	var done int = 0
+	var mutex sync.Mutex
+	errs := make([]error, n)
+
+	workers := ne.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = job(i, rand.New(rand.NewSource(seeds[i])))
+
+				mutex.Lock()
+				done++
+				if step := int(PROGRESS_STEP * float64(n)); !ne.verbose && step > 0 && done%step == 0 && done < n {
+					log.Printf("Played %d of %d %s in %s", done, n, name, time.Since(start).Round(time.Second))
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+/**
+Evaluates every organism against the opponent pool, counting the games in the stats
+*/
+func (ne *neatPlayerEvaluator) playTournaments(pop *genetics.Population, source func() int64, stats *generationStats) error {
+	return ne.runJobs(len(pop.Organisms), "tournaments", source, func(i int, r *rand.Rand) error {
+		rec, err := ne.evaluateOrganism(pop.Organisms[i], r, stats)
+		if ne.verbose {
+			log.Printf("Organism %d: %d wins, %d draws, %d losses", i, rec.wins, rec.draws, rec.losses)
+		}
+		return err
+	})
+}
+
+/**
+Plays the pairs of games of an organism and sets its fitness to the average fitness of its games. The games are played
+with a copy of the organism's network and draw their random numbers from the given source, so that organisms can be
+evaluated concurrently
+*/
+func (ne *neatPlayerEvaluator) evaluateOrganism(org *genetics.Organism, r *rand.Rand, stats *generationStats) (record, error) {
+	var rec record
+
+	clone, err := cloneOrganism(org)
+	if err != nil {
+		return rec, err
+	}
+
+	var fitness float64 = 0
+	for k := 0; k < ne.gamesPerColor; k++ {
+		opponent := ne.opponents[drawOpponent(ne.opponents, r)]
+		opening := ne.drawOpening(r)
+
+		for _, c := range []b.Color{b.WHITE, b.BLACK} {
+			// players run in their own goroutines, so they get their own sources
+			organismPlayer, counter := ne.newPlayer(clone, newRand(r))
+			opponentPlayer, played := ne.newOpponent(opponent, newRand(r))
+			var whitePlayer, blackPlayer player.Player = organismPlayer, opponentPlayer
+			if c == b.BLACK {
+				whitePlayer, blackPlayer = blackPlayer, whitePlayer
+			}
+
+			g, result, err := ne.playGame(whitePlayer, blackPlayer, opening)
+			if err != nil {
+				return rec, err
+			}
+
+			rec.addResult(result, c)
+			stats.addGame(played.String(), g, result, c)
+			stats.addActivations(counter)
+			fitness += ne.getGameFitness(g, result, c)
+		}
+	}
+
+	if ne.gamesPerColor > 0 {
+		fitness /= float64(2 * ne.gamesPerColor)
+	}
+
+	org.Fitness = fitness
+	return rec, nil
+}
+
+/**
+Plays a game from the opening, or from the standard position if the opening is empty
+*/
+func (ne *neatPlayerEvaluator) playGame(whitePlayer, blackPlayer player.Player, opening string) (game.Game, game.Result, error) {
+	board := b.Standard()
+	if opening != "" {
+		var err error
+		if board, err = b.FromFEN(opening); err != nil {
+			return nil, game.UNDETERMINED, err
+		}
+	}
+
+	// untimed, since the players are bounded by depth and the clock would only make the results less reproducible
+	g := game.New(time_control.Builder().Build(), whitePlayer, blackPlayer).
+		Board(board).
+		Verbose(ne.verbose).
+		PlyLimit(ne.plyLimit).
+		Build()
+	result, _ := g.Run()
+
+	return g, result, nil
+}
+
+func (ne *neatPlayerEvaluator) drawOpening(r *rand.Rand) string {
+	if len(ne.openings) == 0 {
+		return ""
+	}
+	return ne.openings[r.Intn(len(ne.openings))]
+}
+
+// building a network writes to its genome, so networks are built one at a time
+var genesisMutex sync.Mutex
+
+/**
+Returns a copy of the organism with its own network
+*/
+func cloneOrganism(org *genetics.Organism) (*genetics.Organism, error) {
+	genesisMutex.Lock()
+	phenotype, err := org.Genotype.Genesis(org.Genotype.Id)
+	genesisMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return &genetics.Organism{Phenotype: phenotype, Genotype: org.Genotype, Fitness: org.Fitness}, nil
+}
+
+/**
+Returns a player for the organism, and what counts the failed activations of its network
+*/
+func (ne *neatPlayerEvaluator) newPlayer(org *genetics.Organism, r *rand.Rand) (player.Player, neat_player.ActivationCounter) {
+	if ne.searchDepth > 0 {
+		evaluator := neat_player.NewNetworkEvaluator(org, ne.encoder)
+		mp := minimax_player.NewWithTableSize(evaluator, EVALUATION_TT_SIZE)
+		mp.SetMaxDepth(ne.searchDepth)
+		mp.SetDeterministic(true)
+		return mp, evaluator.(neat_player.ActivationCounter)
+	}
+
+	np := neat_player.New(org)
+	np.SetEncoder(ne.encoder)
+	np.SetRand(r)
+	return np, np
+}
+
+func newRand(r *rand.Rand) *rand.Rand {
+	return rand.New(rand.NewSource(r.Int63()))
+}
@@ -0,0 +1,331 @@
+package evaluator
+
+import (
+	"bytes"
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/game"
+	neat_player "galapb/chess2022/pkg/players/neat_player/player"
+	"galapb/chess2022/pkg/players/random_player"
+	"galapb/chess2022/pkg/time_control"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yaricom/goNEAT/v2/experiment"
+	"github.com/yaricom/goNEAT/v2/neat/genetics"
+)
+
+const EPSILON float64 = 1e-9
+
+func newTestEvaluator() *neatPlayerEvaluator {
+	return NewNeatPlayerGenerationEvaluator("").
+		PlyLimit(100).
+		Tiebreaks(0.1, 0.05).
+		Opponents(Opponent{Kind: RANDOM_OPPONENT, Weight: 1}, Opponent{Kind: MINIMAX_OPPONENT, Weight: 1, Depth: 2}).
+		Build().(*neatPlayerEvaluator)
+}
+
+/**
+Returns a game which ended in the position, without playing it
+*/
+func newTestGame(t *testing.T, fen string) game.Game {
+	board, err := b.FromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return game.New(time_control.Builder().Build(), random_player.New(), random_player.New()).Board(board).Build()
+}
+
+func TestGameFitness(t *testing.T) {
+	ne := newTestEvaluator()
+
+	// 50 plies, half the ply limit
+	rookUp := newTestGame(t, "4k3/8/8/8/8/8/8/4K2R w - - 0 26")
+	queenUp := newTestGame(t, "4k3/8/8/8/8/8/8/Q3K3 w - - 0 26")
+	material := (9/MAX_MATERIAL_DIFFERENCE + 1) / 2
+
+	tests := []struct {
+		g        game.Game
+		result   game.Result
+		c        b.Color
+		expected float64
+	}{
+		{rookUp, game.WHITE_WINS, b.WHITE, 1 + 0.1*(5/MAX_MATERIAL_DIFFERENCE+1)/2 + 0.05*0.5},
+		{queenUp, game.GAME_DRAWN, b.WHITE, 0.5 + 0.1*material + 0.05*0.5},
+		{queenUp, game.GAME_DRAWN, b.BLACK, 0.5 + 0.1*(1-material) + 0.05*0.5},
+		{queenUp, game.WHITE_WINS, b.BLACK, 0 + 0.1*(1-material) + 0.05*0.5},
+		{queenUp, game.BLACK_WINS, b.BLACK, 1 + 0.1*(1-material) + 0.05*0.5},
+	}
+
+	for _, test := range tests {
+		if actual := ne.getGameFitness(test.g, test.result, test.c); math.Abs(actual-test.expected) > EPSILON {
+			t.Fatalf("\nExpected: \n%f\nActual: \n%f", test.expected, actual)
+		}
+	}
+
+	// quicker wins and slower losses earn more
+	quick := newTestGame(t, "4k3/8/8/8/8/8/8/4K2R w - - 0 6")
+	if ne.getGameFitness(quick, game.WHITE_WINS, b.WHITE) <= ne.getGameFitness(rookUp, game.WHITE_WINS, b.WHITE) {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "quicker win scoring higher", "slower win scoring higher")
+	}
+	if ne.getGameFitness(quick, game.BLACK_WINS, b.WHITE) >= ne.getGameFitness(rookUp, game.BLACK_WINS, b.WHITE) {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "quicker loss scoring lower", "slower loss scoring lower")
+	}
+}
+
+/**
+Counts fixed numbers of moves asked from a network and of failed activations
+*/
+type testCounter struct {
+	activations       int
+	failedActivations int
+}
+
+func (c testCounter) GetActivations() int {
+	return c.activations
+}
+
+func (c testCounter) GetFailedActivations() int {
+	return c.failedActivations
+}
+
+func TestMetricsAggregation(t *testing.T) {
+	ne := newTestEvaluator()
+
+	pop := &genetics.Population{}
+	for _, fitness := range []float64{0.2, 0.9, 0.4, 0.5} {
+		pop.Organisms = append(pop.Organisms, &genetics.Organism{Fitness: fitness})
+	}
+	epoch := &experiment.Generation{Id: 3, TrialId: 1, Diversity: 2, Best: pop.Organisms[1]}
+
+	stats := newGenerationStats()
+	g := newTestGame(t, "4k3/8/8/8/8/8/8/4K2R w - - 0 26")
+	stats.addGame("random", g, game.WHITE_WINS, b.WHITE)
+	stats.addGame("random", g, game.WHITE_WINS, b.BLACK)
+	stats.addGame("random", g, game.GAME_DRAWN, b.BLACK)
+	stats.addGame("random", g, game.BLACK_WINS, b.BLACK)
+	stats.addGame("minimax2", g, game.BLACK_WINS, b.WHITE)
+	stats.addActivations(testCounter{30, 3})
+	stats.addActivations(testCounter{50, 0})
+
+	m := ne.getMetrics(pop, epoch, stats, 2)
+
+	if m.Trial != 1 || m.Generation != 3 || m.Organisms != 4 || m.Species != 2 || m.Games != 5 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%+v", "trial 1, generation 3, 4 organisms, 2 species, 5 games", m)
+	}
+	if math.Abs(m.BestFitness-0.9) > EPSILON || math.Abs(m.MeanFitness-0.5) > EPSILON || math.Abs(m.MedianFitness-0.45) > EPSILON {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%f, %f, %f", "0.9, 0.5, 0.45", m.BestFitness, m.MeanFitness, m.MedianFitness)
+	}
+	if m.GameLength != 50 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%f", 50, m.GameLength)
+	}
+	if math.Abs(m.RandomMoveRate-3.0/80) > EPSILON {
+		t.Fatalf("\nExpected: \n%f\nActual: \n%f", 3.0/80, m.RandomMoveRate)
+	}
+
+	expected := []OpponentMetrics{
+		{Opponent: "random", Games: 4, WinRate: 0.5, DrawRate: 0.25, LossRate: 0.25},
+		{Opponent: "minimax2", Games: 1, WinRate: 0, DrawRate: 0, LossRate: 1},
+	}
+	if len(m.Opponents) != len(expected) {
+		t.Fatalf("\nExpected: \n%v\nActual: \n%v", expected, m.Opponents)
+	}
+	for i := range expected {
+		if m.Opponents[i] != expected[i] {
+			t.Fatalf("\nExpected: \n%v\nActual: \n%v", expected, m.Opponents)
+		}
+	}
+}
+
+/**
+Returns an organism of the start genome, whose network picks moves
+*/
+func newTestOrganism(t *testing.T) *genetics.Organism {
+	var buf bytes.Buffer
+	if err := neat_player.WriteStartGenome(&buf, neat_player.NewInputEncoder().Build(), neat_player.OUTPUT_SIZE); err != nil {
+		t.Fatal(err)
+	}
+	r, err := genetics.NewGenomeReader(&buf, genetics.YAMLGenomeEncoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genome, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	org, err := genetics.NewOrganism(0, genome, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return org
+}
+
+func TestChampionGamesWithoutHallOfFame(t *testing.T) {
+	ne := NewNeatPlayerGenerationEvaluator("").
+		PlyLimit(10).
+		GamesPerColor(2).
+		Opponents(Opponent{Kind: CHAMPION_OPPONENT, Weight: 1}, Opponent{Kind: MINIMAX_OPPONENT, Weight: 0, Depth: 1}).
+		Build().(*neatPlayerEvaluator)
+
+	expected := []string{"champion", "random", "minimax1"}
+	if names := ne.getOpponentNames(); len(names) != len(expected) || names[0] != expected[0] || names[1] != expected[1] || names[2] != expected[2] {
+		t.Fatalf("\nExpected: \n%v\nActual: \n%v", expected, names)
+	}
+
+	// the random player stands in for the champions, and its games are counted as such
+	stats := newGenerationStats()
+	if _, err := ne.evaluateOrganism(newTestOrganism(t), rand.New(rand.NewSource(1)), stats); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stats.records["champion"]; ok {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v", "no games against champions", stats.records)
+	}
+	if rec := stats.records["random"]; rec == nil || rec.wins+rec.draws+rec.losses != 4 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v", "4 games against the random player", stats.records)
+	}
+}
+
+/**
+Returns the games of the win matrix, in which wins[i][j] is the number of games player i won against player j, and
+draws[i][j] the number of draws between them, counted once for i < j
+*/
+func newTestResults(wins, draws [][]int) []gameResult {
+	var results []gameResult
+	for i := range wins {
+		for j := range wins[i] {
+			for k := 0; k < wins[i][j]; k++ {
+				results = append(results, gameResult{white: i, black: j, score: 1})
+			}
+			if i < j {
+				for k := 0; k < draws[i][j]; k++ {
+					results = append(results, gameResult{white: i, black: j, score: 0.5})
+				}
+			}
+		}
+	}
+	return results
+}
+
+func TestFitRatings(t *testing.T) {
+	wins := [][]int{
+		{0, 6, 8, 9},
+		{2, 0, 5, 7},
+		{1, 3, 0, 5},
+		{0, 1, 4, 0},
+	}
+	draws := [][]int{
+		{0, 2, 1, 1},
+		{0, 0, 2, 2},
+		{0, 0, 0, 1},
+		{0, 0, 0, 0},
+	}
+	results := newTestResults(wins, draws)
+	ratings := fitRatings(len(wins), results)
+
+	for i := 1; i < len(ratings); i++ {
+		if ratings[i] >= ratings[i-1] {
+			t.Fatalf("\nExpected: \n%s\nActual: \n%v", "ratings in decreasing order", ratings)
+		}
+	}
+
+	// at the maximum likelihood, every player scores as much as expected from the ratings, the draws against the anchor
+	// included, which the fit reaches within a hundredth of a game
+	actual := make([]float64, len(ratings))
+	expected := make([]float64, len(ratings))
+	for i, rating := range ratings {
+		actual[i] = PRIOR_GAMES / 2
+		expected[i] = PRIOR_GAMES * getExpectedScore(rating)
+	}
+	for _, r := range results {
+		p := 1 / (1 + math.Pow(10, (ratings[r.black]-ratings[r.white])/400))
+		actual[r.white] += r.score
+		actual[r.black] += 1 - r.score
+		expected[r.white] += p
+		expected[r.black] += 1 - p
+	}
+	for i := range ratings {
+		if math.Abs(actual[i]-expected[i]) > 0.01 {
+			t.Fatalf("\nExpected: \n%v\nActual: \n%v", expected, actual)
+		}
+	}
+}
+
+func TestFitRatingsOfEvenPlayers(t *testing.T) {
+	// every player won as many games as they lost against every other one
+	wins := [][]int{{0, 3, 1}, {3, 0, 2}, {1, 2, 0}}
+	draws := [][]int{{0, 1, 0}, {0, 0, 4}, {0, 0, 0}}
+
+	for _, rating := range fitRatings(len(wins), newTestResults(wins, draws)) {
+		if math.Abs(rating-BASE_RATING) > 1e-6 {
+			t.Fatalf("\nExpected: \n%f\nActual: \n%f", BASE_RATING, rating)
+		}
+	}
+
+	// players without games keep the base rating
+	if ratings := fitRatings(2, nil); ratings[0] != BASE_RATING || ratings[1] != BASE_RATING {
+		t.Fatalf("\nExpected: \n%f\nActual: \n%v", BASE_RATING, ratings)
+	}
+}
+
+func TestTruncateMetrics(t *testing.T) {
+	dir := t.TempDir()
+	ne := NewNeatPlayerGenerationEvaluator(dir).
+		Opponents(Opponent{Kind: RANDOM_OPPONENT, Weight: 1}, Opponent{Kind: MINIMAX_OPPONENT, Weight: 1, Depth: 2}).
+		Build().(*neatPlayerEvaluator)
+
+	for _, trial := range []int{0, 1} {
+		for generation := 0; generation < 5; generation++ {
+			m := GenerationMetrics{Trial: trial, Generation: generation, RandomMoveRate: 0.25}
+			for _, name := range ne.getOpponentNames() {
+				m.Opponents = append(m.Opponents, OpponentMetrics{Opponent: name})
+			}
+			if err := ne.writeMetrics(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// resumed before generation 3 of the second trial
+	if err := ne.TruncateMetrics(1, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, METRICS_CSV_FILE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(rows) != 1+8 || rows[0] != strings.Join(ne.getMetricsHeader(), ",") || !strings.HasPrefix(rows[8], "1,2,") {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "the header and 8 generations, up to generation 2 of trial 1", data)
+	}
+	if header, row := strings.Split(rows[0], ","), strings.Split(rows[8], ","); header[10] != "random_move_rate" || row[10] != "0.2500" {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s, %s", "random_move_rate 0.25", header[10], row[10])
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, METRICS_JSON_FILE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 8 || !strings.HasPrefix(lines[7], `{"trial":1,"generation":2,`) {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "8 generations, up to generation 2 of trial 1", data)
+	}
+
+	// nor do the columns of metrics written before the random move rate
+	old := strings.Replace(strings.Join(rows, "\n")+"\n", "game_length,random_move_rate,", "game_length,", 1)
+	if err := os.WriteFile(filepath.Join(dir, METRICS_CSV_FILE), []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ne.TruncateMetrics(1, 3); err == nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v", "mismatched columns", err)
+	}
+
+	// the columns of other opponents don't match
+	other := NewNeatPlayerGenerationEvaluator(dir).Opponents(Opponent{Kind: CHAMPION_OPPONENT, Weight: 1}).Build()
+	if err := other.TruncateMetrics(1, 3); err == nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v", "mismatched columns", err)
+	}
+}
@@ -0,0 +1,184 @@
+package evaluator
+
+import (
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/evaluation"
+	"galapb/chess2022/pkg/game"
+	"galapb/chess2022/pkg/players/minimax_player"
+	"galapb/chess2022/pkg/players/player"
+	"galapb/chess2022/pkg/players/random_player"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// games are drawn once they reach this ply, unless the evaluator sets another limit
+const DEFAULT_PLY_LIMIT int = 1000
+
+// number of champions of past generations kept in the hall of fame, as opponents
+const DEFAULT_HALL_OF_FAME_SIZE int = 10
+
+// number of entries of the transposition tables of the minimax players of the games, which search a few plies deep: every
+// game gets its own table, so that games only depend on their seeds, and the default size would allocate megabytes per game
+const EVALUATION_TT_SIZE int = 1 << 14
+
+// weights of the material and move count tiebreaks, unless the evaluator sets others
+const DEFAULT_MATERIAL_TIEBREAK float64 = 0.1
+const DEFAULT_MOVE_COUNT_TIEBREAK float64 = 0.05
+
+// material of both sides at the start of a game, in pawns, which scales the material tiebreak
+const MAX_MATERIAL_DIFFERENCE float64 = 39
+
+// common openings after 4 plies, white to move, so that games don't all repeat the same moves
+var DEFAULT_OPENINGS []string = []string{
+	"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", // open game
+	"rnbqkbnr/pp2pppp/3p4/2p5/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 3",  // sicilian defence
+	"rnbqkbnr/ppp2ppp/4p3/3p4/3PP3/8/PPP2PPP/RNBQKBNR w KQkq d6 0 3",   // french defence
+	"rnbqkbnr/pp2pppp/2p5/3p4/3PP3/8/PPP2PPP/RNBQKBNR w KQkq d6 0 3",   // caro-kann defence
+	"rnbqkbnr/ppp2ppp/4p3/3p4/2PP4/8/PP2PPPP/RNBQKBNR w KQkq - 0 3",    // queen's gambit declined
+	"rnbqkb1r/pppppp1p/5np1/8/2PP4/8/PP2PPPP/RNBQKBNR w KQkq - 0 3",    // king's indian defence
+	"rnbqkb1r/pppp1ppp/4pn2/8/2PP4/8/PP2PPPP/RNBQKBNR w KQkq - 0 3",    // nimzo/queen's indian defence
+	"rnbqkb1r/pppp1ppp/5n2/4p3/2P5/2N5/PP1PPPPP/R1BQKBNR w KQkq - 2 3", // english opening
+	"rnbqkb1r/ppp1pppp/5n2/3p4/8/5NP1/PPPPPP1P/RNBQKB1R w KQkq - 1 3",  // king's indian attack
+	"rnb1kbnr/ppp1pppp/8/3q4/8/8/PPPP1PPP/RNBQKBNR w KQkq - 0 3",       // scandinavian defence
+}
+
+type OpponentKind int
+
+const (
+	RANDOM_OPPONENT OpponentKind = iota
+	MINIMAX_OPPONENT
+	CHAMPION_OPPONENT // the best organism of a past generation, or the random player in the first generation
+)
+
+/**
+A kind of player organisms are evaluated against, drawn for each pair of games with a probability proportional to its
+weight
+*/
+type Opponent struct {
+	Kind   OpponentKind
+	Weight float64
+
+	// depth of the searches of minimax opponents
+	Depth int
+}
+
+func (o Opponent) String() string {
+	switch o.Kind {
+	case MINIMAX_OPPONENT:
+		return fmt.Sprintf("minimax%d", o.Depth)
+	case CHAMPION_OPPONENT:
+		return "champion"
+	}
+	return "random"
+}
+
+/**
+Parses a comma-separated list of opponents in the format of String, each optionally followed by a colon and its weight
+(1 by default), such as "random:1,minimax2:0.5,champion"
+*/
+func ParseOpponents(s string) ([]Opponent, error) {
+	var opponents []Opponent
+
+	for _, field := range strings.Split(s, ",") {
+		name, weight := strings.TrimSpace(field), "1"
+		if i := strings.Index(name, ":"); i >= 0 {
+			name, weight = name[:i], name[i+1:]
+		}
+
+		o := Opponent{Kind: RANDOM_OPPONENT}
+		var err error
+		if o.Weight, err = strconv.ParseFloat(weight, 64); err != nil || o.Weight < 0 {
+			return nil, fmt.Errorf("invalid weight of opponent %s: %s", name, weight)
+		}
+
+		switch {
+		case name == "random":
+		case name == "champion":
+			o.Kind = CHAMPION_OPPONENT
+		case strings.HasPrefix(name, "minimax"):
+			o.Kind = MINIMAX_OPPONENT
+			if o.Depth, err = strconv.Atoi(strings.TrimPrefix(name, "minimax")); err != nil || o.Depth < 1 {
+				return nil, fmt.Errorf("invalid depth of opponent %s", name)
+			}
+		default:
+			return nil, fmt.Errorf("unknown opponent %s", name)
+		}
+
+		opponents = append(opponents, o)
+	}
+
+	return opponents, nil
+}
+
+/**
+Returns a new opponent of the given kind, playing with the given source of random numbers, and the kind of opponent it
+actually is: the random player stands in for champions until the hall of fame has one
+*/
+func (ne *neatPlayerEvaluator) newOpponent(o Opponent, r *rand.Rand) (player.Player, Opponent) {
+	switch o.Kind {
+	case MINIMAX_OPPONENT:
+		// deterministic, so that games only depend on their seeds
+		mp := minimax_player.NewWithTableSize(evaluation.NewPositionalEvaluator(evaluation.DefaultWeights()), EVALUATION_TT_SIZE)
+		mp.SetMaxDepth(o.Depth)
+		mp.SetDeterministic(true)
+		return mp, o
+	case CHAMPION_OPPONENT:
+		// champions are shared by the workers, so every game gets its own copy of their network
+		if len(ne.champions) > 0 {
+			if champion, err := cloneOrganism(ne.champions[r.Intn(len(ne.champions))]); err == nil {
+				p, _ := ne.newPlayer(champion, r)
+				return p, o
+			}
+		}
+	}
+	return random_player.NewWithRand(r), Opponent{Kind: RANDOM_OPPONENT, Weight: o.Weight}
+}
+
+/**
+Returns the index of an opponent drawn with a probability proportional to its weight
+*/
+func drawOpponent(opponents []Opponent, r *rand.Rand) int {
+	var total float64 = 0
+	for _, o := range opponents {
+		total += o.Weight
+	}
+
+	x := r.Float64() * total
+	for i, o := range opponents {
+		if x < o.Weight {
+			return i
+		}
+		x -= o.Weight
+	}
+
+	return len(opponents) - 1
+}
+
+/**
+Returns the fitness earned by a game, from the perspective of the organism playing the given color: 1 for a win, 0.5 for
+a draw and 0 for a loss, plus the tiebreaks, which are between 0 and their weight. The material tiebreak rewards being
+ahead in material at the end of the game, and the move count tiebreak rewards winning quickly and losing slowly
+*/
+func (ne *neatPlayerEvaluator) getGameFitness(g game.Game, result game.Result, c b.Color) float64 {
+	var score, moves float64 = 0.5, 0.5
+	plies := math.Min(float64(g.GetBoard().GetPly())/float64(ne.plyLimit), 1)
+
+	switch {
+	case result == game.GAME_DRAWN:
+	case (result == game.WHITE_WINS) == (c == b.WHITE):
+		score, moves = 1, 1-plies
+	default:
+		score, moves = 0, plies
+	}
+
+	material := evaluation.NewMaterialEvaluator().Evaluate(g.GetBoard())
+	if c == b.BLACK {
+		material = -material
+	}
+	material = (math.Max(-1, math.Min(1, material/MAX_MATERIAL_DIFFERENCE)) + 1) / 2
+
+	return score + ne.materialWeight*material + ne.moveCountWeight*moves
+}
@@ -0,0 +1,364 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/game"
+	neat_player "galapb/chess2022/pkg/players/neat_player/player"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yaricom/goNEAT/v2/experiment"
+	"github.com/yaricom/goNEAT/v2/neat/genetics"
+)
+
+// the metrics of every generation are appended to these files of the output path, so that runs can be charted while
+// they are ongoing
+const METRICS_CSV_FILE string = "metrics.csv"
+const METRICS_JSON_FILE string = "metrics.jsonl"
+
+// with coevolution, organisms play the other organisms of the population and the hall of fame
+const POPULATION_OPPONENT string = "population"
+const HALL_OF_FAME_OPPONENT string = "hall_of_fame"
+
+/**
+The metrics of a generation, written as a row of METRICS_CSV_FILE and a line of METRICS_JSON_FILE
+*/
+type GenerationMetrics struct {
+	Trial      int `json:"trial"`
+	Generation int `json:"generation"`
+
+	Organisms int     `json:"organisms"`
+	Species   int     `json:"species"`
+	Games     int     `json:"games"`
+	Seconds   float64 `json:"seconds"`
+
+	BestFitness   float64 `json:"best_fitness"`
+	MeanFitness   float64 `json:"mean_fitness"`
+	MedianFitness float64 `json:"median_fitness"`
+
+	// average number of plies of the games, openings included
+	GameLength float64 `json:"game_length"`
+
+	// rate of the moves and positions asked from the networks that were played at random or scored even, because the
+	// networks could not be activated
+	RandomMoveRate float64 `json:"random_move_rate"`
+
+	// the results of the organisms against each kind of opponent, in the order of getOpponentNames
+	Opponents []OpponentMetrics `json:"opponents"`
+}
+
+/**
+The results of the organisms against a kind of opponent, as rates of their games
+*/
+type OpponentMetrics struct {
+	Opponent string  `json:"opponent"`
+	Games    int     `json:"games"`
+	WinRate  float64 `json:"win_rate"`
+	DrawRate float64 `json:"draw_rate"`
+	LossRate float64 `json:"loss_rate"`
+}
+
+/**
+The results and lengths of the games of a generation, by kind of opponent, gathered by the workers
+*/
+type generationStats struct {
+	records map[string]*record
+	games   int
+	plies   int
+
+	// moves and positions asked from the networks, and those they could not score
+	activations       int
+	failedActivations int
+
+	mutex sync.Mutex
+}
+
+func newGenerationStats() *generationStats {
+	return &generationStats{records: make(map[string]*record)}
+}
+
+/**
+Counts a game against the opponent, from the perspective of the organism playing the given color
+*/
+func (s *generationStats) addGame(opponent string, g game.Game, result game.Result, c b.Color) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rec, ok := s.records[opponent]
+	if !ok {
+		rec = &record{}
+		s.records[opponent] = rec
+	}
+	rec.addResult(result, c)
+
+	s.games++
+	s.plies += g.GetBoard().GetPly()
+}
+
+/**
+Counts the moves and positions asked from a network of a game, and those it could not score
+*/
+func (s *generationStats) addActivations(counter neat_player.ActivationCounter) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.activations += counter.GetActivations()
+	s.failedActivations += counter.GetFailedActivations()
+}
+
+/**
+Returns the results against all the opponents but the population
+*/
+func (s *generationStats) getOpponentsRecord() record {
+	var total record
+	for opponent, rec := range s.records {
+		if opponent != POPULATION_OPPONENT {
+			total.add(*rec)
+		}
+	}
+	return total
+}
+
+/**
+Returns the names of the kinds of opponents of the organisms, which are the columns of the opponents in the metrics.
+Champions come with the random player, which stands in for them until the hall of fame has one
+*/
+func (ne *neatPlayerEvaluator) getOpponentNames() []string {
+	if ne.coevolution {
+		return []string{POPULATION_OPPONENT, HALL_OF_FAME_OPPONENT}
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+
+	for _, o := range ne.opponents {
+		add(o.String())
+		if o.Kind == CHAMPION_OPPONENT {
+			add(Opponent{Kind: RANDOM_OPPONENT}.String())
+		}
+	}
+	return names
+}
+
+func (ne *neatPlayerEvaluator) getMetrics(pop *genetics.Population, epoch *experiment.Generation, stats *generationStats, seconds float64) GenerationMetrics {
+	m := GenerationMetrics{
+		Trial:       epoch.TrialId,
+		Generation:  epoch.Id,
+		Organisms:   len(pop.Organisms),
+		Species:     epoch.Diversity,
+		Games:       stats.games,
+		Seconds:     seconds,
+		BestFitness: epoch.Best.Fitness,
+	}
+
+	fitness := make([]float64, len(pop.Organisms))
+	for i, org := range pop.Organisms {
+		fitness[i] = org.Fitness
+		m.MeanFitness += org.Fitness / float64(len(pop.Organisms))
+	}
+	sort.Float64s(fitness)
+	if n := len(fitness); n > 0 {
+		m.MedianFitness = (fitness[(n-1)/2] + fitness[n/2]) / 2
+	}
+
+	if stats.games > 0 {
+		m.GameLength = float64(stats.plies) / float64(stats.games)
+	}
+	if stats.activations > 0 {
+		m.RandomMoveRate = float64(stats.failedActivations) / float64(stats.activations)
+	}
+
+	for _, name := range ne.getOpponentNames() {
+		om := OpponentMetrics{Opponent: name}
+		if rec, ok := stats.records[name]; ok {
+			om.Games = rec.wins + rec.draws + rec.losses
+			if om.Games > 0 {
+				om.WinRate = float64(rec.wins) / float64(om.Games)
+				om.DrawRate = float64(rec.draws) / float64(om.Games)
+				om.LossRate = float64(rec.losses) / float64(om.Games)
+			}
+		}
+		m.Opponents = append(m.Opponents, om)
+	}
+
+	return m
+}
+
+/**
+Appends the metrics to METRICS_CSV_FILE, whose header is written when the file is created, and to METRICS_JSON_FILE,
+unless the output path is empty
+*/
+func (ne *neatPlayerEvaluator) writeMetrics(m GenerationMetrics) error {
+	if ne.OutputPath == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(ne.OutputPath, os.ModePerm); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := appendToFile(filepath.Join(ne.OutputPath, METRICS_JSON_FILE), append(line, '\n')); err != nil {
+		return err
+	}
+
+	path := filepath.Join(ne.OutputPath, METRICS_CSV_FILE)
+	_, err = os.Stat(path)
+	created := os.IsNotExist(err)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(f)
+	if created {
+		w.Write(ne.getMetricsHeader())
+	}
+
+	row := []string{
+		strconv.Itoa(m.Trial), strconv.Itoa(m.Generation), strconv.Itoa(m.Organisms), strconv.Itoa(m.Species),
+		strconv.Itoa(m.Games), formatFloat(m.Seconds), formatFloat(m.BestFitness), formatFloat(m.MeanFitness),
+		formatFloat(m.MedianFitness), formatFloat(m.GameLength), formatFloat(m.RandomMoveRate),
+	}
+	for _, om := range m.Opponents {
+		row = append(row, strconv.Itoa(om.Games), formatFloat(om.WinRate), formatFloat(om.DrawRate), formatFloat(om.LossRate))
+	}
+	w.Write(row)
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+/**
+Returns the columns of METRICS_CSV_FILE, with the results against each kind of opponent of the evaluator
+*/
+func (ne *neatPlayerEvaluator) getMetricsHeader() []string {
+	header := []string{"trial", "generation", "organisms", "species", "games", "seconds", "best_fitness", "mean_fitness", "median_fitness", "game_length", "random_move_rate"}
+	for _, name := range ne.getOpponentNames() {
+		header = append(header, name+"_games", name+"_win_rate", name+"_draw_rate", name+"_loss_rate")
+	}
+	return header
+}
+
+/**
+Removes the metrics of the generations from the given generation of the trial on, which are evaluated again when an
+experiment is resumed from a checkpoint. Fails if the columns of METRICS_CSV_FILE are not those of the opponents of the
+evaluator, since the rows of the resumed experiment wouldn't match them
+*/
+func (ne *neatPlayerEvaluator) TruncateMetrics(trial, generation int) error {
+	if ne.OutputPath == "" {
+		return nil
+	}
+
+	before := func(t, g int) bool {
+		return t < trial || t == trial && g < generation
+	}
+
+	path := filepath.Join(ne.OutputPath, METRICS_CSV_FILE)
+	if data, err := os.ReadFile(path); err == nil {
+		rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			return fmt.Errorf("invalid metrics in %s: %w", path, err)
+		}
+
+		header := ne.getMetricsHeader()
+		if len(rows) == 0 || strings.Join(rows[0], ",") != strings.Join(header, ",") {
+			return fmt.Errorf("the columns of %s don't match the opponents %s", path, strings.Join(ne.getOpponentNames(), ","))
+		}
+
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(header)
+		for _, row := range rows[1:] {
+			t, errTrial := strconv.Atoi(row[0])
+			g, errGeneration := strconv.Atoi(row[1])
+			if errTrial != nil || errGeneration != nil {
+				return fmt.Errorf("invalid metrics in %s: %v", path, row)
+			}
+			if before(t, g) {
+				w.Write(row)
+			}
+		}
+		w.Flush()
+
+		if err := replaceFile(path, buf.Bytes()); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	path = filepath.Join(ne.OutputPath, METRICS_JSON_FILE)
+	if data, err := os.ReadFile(path); err == nil {
+		var buf bytes.Buffer
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+
+			var m GenerationMetrics
+			if err := json.Unmarshal(line, &m); err != nil {
+				return fmt.Errorf("invalid metrics in %s: %w", path, err)
+			}
+			if before(m.Trial, m.Generation) {
+				buf.Write(append(line, '\n'))
+			}
+		}
+
+		if err := replaceFile(path, buf.Bytes()); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+/**
+Replaces the content of the file, writing it to a temporary file first so that an interruption never leaves it partial
+*/
+func replaceFile(path string, data []byte) error {
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func appendToFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to append to %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+func formatFloat(x float64) string {
+	return strconv.FormatFloat(x, 'f', 4, 64)
+}
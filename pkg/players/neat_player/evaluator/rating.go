@@ -0,0 +1,73 @@
+package evaluator
+
+import "math"
+
+// rating of the anchor every player is assumed to have drawn PRIOR_GAMES games against, which keeps the ratings of
+// players who won or lost all their games finite
+const (
+	BASE_RATING float64 = 1500
+	PRIOR_GAMES float64 = 1
+)
+
+// iterations of the fit of the ratings, which converges in a few dozens for the games of a generation
+const RATING_ITERATIONS int = 200
+
+/**
+The result of a game between two players, by their indices
+*/
+type gameResult struct {
+	white, black int
+
+	// score of white: 1 for a win, 0.5 for a draw and 0 for a loss
+	score float64
+}
+
+/**
+Returns the Elo ratings of the players best explaining the results, by maximum likelihood of the Bradley-Terry model
+with the minorization-maximization algorithm, draws counting as half a win for each player
+*/
+func fitRatings(numPlayers int, results []gameResult) []float64 {
+	// strengths of the players: a player of strength x scores x / (x + y) against a player of strength y
+	strengths := make([]float64, numPlayers)
+	wins := make([]float64, numPlayers)
+	for i := range strengths {
+		strengths[i] = 1
+		wins[i] = PRIOR_GAMES / 2
+	}
+
+	for _, r := range results {
+		wins[r.white] += r.score
+		wins[r.black] += 1 - r.score
+	}
+
+	for iteration := 0; iteration < RATING_ITERATIONS; iteration++ {
+		denominators := make([]float64, numPlayers)
+		for i := range denominators {
+			denominators[i] = PRIOR_GAMES / (strengths[i] + 1)
+		}
+
+		for _, r := range results {
+			d := 1 / (strengths[r.white] + strengths[r.black])
+			denominators[r.white] += d
+			denominators[r.black] += d
+		}
+
+		for i := range strengths {
+			strengths[i] = wins[i] / denominators[i]
+		}
+	}
+
+	ratings := make([]float64, numPlayers)
+	for i, strength := range strengths {
+		ratings[i] = BASE_RATING + 400*math.Log10(strength)
+	}
+
+	return ratings
+}
+
+/**
+Returns the expected score of a player with the given rating against a player rated BASE_RATING
+*/
+func getExpectedScore(rating float64) float64 {
+	return 1 / (1 + math.Pow(10, (BASE_RATING-rating)/400))
+}
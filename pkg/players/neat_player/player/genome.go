@@ -0,0 +1,136 @@
+package player
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/yaricom/goNEAT/v2/neat/genetics"
+	"github.com/yaricom/goNEAT/v2/neat/network"
+)
+
+/**
+Writes the genome the population starts from, in the YAML format read by the experiment: a sensor for every input of
+the encoder, the outputs (OUTPUT_SIZE to pick moves, VALUE_OUTPUT_SIZE to evaluate positions) and a bias, numbered in
+that order as the experiment expects, with a zero-weight connection from the bias to every output, leaving the rest of
+the topology to evolution. An output without connections would never be activated, and neither would the network
+*/
+func WriteStartGenome(w io.Writer, encoder *InputEncoder, numOutputs int) error {
+	numInputs := encoder.GetInputSize()
+	biasId := numInputs + numOutputs + 1
+
+	lines := []string{
+		"genome:",
+		"  id: 1",
+		"  # The traits used in this genome",
+		"  traits:",
+		"    - {id: 1,  params: [0.1, 0.0, 0.0, 0.0, 0.0, 0.0, 0.0, 0.0]}",
+		"",
+		"  # The neuron nodes for this genome",
+		"  nodes:",
+		fmt.Sprintf("    # The input nodes - sensors, see InputEncoder (%d inputs)", numInputs),
+	}
+
+	for id := 1; id <= numInputs; id++ {
+		lines = append(lines, fmt.Sprintf("    - {id: %d,  trait_id: 0, type: INPT, activation: NullActivation}", id))
+	}
+
+	lines = append(lines, "    # The output nodes - actuators")
+	for i := 1; i <= numOutputs; i++ {
+		lines = append(lines, fmt.Sprintf("    - {id: %d,  trait_id: 0, type: OUTP, activation: SigmoidPlainActivation}", numInputs+i))
+	}
+
+	lines = append(lines,
+		"",
+		"    # The bias nodes",
+		fmt.Sprintf("    - {id: %d,  trait_id: 0, type: BIAS, activation: NullActivation}", biasId),
+		"",
+		"  # The genes - connection between neuron nodes within this genome",
+		"  genes:",
+	)
+	for i := 1; i <= numOutputs; i++ {
+		lines = append(lines, fmt.Sprintf("    - {src_id: %d,  tgt_id: %d,  weight: 0.0, trait_id: 1, innov_num: %d,  mut_num: 0, recurrent: false, enabled: true}", biasId, numInputs+i, i))
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+/**
+Reads a genome in the YAML format, such as the start genome or a champion saved by the evaluator
+*/
+func ReadGenomeFile(path string) (*genetics.Genome, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := genetics.NewGenomeReader(f, genetics.YAMLGenomeEncoding)
+	if err != nil {
+		return nil, err
+	}
+	return r.Read()
+}
+
+/**
+Writes a genome in the YAML format read by ReadGenomeFile
+*/
+func WriteGenomeFile(path string, genome *genetics.Genome) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w, err := genetics.NewGenomeWriter(f, genetics.YAMLGenomeEncoding)
+	if err == nil {
+		err = w.WriteGenome(genome)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+/**
+Returns the number of sensors of the genome, bias excluded
+*/
+func GetNumInputs(genome *genetics.Genome) int {
+	var n int = 0
+	for _, node := range genome.Nodes {
+		if node.NeuronType == network.InputNeuron {
+			n++
+		}
+	}
+	return n
+}
+
+/**
+Returns the number of outputs of the genome
+*/
+func GetNumOutputs(genome *genetics.Genome) int {
+	var n int = 0
+	for _, node := range genome.Nodes {
+		if node.NeuronType == network.OutputNeuron {
+			n++
+		}
+	}
+	return n
+}
+
+/**
+Returns the encoder producing as many inputs as the genome has sensors
+*/
+func GetInputEncoderFor(genome *genetics.Genome) (*InputEncoder, error) {
+	n := GetNumInputs(genome)
+
+	for _, attackMaps := range []bool{false, true} {
+		if encoder := NewInputEncoder().AttackMaps(attackMaps).Build(); encoder.GetInputSize() == n {
+			return encoder, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no encoder produces %d inputs", n)
+}
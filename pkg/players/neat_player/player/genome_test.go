@@ -0,0 +1,123 @@
+package player
+
+import (
+	"bytes"
+	b "galapb/chess2022/pkg/board"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/yaricom/goNEAT/v2/neat"
+	"github.com/yaricom/goNEAT/v2/neat/genetics"
+)
+
+/**
+Returns the start genome written for the encoder and number of outputs
+*/
+func newTestGenome(t *testing.T, encoder *InputEncoder, numOutputs int) *genetics.Genome {
+	var buf bytes.Buffer
+	if err := WriteStartGenome(&buf, encoder, numOutputs); err != nil {
+		t.Fatal(err)
+	}
+	return readTestGenome(t, buf.String())
+}
+
+func readTestGenome(t *testing.T, yaml string) *genetics.Genome {
+	r, err := genetics.NewGenomeReader(strings.NewReader(yaml), genetics.YAMLGenomeEncoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genome, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return genome
+}
+
+/**
+Returns an organism of the start genome with the given outputs, with the genes added to its only gene
+*/
+func newTestOrganism(t *testing.T, numOutputs int, genes ...string) *genetics.Organism {
+	var buf bytes.Buffer
+	if err := WriteStartGenome(&buf, NewInputEncoder().Build(), numOutputs); err != nil {
+		t.Fatal(err)
+	}
+	for _, gene := range genes {
+		buf.WriteString("    - " + gene + "\n")
+	}
+
+	org, err := genetics.NewOrganism(0, readTestGenome(t, buf.String()), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return org
+}
+
+func TestStartGenomeSpawnsPopulation(t *testing.T) {
+	f, err := os.Open("config/params.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	options, err := neat.LoadYAMLOptions(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, encoder := range []*InputEncoder{NewInputEncoder().Build(), NewInputEncoder().AttackMaps(true).Build()} {
+		for _, numOutputs := range []int{OUTPUT_SIZE, VALUE_OUTPUT_SIZE} {
+			genome := newTestGenome(t, encoder, numOutputs)
+
+			if n := GetNumOutputs(genome); n != numOutputs {
+				t.Fatalf("\nExpected: \n%d\nActual: \n%d", numOutputs, n)
+			}
+			actual, err := GetInputEncoderFor(genome)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual.GetInputSize() != encoder.GetInputSize() {
+				t.Fatalf("\nExpected: \n%d\nActual: \n%d", encoder.GetInputSize(), actual.GetInputSize())
+			}
+
+			pop, err := genetics.NewPopulation(genome, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = pop.Verify(); err != nil {
+				t.Fatal(err)
+			}
+
+			// every output is connected, so the networks of the population can be activated
+			for _, org := range pop.Organisms {
+				if _, err = org.Phenotype.Activate(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+}
+
+func TestFailedActivationPlaysValidMove(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteStartGenome(&buf, NewInputEncoder().Build(), OUTPUT_SIZE); err != nil {
+		t.Fatal(err)
+	}
+
+	// without its connection, the first output is never activated
+	genome := readTestGenome(t, strings.Replace(buf.String(), "enabled: true", "enabled: false", 1))
+	org, err := genetics.NewOrganism(0, genome, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	np := New(org)
+	board := b.Standard()
+	move := np.getMove(board)
+
+	if err := board.Make(move); err != nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "a valid move", move)
+	}
+	if np.GetFailedActivations() != 1 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 1, np.GetFailedActivations())
+	}
+}
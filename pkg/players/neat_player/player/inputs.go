@@ -0,0 +1,79 @@
+package player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/evaluation"
+	"galapb/chess2022/pkg/network"
+	"math/bits"
+)
+
+// the planes, castling rights, en-passant file and halfmove clock of network.EncodeBoard, and the side to move
+const BASE_INPUT_SIZE int = network.INPUT_SIZE + 1
+
+// squares attacked by the side to move, then by the opponent
+const ATTACK_MAPS_SIZE int = 2 * 64
+
+type InputEncoderBuilder interface {
+	AttackMaps(bool) InputEncoderBuilder
+	Build() *InputEncoder
+}
+
+/**
+Turns a board into the inputs of a network, seen from the side to move like network.EncodeBoard: the pieces of the side
+to move come first and the board is flipped vertically when black is to move
+*/
+type InputEncoder struct {
+	attackMaps bool
+}
+
+func NewInputEncoder() InputEncoderBuilder {
+	return &InputEncoder{}
+}
+
+/**
+Adds the squares attacked by each side to the inputs
+*/
+func (ie *InputEncoder) AttackMaps(attackMaps bool) InputEncoderBuilder {
+	ie.attackMaps = attackMaps
+	return ie
+}
+
+func (ie *InputEncoder) Build() *InputEncoder {
+	return ie
+}
+
+/**
+Returns the number of inputs of the encoding, which must be the number of sensors of the network
+*/
+func (ie *InputEncoder) GetInputSize() int {
+	if ie.attackMaps {
+		return BASE_INPUT_SIZE + ATTACK_MAPS_SIZE
+	}
+	return BASE_INPUT_SIZE
+}
+
+/**
+Encodes the board as GetInputSize() values between 0 and 1: 12 one-hot piece planes, castling rights, en-passant file,
+halfmove clock, side to move (1 for white) and, if enabled, the attack maps
+*/
+func (ie *InputEncoder) Encode(board b.Board) []float64 {
+	inputs := make([]float64, ie.GetInputSize())
+	turn := board.GetTurn()
+
+	copy(inputs, network.EncodeBoard(board))
+	if turn == b.WHITE {
+		inputs[network.INPUT_SIZE] = 1
+	}
+
+	if ie.attackMaps {
+		for side, c := range []b.Color{turn, turn.Opposite()} {
+			offset := BASE_INPUT_SIZE + side*64
+
+			for bm := evaluation.GetAttackedSquares(board, c); bm != 0; bm &= bm - 1 {
+				inputs[offset+b.GetRelativeIndex(turn, bits.TrailingZeros64(uint64(bm))^56)] = 1
+			}
+		}
+	}
+
+	return inputs
+}
@@ -1,20 +1,108 @@
 package player
 
 import (
+	"fmt"
 	b "galapb/chess2022/pkg/board"
 	"math/rand"
 
 	"github.com/yaricom/goNEAT/v2/neat/genetics"
 )
 
+/**
+A player or evaluator counting the moves or positions it could not get from its network, because the network could not
+be activated
+*/
+type ActivationCounter interface {
+	GetActivations() int
+	GetFailedActivations() int
+}
+
 type NeatPlayer struct {
 	prompt   chan b.Move
 	response chan b.Move
 	org      *genetics.Organism
+	encoder  *InputEncoder
+
+	// moves are sampled from the softmax of their scores with this temperature, or the best move is played if it is 0
+	temperature float64
+	rand        *rand.Rand
+
+	// moves asked from the network, and those played at random because the network could not be activated
+	activations       int
+	failedActivations int
 }
 
+/**
+Returns a player for the organism, whose network takes the inputs of the default encoder
+*/
 func New(org *genetics.Organism) *NeatPlayer {
-	return &NeatPlayer{nil, nil, org}
+	return &NeatPlayer{nil, nil, org, NewInputEncoder().Build(), 0, nil, 0, 0}
+}
+
+/**
+Returns a player for the genome saved at the path, such as a champion saved by the evaluator, with the encoder matching
+its sensors. The genome must pick moves, genomes evaluating positions are loaded with LoadNetworkEvaluator
+*/
+func Load(path string) (*NeatPlayer, error) {
+	genome, err := ReadGenomeFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if n := GetNumOutputs(genome); n != OUTPUT_SIZE {
+		return nil, fmt.Errorf("the genome has %d outputs instead of %d", n, OUTPUT_SIZE)
+	}
+
+	encoder, err := GetInputEncoderFor(genome)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := genetics.NewOrganism(0, genome, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	np := New(org)
+	np.SetEncoder(encoder)
+	return np, nil
+}
+
+/**
+Sets the encoder of the board, which must produce as many inputs as the network has sensors
+*/
+func (np *NeatPlayer) SetEncoder(encoder *InputEncoder) {
+	np.encoder = encoder
+}
+
+/**
+Samples moves from the softmax of their scores divided by the temperature instead of playing the best move, for more
+varied games
+*/
+func (np *NeatPlayer) SetTemperature(temperature float64) {
+	np.temperature = temperature
+}
+
+/**
+Samples moves from the given source instead of the global one, so that concurrent games can be replayed from their seeds
+*/
+func (np *NeatPlayer) SetRand(r *rand.Rand) {
+	np.rand = r
+}
+
+/**
+Returns the number of moves asked from the network, whether it could be activated or not
+*/
+func (np *NeatPlayer) GetActivations() int {
+	return np.activations
+}
+
+/**
+Returns the number of moves played at random because the network could not be activated, such as a recurrent network
+whose outputs never settle
+*/
+func (np *NeatPlayer) GetFailedActivations() int {
+	return np.failedActivations
 }
 
 func (np *NeatPlayer) Init(prompt chan b.Move, response chan b.Move) {
@@ -29,8 +117,7 @@ func (np *NeatPlayer) Start(board b.Board, quit chan bool) {
 		select {
 		case <-quit:
 			return
-		default:
-			move := <-np.prompt
+		case move := <-np.prompt:
 			if err = board.Make(move); err != nil {
 				panic(err)
 			}
@@ -38,136 +125,47 @@ func (np *NeatPlayer) Start(board b.Board, quit chan bool) {
 			response := np.getMove(board)
 			board.Make(response)
 
-			np.response <- response
+			select {
+			case np.response <- response:
+			case <-quit:
+				return
+			}
 		}
 	}
 }
 
+/**
+Returns the best valid move according to the network, or a move sampled from their scores if a temperature is set, so
+that the outputs of the network are never spent on invalid moves. A random valid move is played if the network can't be
+activated
+*/
 func (np *NeatPlayer) getMove(board b.Board) b.Move {
-	var inputs []float64
-	var outputs []float64
-	net := np.org.Phenotype // Neural Network (NN)
-
-	// Send inputs to NN
-	inputs = getNetInputsFromBoard(board)
-	net.LoadSensors(inputs)
-
-	// Run the NN
-	net.Activate()
-
-	// Get output from NN
-	outputs = net.ReadOutputs()
-
-	// Translate NN output to a board move
-	move, isRandomMove := getBoardMoveFromNetOutputs(outputs, board)
-
-	// Penalize the fitness of the organism for making random moves
-	if isRandomMove {
-		np.org.Fitness -= 0.001
-	}
-
-	return move
-}
-
-func getNetInputsFromBoard(board b.Board) []float64 {
-	var inputs []float64 = []float64{
-		float64(board.GetPieceBitmap(b.WHITE, b.KING)),
-		float64(board.GetPieceBitmap(b.WHITE, b.QUEEN)),
-		float64(board.GetPieceBitmap(b.WHITE, b.BISHOP)),
-		float64(board.GetPieceBitmap(b.WHITE, b.KNIGHT)),
-		float64(board.GetPieceBitmap(b.WHITE, b.ROOK)),
-		float64(board.GetPieceBitmap(b.WHITE, b.PAWN)),
-		float64(board.GetPieceBitmap(b.BLACK, b.KING)),
-		float64(board.GetPieceBitmap(b.BLACK, b.QUEEN)),
-		float64(board.GetPieceBitmap(b.BLACK, b.BISHOP)),
-		float64(board.GetPieceBitmap(b.BLACK, b.KNIGHT)),
-		float64(board.GetPieceBitmap(b.BLACK, b.ROOK)),
-		float64(board.GetPieceBitmap(b.BLACK, b.PAWN)),
-
-		// TODO castling rights, en-passent, + other inputs
+	moves := board.GetValidMoves()
+	if len(moves) == 0 {
+		return b.GetEmptyMove()
 	}
 
-	return inputs
-}
-
-func getBoardMoveFromNetOutputs(outputs []float64, board b.Board) (b.Move, bool) {
-	srcIndexes, dstIndexes := getSrcAndDstIndexesFromOutputs(outputs)
-	return getBoardMoveFromIndexes(srcIndexes, dstIndexes, board)
-}
-
-func getBoardMoveFromIndexes(srcIndexes, dstIndexes []int, board b.Board) (b.Move, bool) {
-	var srcSquare b.Square
-	var dstSquare b.Square
-	var move b.Move = nil
-	var err error = nil
-
-	Shuffle(srcIndexes)
-	Shuffle(dstIndexes)
-
-	for _, srcIndex := range srcIndexes {
-		for _, dstIndex := range dstIndexes {
-			srcSquare = b.GetSquareFromIndex(srcIndex)
-			dstSquare = b.GetSquareFromIndex(dstIndex)
-
-			move = b.NewMove(srcSquare, dstSquare).Build()
-
-			if err = board.IsValidMove(move); err == nil {
-				return move, false
-			}
-		}
-	}
-
-	return getRandomMove(board), true
-}
-
-func getSrcAndDstIndexesFromOutputs(outputs []float64) ([]int, []int) {
-	var srcIndexes []int = make([]int, 0)
-	var srcIndexScore float64 = 0
+	net := np.org.Phenotype // Neural Network (NN)
+	np.activations++
 
-	var dstIndexes []int = make([]int, 0)
-	var dstIndexScore float64 = 0
+	// Send inputs to NN, without the activations of the previous move, so that the move only depends on the position
+	net.Flush()
+	err := net.LoadSensors(np.encoder.Encode(board))
 
-	for i, output := range outputs {
-		if i < 64 {
-			if i == 0 || output > srcIndexScore {
-				srcIndexes = []int{i}
-				srcIndexScore = output
-			} else if output == srcIndexScore {
-				srcIndexes = append(srcIndexes, i)
-			}
-		} else {
-			if i == 0 || output > dstIndexScore {
-				dstIndexes = []int{i - 64}
-				dstIndexScore = output
-			} else if output == dstIndexScore {
-				dstIndexes = append(dstIndexes, i-64)
-			}
-		}
+	// Run the NN
+	if err == nil {
+		_, err = net.Activate()
 	}
-
-	return srcIndexes, dstIndexes
-}
-
-func getRandomMove(board b.Board) b.Move {
-	srcSquare := b.GetSquareFromCoord(rand.Intn(8), rand.Intn(8))
-	dstSquare := b.GetSquareFromCoord(rand.Intn(8), rand.Intn(8))
-	move := b.NewMove(srcSquare, dstSquare).Build()
-
-	for board.IsValidMove(move) != nil {
-		srcSquare = b.GetSquareFromCoord(rand.Intn(8), rand.Intn(8))
-		dstSquare = b.GetSquareFromCoord(rand.Intn(8), rand.Intn(8))
-		move = b.NewMove(srcSquare, dstSquare).Build()
-
-		// add a promotion piece if promoting a pawn
-		if piece, _ := board.GetPieceAt(srcSquare); piece != nil && piece.GetPieceType() == b.PAWN && (dstSquare.GetRank() == 1 || dstSquare.GetRank() == 8) {
-			move = move.AddPromotionPieceType(getRandomPromotionPieceType())
+	if err != nil {
+		np.failedActivations++
+		if np.rand != nil {
+			return moves[np.rand.Intn(len(moves))]
 		}
+		return moves[rand.Intn(len(moves))]
 	}
 
-	return move
-}
+	// Translate NN output to a board move
+	scores := ScoreMoves(net.ReadOutputs(), moves, board.GetTurn())
 
-func getRandomPromotionPieceType() b.PieceType {
-	i := rand.Intn(len(b.PROMOTION_PIECE_TYPES))
-	return b.PROMOTION_PIECE_TYPES[i]
+	return moves[SelectMove(scores, np.temperature, np.rand)]
 }
@@ -0,0 +1,29 @@
+package player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"testing"
+)
+
+func TestMoveDoesNotDependOnPreviousPositions(t *testing.T) {
+	// a knight of the side to move on g1 (sensor 128 + 6 + 1) raises the output of the source square g1 (output 782 + 6 +
+	// 1), which also feeds itself, so that it would stay high in the next position if the network wasn't flushed
+	org := newTestOrganism(t, OUTPUT_SIZE,
+		"{src_id: 135,  tgt_id: 789,  weight: 5.0, trait_id: 1, innov_num: 132,  mut_num: 0, recurrent: false, enabled: true}",
+		"{src_id: 789,  tgt_id: 789,  weight: 5.0, trait_id: 1, innov_num: 133,  mut_num: 0, recurrent: true, enabled: true}")
+	np := New(org)
+
+	// every move scores the same, so the first one is played, unless the bishop on g1 is favored
+	bishop, _ := b.FromFEN("4k3/8/8/8/8/8/8/K5B1 w - - 0 1")
+	fresh := np.getMove(bishop)
+
+	knight, _ := b.FromFEN("4k3/8/8/8/8/8/8/K5N1 w - - 0 1")
+	np.getMove(knight)
+
+	if move := np.getMove(bishop); !b.SameMove(move, fresh) {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", fresh, move)
+	}
+	if np.GetActivations() != 3 || np.GetFailedActivations() != 0 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%d, %d", "3, 0", np.GetActivations(), np.GetFailedActivations())
+	}
+}
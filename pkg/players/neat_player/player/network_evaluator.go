@@ -0,0 +1,116 @@
+package player
+
+import (
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/evaluation"
+	"math"
+	"sync"
+
+	"github.com/yaricom/goNEAT/v2/neat/genetics"
+)
+
+// the single output of a network evaluating positions: the expected score of the side to move, between 0 and 1
+const VALUE_OUTPUT_SIZE int = 1
+
+// expected scores are kept this far from 0 and 1, which would be worth infinitely many pawns
+const VALUE_EPSILON float64 = 0.001
+
+/**
+Evaluates positions with the network of an organism, for a search to use in place of a handwritten evaluation
+*/
+type networkEvaluator struct {
+	org     *genetics.Organism
+	encoder *InputEncoder
+
+	// the network keeps the activations of its last evaluation, so the threads of a search take turns
+	mutex sync.Mutex
+
+	// positions scored by the network, and those scored even because the network could not be activated
+	activations       int
+	failedActivations int
+}
+
+/**
+Returns an evaluator whose network takes the inputs of the encoder and has VALUE_OUTPUT_SIZE outputs
+*/
+func NewNetworkEvaluator(org *genetics.Organism, encoder *InputEncoder) evaluation.Evaluator {
+	return &networkEvaluator{org: org, encoder: encoder}
+}
+
+/**
+Returns the number of positions scored by the network, whether it could be activated or not
+*/
+func (ne *networkEvaluator) GetActivations() int {
+	ne.mutex.Lock()
+	defer ne.mutex.Unlock()
+	return ne.activations
+}
+
+/**
+Returns the number of positions scored even because the network could not be activated
+*/
+func (ne *networkEvaluator) GetFailedActivations() int {
+	ne.mutex.Lock()
+	defer ne.mutex.Unlock()
+	return ne.failedActivations
+}
+
+/**
+Returns an evaluator for the genome saved at the path, such as a champion saved by the evaluator, with the encoder
+matching its sensors. The genome must have VALUE_OUTPUT_SIZE outputs
+*/
+func LoadNetworkEvaluator(path string) (evaluation.Evaluator, error) {
+	genome, err := ReadGenomeFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if n := GetNumOutputs(genome); n != VALUE_OUTPUT_SIZE {
+		return nil, fmt.Errorf("the genome has %d outputs instead of %d", n, VALUE_OUTPUT_SIZE)
+	}
+
+	encoder, err := GetInputEncoderFor(genome)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := genetics.NewOrganism(0, genome, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewNetworkEvaluator(org, encoder), nil
+}
+
+/**
+Converts the expected score of the side to move given by the network to pawns from white's perspective, with the
+sigmoid of the tuner (K = 1), so that an expected score of 0.75 is worth about 2 pawns. Positions are scored even if the
+network can't be activated
+*/
+func (ne *networkEvaluator) Evaluate(board b.Board) float64 {
+	inputs := ne.encoder.Encode(board)
+
+	ne.mutex.Lock()
+	net := ne.org.Phenotype
+	ne.activations++
+	net.Flush()
+	err := net.LoadSensors(inputs)
+	if err == nil {
+		_, err = net.Activate()
+	}
+	p := net.ReadOutputs()[0]
+	if err != nil {
+		ne.failedActivations++
+		p = 0.5
+	}
+	ne.mutex.Unlock()
+
+	p = math.Max(VALUE_EPSILON, math.Min(1-VALUE_EPSILON, p))
+	pawns := 4 * math.Log10(p/(1-p))
+
+	if board.GetTurn() == b.BLACK {
+		return -pawns
+	}
+	return pawns
+}
@@ -0,0 +1,70 @@
+package player
+
+import (
+	"bytes"
+	b "galapb/chess2022/pkg/board"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/yaricom/goNEAT/v2/neat/genetics"
+)
+
+func evaluate(t *testing.T, evaluator interface{ Evaluate(b.Board) float64 }, fen string) float64 {
+	board, err := b.FromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return evaluator.Evaluate(board)
+}
+
+func TestNetworkEvaluatorIsSymmetric(t *testing.T) {
+	// the output rises with a queen of the side to move on d1 (the input 64 + 3 of the queen plane, sensor 68)
+	org := newTestOrganism(t, VALUE_OUTPUT_SIZE,
+		"{src_id: 68,  tgt_id: 783,  weight: 2.0, trait_id: 1, innov_num: 2,  mut_num: 0, recurrent: false, enabled: true}")
+	evaluator := NewNetworkEvaluator(org, NewInputEncoder().Build())
+
+	white := evaluate(t, evaluator, "4k3/8/8/8/8/8/8/3QK3 w - - 0 1")
+	if white <= 0 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%f", "white ahead", white)
+	}
+
+	// the same position for black, which the network sees the same way
+	black := evaluate(t, evaluator, "3qk3/8/8/8/8/8/8/4K3 b - - 0 1")
+	if math.Abs(black+white) > 1e-9 {
+		t.Fatalf("\nExpected: \n%f\nActual: \n%f", -white, black)
+	}
+
+	// without the queen, the expected score is 0.5, which is even
+	if score := evaluate(t, evaluator, "4k3/8/8/8/8/8/8/4K3 b - - 0 1"); math.Abs(score) > 1e-9 {
+		t.Fatalf("\nExpected: \n%f\nActual: \n%f", 0.0, score)
+	}
+	if n := evaluator.(ActivationCounter).GetFailedActivations(); n != 0 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 0, n)
+	}
+}
+
+func TestNetworkEvaluatorFailedActivation(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteStartGenome(&buf, NewInputEncoder().Build(), VALUE_OUTPUT_SIZE); err != nil {
+		t.Fatal(err)
+	}
+
+	// without its connection, the output is never activated
+	genome := readTestGenome(t, strings.Replace(buf.String(), "enabled: true", "enabled: false", 1))
+	org, err := genetics.NewOrganism(0, genome, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluator := NewNetworkEvaluator(org, NewInputEncoder().Build())
+
+	if score := evaluate(t, evaluator, "4k3/8/8/8/8/8/8/3QK3 w - - 0 1"); score != 0 {
+		t.Fatalf("\nExpected: \n%f\nActual: \n%f", 0.0, score)
+	}
+	if n := evaluator.(ActivationCounter).GetFailedActivations(); n != 1 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 1, n)
+	}
+	if n := evaluator.(ActivationCounter).GetActivations(); n != 1 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 1, n)
+	}
+}
@@ -0,0 +1,83 @@
+package player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"math"
+	"math/rand"
+)
+
+// The outputs of the network are seen from the side to move, like its inputs: scores of the 64 source squares, then of
+// the 64 destination squares (a1 being 0 once the board is flipped for black), then of the underpromotions
+const (
+	SRC_OUTPUTS            int = 0
+	DST_OUTPUTS            int = 64
+	UNDERPROMOTION_OUTPUTS int = 128
+	OUTPUT_SIZE            int = UNDERPROMOTION_OUTPUTS + len(UNDERPROMOTION_PIECE_TYPES)
+)
+
+var UNDERPROMOTION_PIECE_TYPES [3]b.PieceType = [3]b.PieceType{b.KNIGHT, b.BISHOP, b.ROOK}
+
+/**
+Returns the score of every valid move from the outputs of the network: the score of its source square plus the score of
+its destination square. Promoting to a queen scores like any other move, and underpromotions score more than it when
+the output of their piece is above 0.5
+*/
+func ScoreMoves(outputs []float64, moves []b.Move, turn b.Color) []float64 {
+	scores := make([]float64, len(moves))
+
+	for i, move := range moves {
+		src := b.GetRelativeIndex(turn, move.GetSrcSquare().GetIndex())
+		dst := b.GetRelativeIndex(turn, move.GetDstSquare().GetIndex())
+		scores[i] = outputs[SRC_OUTPUTS+src] + outputs[DST_OUTPUTS+dst]
+
+		if promotion := move.GetPromotionPieceType(); promotion != nil {
+			for j, pt := range UNDERPROMOTION_PIECE_TYPES {
+				if *promotion == pt {
+					scores[i] += outputs[UNDERPROMOTION_OUTPUTS+j] - 0.5
+				}
+			}
+		}
+	}
+
+	return scores
+}
+
+/**
+Returns the index of the move to play: the best scored move, the first one on ties, with a temperature of 0, otherwise a
+move sampled from the softmax of the scores divided by the temperature, with the given source or the global one if nil
+*/
+func SelectMove(scores []float64, temperature float64, r *rand.Rand) int {
+	best := 0
+	for i, score := range scores {
+		if score > scores[best] {
+			best = i
+		}
+	}
+
+	if temperature <= 0 {
+		return best
+	}
+
+	// subtracting the best score keeps the exponentials from overflowing
+	weights := make([]float64, len(scores))
+	var total float64 = 0
+	for i, score := range scores {
+		weights[i] = math.Exp((score - scores[best]) / temperature)
+		total += weights[i]
+	}
+
+	x := rand.Float64
+	if r != nil {
+		x = r.Float64
+	}
+
+	target := x() * total
+	for i, w := range weights {
+		if target < w {
+			return i
+		}
+		target -= w
+	}
+
+	return best
+}
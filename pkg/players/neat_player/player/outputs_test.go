@@ -0,0 +1,115 @@
+package player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+/**
+Returns outputs of 0 but for the given source, destination and underpromotion outputs
+*/
+func newTestOutputs(values map[int]float64) []float64 {
+	outputs := make([]float64, OUTPUT_SIZE)
+	for i, value := range values {
+		outputs[i] = value
+	}
+	return outputs
+}
+
+func getBestMove(t *testing.T, fen string, outputs []float64) string {
+	board, err := b.FromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moves := board.GetValidMoves()
+	move := moves[SelectMove(ScoreMoves(outputs, moves, board.GetTurn()), 0, nil)]
+	return strings.ToLower(move.GetSrcSquare().GetName() + move.GetDstSquare().GetName())
+}
+
+func TestScoreMoves(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		outputs  map[int]float64
+		expected string
+	}{
+		// e2e5 would score the highest, but it is not a valid move
+		{"invalid moves", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", map[int]float64{SRC_OUTPUTS + 12: 0.9, DST_OUTPUTS + 36: 0.9, SRC_OUTPUTS + 6: 0.6, DST_OUTPUTS + 21: 0.6}, "g1f3"},
+		// the squares are seen from the side to move: g1 and f3 are g8 and f6 for black
+		{"black", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", map[int]float64{SRC_OUTPUTS + 6: 0.6, DST_OUTPUTS + 21: 0.6}, "g8f6"},
+		{"black pawn", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", map[int]float64{SRC_OUTPUTS + 11: 0.6, DST_OUTPUTS + 27: 0.6}, "d7d5"},
+	}
+
+	for _, test := range tests {
+		if actual := getBestMove(t, test.fen, newTestOutputs(test.outputs)); actual != test.expected {
+			t.Fatalf("%s\nExpected: \n%s\nActual: \n%s", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestScoreUnderpromotions(t *testing.T) {
+	board, _ := b.FromFEN("8/1P6/8/8/8/8/k7/4K3 w - - 0 1")
+	outputs := newTestOutputs(map[int]float64{
+		SRC_OUTPUTS + 49:           1,
+		DST_OUTPUTS + 57:           1,
+		UNDERPROMOTION_OUTPUTS:     0.9, // knight
+		UNDERPROMOTION_OUTPUTS + 1: 0.2, // bishop
+		UNDERPROMOTION_OUTPUTS + 2: 0.5, // rook
+	})
+
+	// a queen promotion scores like any other move, and underpromotions score more than it above 0.5
+	expected := map[b.PieceType]float64{b.QUEEN: 2, b.KNIGHT: 2.4, b.BISHOP: 1.7, b.ROOK: 2}
+
+	moves := board.GetValidMoves()
+	scores := ScoreMoves(outputs, moves, board.GetTurn())
+	promotions := 0
+	for i, move := range moves {
+		promotion := move.GetPromotionPieceType()
+		if promotion == nil {
+			continue
+		}
+		promotions++
+		if math.Abs(scores[i]-expected[*promotion]) > 1e-9 {
+			t.Fatalf("\nExpected: \n%f\nActual: \n%f", expected[*promotion], scores[i])
+		}
+	}
+	if promotions != 4 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 4, promotions)
+	}
+
+	// for black, the promotion square is seen from black's side, where b1 is b8
+	board, _ = b.FromFEN("4K3/8/8/8/8/8/1p6/7k b - - 0 1")
+	moves = board.GetValidMoves()
+	scores = ScoreMoves(outputs, moves, board.GetTurn())
+	best := moves[SelectMove(scores, 0, nil)]
+	if promotion := best.GetPromotionPieceType(); promotion == nil || *promotion != b.KNIGHT {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "b2b1n", best)
+	}
+}
+
+func TestSelectMove(t *testing.T) {
+	// without temperature, the best move, the first one on ties
+	if i := SelectMove([]float64{0.3, 0.8, 0.8, 0.1}, 0, nil); i != 1 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 1, i)
+	}
+
+	// with a temperature, moves are sampled from the softmax of their scores divided by it
+	r := rand.New(rand.NewSource(1))
+	scores := []float64{1, 0, 0}
+	counts := make([]int, len(scores))
+	const draws int = 20000
+	for k := 0; k < draws; k++ {
+		counts[SelectMove(scores, 1, r)]++
+	}
+
+	expected := math.E / (math.E + 2)
+	if actual := float64(counts[0]) / float64(draws); math.Abs(actual-expected) > 0.02 {
+		t.Fatalf("\nExpected: \n%f\nActual: \n%f", expected, actual)
+	}
+	if counts[1] == 0 || counts[2] == 0 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v", "every move sampled", counts)
+	}
+}
@@ -0,0 +1,316 @@
+package trainer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"unsafe"
+
+	"github.com/yaricom/goNEAT/v2/experiment"
+	"github.com/yaricom/goNEAT/v2/neat"
+	"github.com/yaricom/goNEAT/v2/neat/genetics"
+)
+
+// directory of the output path holding the latest checkpoint, replaced by every new one
+const CHECKPOINT_DIR string = "checkpoint"
+
+const STATE_FILE string = "state.json"
+const EXPERIMENT_FILE string = "experiment.dat"
+const POPULATION_FILE string = "population.txt"
+const SPECIES_FILE string = "species.json"
+const HALL_OF_FAME_FILE string = "hall_of_fame.txt"
+
+/**
+The state of an experiment between two generations: the generation to evaluate next, the seed the global source is
+reseeded from before every generation, the trials evaluated so far, the population and the hall of fame. The genomes
+of the population are written by goNEAT, and its species, stagnation and counters of innovations and nodes next to them,
+so that the population is restored as it was instead of being speciated again
+*/
+type Checkpoint struct {
+	Seed       int64 `json:"seed"`
+	Trial      int   `json:"trial"`
+	Generation int   `json:"generation"`
+
+	Experiment *experiment.Experiment `json:"-"`
+
+	// nil at the start of a trial, whose population is then spawned from the start genome
+	Population *genetics.Population `json:"-"`
+
+	HallOfFame []*genetics.Genome `json:"-"`
+}
+
+/**
+The state of a population which its genomes don't hold, written to SPECIES_FILE
+*/
+type populationState struct {
+	LastSpecies              int     `json:"last_species"`
+	HighestFitness           float64 `json:"highest_fitness"`
+	EpochsHighestLastChanged int     `json:"epochs_highest_last_changed"`
+
+	// the last innovation number and node id given to the genes and nodes of the population
+	NextInnovNum int64 `json:"next_innov_num"`
+	NextNodeId   int64 `json:"next_node_id"`
+
+	Species []speciesState `json:"species"`
+}
+
+/**
+The state of a species, with the indexes of its organisms in the population
+*/
+type speciesState struct {
+	Id                   int     `json:"id"`
+	Age                  int     `json:"age"`
+	AgeOfLastImprovement int     `json:"age_of_last_improvement"`
+	MaxFitnessEver       float64 `json:"max_fitness_ever"`
+	IsNovel              bool    `json:"is_novel"`
+	Organisms            []int   `json:"organisms"`
+}
+
+/**
+Writes the checkpoint to CHECKPOINT_DIR in the output path. The files are written to a temporary directory first, which
+then replaces the previous checkpoint, so that an interruption never leaves a partial checkpoint
+*/
+func (c *Checkpoint) Write(outputPath string) error {
+	dir := filepath.Join(outputPath, CHECKPOINT_DIR)
+	tmpDir := dir + ".tmp"
+
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tmpDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	state, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, STATE_FILE), state, 0644); err != nil {
+		return err
+	}
+
+	err = writeFile(filepath.Join(tmpDir, EXPERIMENT_FILE), c.Experiment.Write)
+	if err != nil {
+		return err
+	}
+
+	if c.Population != nil {
+		if err := writeFile(filepath.Join(tmpDir, POPULATION_FILE), c.Population.Write); err != nil {
+			return err
+		}
+
+		species, err := json.MarshalIndent(getPopulationState(c.Population), "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, SPECIES_FILE), species, 0644); err != nil {
+			return err
+		}
+	}
+
+	err = writeFile(filepath.Join(tmpDir, HALL_OF_FAME_FILE), func(w io.Writer) error {
+		for _, genome := range c.HallOfFame {
+			if err := genome.Write(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Rename(tmpDir, dir)
+}
+
+/**
+Reads the checkpoint in CHECKPOINT_DIR of the output path
+*/
+func ReadCheckpoint(outputPath string, options *neat.Options) (*Checkpoint, error) {
+	dir := filepath.Join(outputPath, CHECKPOINT_DIR)
+
+	state, err := os.ReadFile(filepath.Join(dir, STATE_FILE))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Checkpoint{Experiment: &experiment.Experiment{}}
+	if err := json.Unmarshal(state, c); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint state: %w", err)
+	}
+
+	experimentFile, err := os.Open(filepath.Join(dir, EXPERIMENT_FILE))
+	if err != nil {
+		return nil, err
+	}
+	defer experimentFile.Close()
+
+	if err := c.Experiment.Read(experimentFile); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint experiment: %w", err)
+	}
+	c.Experiment.RandSeed = c.Seed
+
+	if populationFile, err := os.Open(filepath.Join(dir, POPULATION_FILE)); err == nil {
+		defer populationFile.Close()
+
+		if c.Population, err = readPopulation(populationFile, options); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint population: %w", err)
+		}
+
+		species, err := os.ReadFile(filepath.Join(dir, SPECIES_FILE))
+		if err != nil {
+			return nil, err
+		}
+		var state populationState
+		if err := json.Unmarshal(species, &state); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint species: %w", err)
+		}
+		if err := setPopulationState(c.Population, state); err != nil {
+			return nil, fmt.Errorf("invalid checkpoint species: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	hallOfFame, err := os.ReadFile(filepath.Join(dir, HALL_OF_FAME_FILE))
+	if err != nil {
+		return nil, err
+	}
+
+	// the hall of fame is written like a population, without the species
+	if len(hallOfFame) > 0 {
+		champions, err := readPopulation(bytes.NewReader(hallOfFame), options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checkpoint hall of fame: %w", err)
+		}
+		for _, champion := range champions.Organisms {
+			c.HallOfFame = append(c.HallOfFame, champion.Genotype)
+		}
+	}
+
+	return c, nil
+}
+
+/**
+Reads a population written by Population.Write. genetics.ReadPopulation joins the line following "genomestart" to it,
+which would drop the first trait of every genome, so a blank line is inserted there for it to join instead
+*/
+func readPopulation(r io.Reader, options *neat.Options) (*genetics.Population, error) {
+	var buf bytes.Buffer
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		buf.WriteString(line + "\n")
+		if strings.HasPrefix(line, "genomestart ") {
+			buf.WriteString(" \n")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return genetics.ReadPopulation(&buf, options)
+}
+
+/**
+Returns the species, stagnation and counters of the population
+*/
+func getPopulationState(pop *genetics.Population) populationState {
+	state := populationState{
+		LastSpecies:              pop.LastSpecies,
+		HighestFitness:           pop.HighestFitness,
+		EpochsHighestLastChanged: pop.EpochsHighestLastChanged,
+		NextInnovNum:             getCounter(pop, "nextInnovNum").Int(),
+		NextNodeId:               getCounter(pop, "nextNodeId").Int(),
+	}
+
+	indexes := make(map[*genetics.Organism]int)
+	for i, org := range pop.Organisms {
+		indexes[org] = i
+	}
+	for _, s := range pop.Species {
+		ss := speciesState{
+			Id:                   s.Id,
+			Age:                  s.Age,
+			AgeOfLastImprovement: s.AgeOfLastImprovement,
+			MaxFitnessEver:       s.MaxFitnessEver,
+			IsNovel:              s.IsNovel,
+		}
+		for _, org := range s.Organisms {
+			ss.Organisms = append(ss.Organisms, indexes[org])
+		}
+		state.Species = append(state.Species, ss)
+	}
+	return state
+}
+
+/**
+Replaces the species genetics.ReadPopulation gave the population, and the counters it rebuilt from the genomes, with
+those of the state. Fails unless every organism belongs to exactly one species
+*/
+func setPopulationState(pop *genetics.Population, state populationState) error {
+	pop.LastSpecies = state.LastSpecies
+	pop.HighestFitness = state.HighestFitness
+	pop.EpochsHighestLastChanged = state.EpochsHighestLastChanged
+	getCounter(pop, "nextInnovNum").SetInt(state.NextInnovNum)
+	getCounter(pop, "nextNodeId").SetInt(state.NextNodeId)
+
+	assigned := make([]bool, len(pop.Organisms))
+	pop.Species = nil
+	for _, ss := range state.Species {
+		s := genetics.NewSpeciesNovel(ss.Id, ss.IsNovel)
+		s.Age = ss.Age
+		s.AgeOfLastImprovement = ss.AgeOfLastImprovement
+		s.MaxFitnessEver = ss.MaxFitnessEver
+
+		for _, i := range ss.Organisms {
+			if i < 0 || i >= len(pop.Organisms) || assigned[i] {
+				return fmt.Errorf("organism %d of species %d is not in the population or in another species", i, ss.Id)
+			}
+			assigned[i] = true
+			pop.Organisms[i].Species = s
+			s.Organisms = append(s.Organisms, pop.Organisms[i])
+		}
+		pop.Species = append(pop.Species, s)
+	}
+
+	for i, ok := range assigned {
+		if !ok {
+			return fmt.Errorf("organism %d is in no species", i)
+		}
+	}
+	return nil
+}
+
+/**
+Returns a settable counter of the population. goNEAT only exports the methods incrementing its counters of
+innovations and nodes, and rebuilds them from the genomes when reading a population, which gives them the ids of genes
+and nodes of organisms that died out again
+*/
+func getCounter(pop *genetics.Population, name string) reflect.Value {
+	field := reflect.ValueOf(pop).Elem().FieldByName(name)
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
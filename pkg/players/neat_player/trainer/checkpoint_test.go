@@ -0,0 +1,198 @@
+package trainer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	neat_player "galapb/chess2022/pkg/players/neat_player/player"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/yaricom/goNEAT/v2/experiment"
+	"github.com/yaricom/goNEAT/v2/neat"
+	"github.com/yaricom/goNEAT/v2/neat/genetics"
+)
+
+func newTestOptions(t *testing.T) *neat.Options {
+	f, err := os.Open("../player/config/params.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	options, err := neat.LoadYAMLOptions(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// enough organisms in enough species for the species to be compared
+	options.PopSize = 12
+	options.CompatThreshold = 0.05
+	return options
+}
+
+func newTestPopulation(t *testing.T, options *neat.Options) *genetics.Population {
+	var buf bytes.Buffer
+	if err := neat_player.WriteStartGenome(&buf, neat_player.NewInputEncoder().Build(), neat_player.VALUE_OUTPUT_SIZE); err != nil {
+		t.Fatal(err)
+	}
+	r, err := genetics.NewGenomeReader(&buf, genetics.YAMLGenomeEncoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genome, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the weights of the organisms are drawn from the global source
+	rand.Seed(1)
+	pop, err := genetics.NewPopulation(genome, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pop
+}
+
+func writeGenome(t *testing.T, genome *genetics.Genome) string {
+	var buf bytes.Buffer
+	if err := genome.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+/**
+Evaluates the organisms with fitnesses drawn from the global source, and turns the population over to the next
+generation
+*/
+func advancePopulation(t *testing.T, pop *genetics.Population, options *neat.Options, generation int) {
+	for _, org := range pop.Organisms {
+		org.Fitness = rand.Float64()
+	}
+	executor := &genetics.SequentialPopulationEpochExecutor{}
+	if err := executor.NextEpoch(neat.NewContext(context.Background(), options), generation, pop); err != nil {
+		t.Fatal(err)
+	}
+}
+
+/**
+Returns the species of the population with the indexes of their organisms, in the order of the population
+*/
+func getSpecies(pop *genetics.Population) string {
+	indexes := make(map[*genetics.Organism]int)
+	for i, org := range pop.Organisms {
+		indexes[org] = i
+	}
+
+	var buf bytes.Buffer
+	for _, s := range pop.Species {
+		fmt.Fprintf(&buf, "species %d: age %d, improved at age %d, max fitness %f, organisms", s.Id, s.Age, s.AgeOfLastImprovement, s.MaxFitnessEver)
+		for _, org := range s.Organisms {
+			if org.Species != s {
+				fmt.Fprintf(&buf, " (%d in another species)", indexes[org])
+			}
+			fmt.Fprintf(&buf, " %d", indexes[org])
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	options := newTestOptions(t)
+	pop := newTestPopulation(t, options)
+	for generation := 0; generation < 2; generation++ {
+		advancePopulation(t, pop, options, generation)
+	}
+
+	expected := &Checkpoint{
+		Seed:       42,
+		Trial:      1,
+		Generation: 7,
+		Experiment: &experiment.Experiment{Id: 3, Name: "test"},
+		Population: pop,
+		HallOfFame: []*genetics.Genome{pop.Organisms[2].Genotype, pop.Organisms[0].Genotype},
+	}
+
+	dir := t.TempDir()
+	if err := expected.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := ReadCheckpoint(dir, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual.Seed != expected.Seed || actual.Trial != expected.Trial || actual.Generation != expected.Generation {
+		t.Fatalf("\nExpected: \n%+v\nActual: \n%+v", expected, actual)
+	}
+	if actual.Experiment.Id != expected.Experiment.Id || actual.Experiment.Name != expected.Experiment.Name || actual.Experiment.RandSeed != expected.Seed {
+		t.Fatalf("\nExpected: \n%+v\nActual: \n%+v", expected.Experiment, actual.Experiment)
+	}
+
+	if len(actual.Population.Organisms) != len(pop.Organisms) {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", len(pop.Organisms), len(actual.Population.Organisms))
+	}
+	for i, org := range pop.Organisms {
+		e, a := org.Genotype, actual.Population.Organisms[i].Genotype
+		if a.Id != e.Id || len(a.Traits) != len(e.Traits) || len(a.Nodes) != len(e.Nodes) || len(a.Genes) != len(e.Genes) {
+			t.Fatalf("\nExpected: \n%s\nActual: \n%s", e, a)
+		}
+		// the traits, nodes and genes, with their parameters and weights
+		if writeGenome(t, a) != writeGenome(t, e) {
+			t.Fatalf("\nExpected: \n%s\nActual: \n%s", writeGenome(t, e), writeGenome(t, a))
+		}
+	}
+	if _, err := actual.Population.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the species are restored with their organisms, ages and fitnesses, rather than speciated again
+	if len(pop.Species) < 2 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "several species", getSpecies(pop))
+	}
+	if getSpecies(actual.Population) != getSpecies(pop) {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", getSpecies(pop), getSpecies(actual.Population))
+	}
+	e, a := pop, actual.Population
+	if a.LastSpecies != e.LastSpecies || a.HighestFitness != e.HighestFitness || a.EpochsHighestLastChanged != e.EpochsHighestLastChanged {
+		t.Fatalf("\nExpected: \n%d, %f, %d\nActual: \n%d, %f, %d", e.LastSpecies, e.HighestFitness, e.EpochsHighestLastChanged, a.LastSpecies, a.HighestFitness, a.EpochsHighestLastChanged)
+	}
+
+	// new genes and nodes get the innovation numbers and ids the population would have given them
+	if expected, actual := e.NextInnovationNumber(), a.NextInnovationNumber(); actual != expected {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", expected, actual)
+	}
+	if expected, actual := e.NextNodeId(), a.NextNodeId(); actual != expected {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", expected, actual)
+	}
+
+	if len(actual.HallOfFame) != len(expected.HallOfFame) {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", len(expected.HallOfFame), len(actual.HallOfFame))
+	}
+	for i, genome := range expected.HallOfFame {
+		if writeGenome(t, actual.HallOfFame[i]) != writeGenome(t, genome) {
+			t.Fatalf("\nExpected: \n%s\nActual: \n%s", writeGenome(t, genome), writeGenome(t, actual.HallOfFame[i]))
+		}
+	}
+}
+
+func TestCheckpointWithoutPopulation(t *testing.T) {
+	// at the start of a trial, the population is spawned again from the start genome
+	expected := &Checkpoint{Seed: 1, Trial: 2, Experiment: &experiment.Experiment{Id: 1, Name: "test"}}
+
+	dir := t.TempDir()
+	if err := expected.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := ReadCheckpoint(dir, newTestOptions(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual.Trial != 2 || actual.Population != nil || len(actual.HallOfFame) != 0 {
+		t.Fatalf("\nExpected: \n%+v\nActual: \n%+v", expected, actual)
+	}
+}
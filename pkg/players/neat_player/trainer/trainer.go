@@ -0,0 +1,222 @@
+package trainer
+
+import (
+	"context"
+	"errors"
+	"galapb/chess2022/pkg/players/neat_player/evaluator"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/yaricom/goNEAT/v2/experiment"
+	"github.com/yaricom/goNEAT/v2/neat"
+	"github.com/yaricom/goNEAT/v2/neat/genetics"
+)
+
+// number of generations between two checkpoints
+const DEFAULT_CHECKPOINT_INTERVAL int = 10
+
+type TrainerBuilder interface {
+	CheckpointInterval(int) TrainerBuilder
+	Build() *Trainer
+}
+
+/**
+Runs the trials of an experiment generation by generation like experiment.Execute, but writes checkpoints to the output
+path along the way, from which an interrupted experiment can be resumed
+*/
+type Trainer struct {
+	outputPath string
+	options    *neat.Options
+	evaluator  evaluator.NeatPlayerEvaluator
+
+	// a checkpoint is written every this many generations, and when the experiment is stopped
+	checkpointInterval int
+}
+
+func NewTrainer(outputPath string, options *neat.Options, evaluator evaluator.NeatPlayerEvaluator) TrainerBuilder {
+	return &Trainer{
+		outputPath:         outputPath,
+		options:            options,
+		evaluator:          evaluator,
+		checkpointInterval: DEFAULT_CHECKPOINT_INTERVAL,
+	}
+}
+
+/**
+Sets the number of generations between two checkpoints, or disables the periodic checkpoints if 0
+*/
+func (t *Trainer) CheckpointInterval(checkpointInterval int) TrainerBuilder {
+	t.checkpointInterval = checkpointInterval
+	return t
+}
+
+func (t *Trainer) Build() *Trainer {
+	return t
+}
+
+/**
+Runs the experiment from populations spawned from the start genome. The global source of random numbers is reseeded
+from the seed of the experiment before every generation, so that the generation only depends on the checkpoint it
+starts from. When the context is canceled, the experiment stops after the current generation and writes a checkpoint
+*/
+func (t *Trainer) Run(ctx context.Context, expt *experiment.Experiment, startGenome *genetics.Genome) error {
+	return t.run(ctx, expt, startGenome, &Checkpoint{Seed: expt.RandSeed, Experiment: expt})
+}
+
+/**
+Resumes the experiment from the checkpoint in the output path: the trials evaluated so far are restored into the
+experiment, and the evaluation continues with the population, hall of fame and seed of the checkpoint. The metrics of
+the generations evaluated after the checkpoint was written are removed, since they are evaluated again
+*/
+func (t *Trainer) Resume(ctx context.Context, expt *experiment.Experiment, startGenome *genetics.Genome) error {
+	checkpoint, err := ReadCheckpoint(t.outputPath, t.options)
+	if err != nil {
+		return err
+	}
+
+	if err := t.evaluator.SetHallOfFame(checkpoint.HallOfFame); err != nil {
+		return err
+	}
+	if err := t.evaluator.TruncateMetrics(checkpoint.Trial, checkpoint.Generation); err != nil {
+		return err
+	}
+
+	expt.RandSeed = checkpoint.Seed
+	expt.Trials = checkpoint.Experiment.Trials
+	checkpoint.Experiment = expt
+
+	log.Printf("Resuming trial %d from generation %d", checkpoint.Trial, checkpoint.Generation)
+	return t.run(ctx, expt, startGenome, checkpoint)
+}
+
+func (t *Trainer) run(ctx context.Context, expt *experiment.Experiment, startGenome *genetics.Genome, checkpoint *Checkpoint) error {
+	if len(expt.Trials) != t.options.NumRuns {
+		trials := make(experiment.Trials, t.options.NumRuns)
+		copy(trials, expt.Trials)
+		expt.Trials = trials
+	}
+
+	// the turnover of a generation is never interrupted, so that the population can always be checkpointed
+	epochCtx := neat.NewContext(context.Background(), t.options)
+
+	for run := checkpoint.Trial; run < t.options.NumRuns; run++ {
+		trialStart := time.Now()
+
+		pop := checkpoint.Population
+		firstGeneration := checkpoint.Generation
+		if pop == nil {
+			var err error
+			if pop, err = genetics.NewPopulation(startGenome, t.options); err != nil {
+				return err
+			}
+			if _, err = pop.Verify(); err != nil {
+				return err
+			}
+			firstGeneration = 0
+		}
+		checkpoint.Population = nil
+
+		epochExecutor, err := newEpochExecutor(t.options)
+		if err != nil {
+			return err
+		}
+
+		trial := expt.Trials[run]
+		trial.Id = run
+		if len(trial.Generations) > firstGeneration {
+			trial.Generations = trial.Generations[:firstGeneration]
+		}
+
+		for generationId := firstGeneration; generationId < t.options.NumGenerations; generationId++ {
+			select {
+			case <-ctx.Done():
+				log.Printf("Stopped before generation %d of trial %d", generationId, run)
+				if err := t.writeCheckpoint(expt, run, generationId, pop); err != nil {
+					return err
+				}
+				return ctx.Err()
+			default:
+			}
+
+			rand.Seed(getGenerationSeed(expt.RandSeed, run, generationId))
+
+			generation := experiment.Generation{
+				Id:      generationId,
+				TrialId: run,
+			}
+			generationStart := time.Now()
+			if err := t.evaluator.GenerationEvaluate(pop, &generation, t.options); err != nil {
+				return err
+			}
+			generation.Executed = time.Now()
+
+			if !generation.Solved {
+				if err := epochExecutor.NextEpoch(epochCtx, generationId, pop); err != nil {
+					return err
+				}
+			}
+
+			generation.Duration = generation.Executed.Sub(generationStart)
+			trial.Generations = append(trial.Generations, generation)
+			trial.Duration += time.Since(trialStart)
+			trialStart = time.Now()
+			expt.Trials[run] = trial
+
+			if generation.Solved {
+				log.Printf("Winner found in generation %d of trial %d, fitness %f", generationId, run, generation.Best.Fitness)
+				break
+			}
+
+			if t.checkpointInterval > 0 && (generationId+1)%t.checkpointInterval == 0 && generationId+1 < t.options.NumGenerations {
+				if err := t.writeCheckpoint(expt, run, generationId+1, pop); err != nil {
+					return err
+				}
+			}
+		}
+
+		// the next trial starts from a new population
+		if run+1 < t.options.NumRuns {
+			if err := t.writeCheckpoint(expt, run+1, 0, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (t *Trainer) writeCheckpoint(expt *experiment.Experiment, trial, generation int, pop *genetics.Population) error {
+	checkpoint := Checkpoint{
+		Seed:       expt.RandSeed,
+		Trial:      trial,
+		Generation: generation,
+		Experiment: expt,
+		Population: pop,
+		HallOfFame: t.evaluator.GetHallOfFame(),
+	}
+
+	if err := checkpoint.Write(t.outputPath); err != nil {
+		return err
+	}
+
+	log.Printf("Wrote a checkpoint before generation %d of trial %d", generation, trial)
+	return nil
+}
+
+/**
+Returns the executor turning a population over to the next generation, as selected by the options
+*/
+func newEpochExecutor(options *neat.Options) (genetics.PopulationEpochExecutor, error) {
+	switch options.EpochExecutorType {
+	case neat.EpochExecutorTypeSequential:
+		return &genetics.SequentialPopulationEpochExecutor{}, nil
+	case neat.EpochExecutorTypeParallel:
+		return &genetics.ParallelPopulationEpochExecutor{}, nil
+	}
+	return nil, errors.New("unsupported epoch executor type")
+}
+
+func getGenerationSeed(seed int64, trial, generation int) int64 {
+	return seed + int64(trial)<<32 + int64(generation)
+}
@@ -0,0 +1,55 @@
+package perfect_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/retrograde"
+	"math/rand"
+)
+
+/**
+Plays perfectly from retrograde tables: the fastest mate when winning, the slowest mate when losing, and a drawing move
+otherwise. Positions missing from the tables get a random move
+*/
+type PerfectPlayer struct {
+	prompt    chan b.Move
+	response  chan b.Move
+	tablebase *retrograde.Tablebase
+}
+
+func New(tablebase *retrograde.Tablebase) *PerfectPlayer {
+	return &PerfectPlayer{nil, nil, tablebase}
+}
+
+func (pp *PerfectPlayer) Init(prompt chan b.Move, response chan b.Move) {
+	pp.prompt = prompt
+	pp.response = response
+}
+
+func (pp *PerfectPlayer) Start(board b.Board, quit chan bool) {
+	var err error
+
+	for {
+		select {
+		case <-quit:
+			return
+		case move := <-pp.prompt:
+			if err = board.Make(move); err != nil {
+				panic(err)
+			}
+
+			response := pp.getMove(board)
+			board.Make(response)
+
+			pp.response <- response
+		}
+	}
+}
+
+func (pp *PerfectPlayer) getMove(board b.Board) b.Move {
+	moves, _, err := pp.tablebase.GetBestMoves(board)
+	if err != nil || len(moves) == 0 {
+		moves = board.GetValidMoves()
+	}
+
+	return moves[rand.Intn(len(moves))]
+}
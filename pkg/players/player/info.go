@@ -0,0 +1,86 @@
+package player
+
+import (
+	"fmt"
+	"galapb/chess2022/pkg/board"
+	"io"
+	"strings"
+	"time"
+)
+
+/**
+What a search found so far, reported by engines after every iteration
+*/
+type SearchInfo struct {
+	// depth of the iteration, and deepest ply reached including quiescence
+	Depth    int
+	SelDepth int
+
+	// number of the line when several best moves are reported, starting at 1
+	MultiPV int
+
+	// score from the perspective of the side to move, in centipawns, unless the line leads to a mate
+	Score int
+
+	// moves to mate if not 0, negative if the side to move gets mated
+	Mate int
+
+	Nodes int64
+	Time  time.Duration
+
+	// fill rate of the transposition table, in permille
+	HashFull int
+
+	// the best line, starting with the move to play
+	PV []board.Move
+}
+
+/**
+Receives the info of a search, from the goroutine of the search
+*/
+type InfoHandler func(info SearchInfo)
+
+/**
+A player that reports what its search is doing
+*/
+type InfoPlayer interface {
+	Player
+	SetInfoHandler(handler InfoHandler)
+}
+
+/**
+Returns the number of nodes searched per second
+*/
+func (si SearchInfo) GetNPS() int64 {
+	if si.Time <= 0 {
+		return 0
+	}
+	return int64(float64(si.Nodes) / si.Time.Seconds())
+}
+
+func (si SearchInfo) String() string {
+	score := fmt.Sprintf("%+.2f", float64(si.Score)/100)
+	if si.Mate != 0 {
+		score = fmt.Sprintf("#%d", si.Mate)
+	}
+
+	pv := make([]string, len(si.PV))
+	for i, move := range si.PV {
+		pv[i] = move.String()
+	}
+
+	return fmt.Sprintf(
+		"depth %d/%d score %s nodes %d nps %d hashfull %.1f%% time %s pv %s",
+		si.Depth, si.SelDepth, score, si.Nodes, si.GetNPS(), float64(si.HashFull)/10, si.Time.Round(time.Millisecond),
+		strings.Join(pv, " "),
+	)
+}
+
+/**
+Returns a handler printing every info on its own line, after the prefix
+*/
+func NewInfoPrinter(out io.Writer, prefix string) InfoHandler {
+	return func(info SearchInfo) {
+		fmt.Fprintf(out, "%s%s\n", prefix, info)
+	}
+}
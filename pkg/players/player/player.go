@@ -1,8 +1,24 @@
 package player
 
-import "galapb/chess2022/pkg/board"
+import (
+	"galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/time_control"
+)
 
+/**
+A player is prompted with the opponent's last move, the empty move for the first move of white, and responds with its
+own move. Between prompts, a player may keep thinking on its own copy of the board (pondering), until the quit channel
+is closed at the end of the game
+*/
 type Player interface {
 	Init(prompt chan board.Move, response chan board.Move)
 	Start(board board.Board, quit chan bool)
 }
+
+/**
+A player that wants to know the state of its clock. The game sets the clock before every prompt
+*/
+type TimedPlayer interface {
+	Player
+	SetClock(clock time_control.Clock)
+}
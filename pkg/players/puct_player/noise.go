@@ -0,0 +1,58 @@
+package puct_player
+
+import (
+	"math"
+	"math/rand"
+)
+
+/**
+Returns n values drawn from a symmetric Dirichlet distribution, which sum to 1. A small alpha concentrates the noise on
+a few values
+*/
+func sampleDirichlet(alpha float64, n int) []float64 {
+	values := make([]float64, n)
+
+	var total float64 = 0
+	for i := range values {
+		values[i] = sampleGamma(alpha)
+		total += values[i]
+	}
+
+	for i := range values {
+		if total > 0 {
+			values[i] /= total
+		} else {
+			values[i] = 1 / float64(n)
+		}
+	}
+
+	return values
+}
+
+/**
+Returns a value drawn from a Gamma distribution of the given shape and a scale of 1, with the method of Marsaglia and
+Tsang
+*/
+func sampleGamma(shape float64) float64 {
+	if shape < 1 {
+		// boosts the shape above 1, then scales the sample back down
+		return sampleGamma(shape+1) * math.Pow(rand.Float64(), 1/shape)
+	}
+
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+
+	for {
+		x := rand.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+
+		v = v * v * v
+		u := rand.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package puct_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/network"
+	"galapb/chess2022/pkg/time_control"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	DEFAULT_CPUCT       float64 = 1.5
+	DEFAULT_SIMULATIONS int     = 200
+
+	// the noise of AlphaZero for chess, with about 30 valid moves per position
+	DEFAULT_DIRICHLET_ALPHA   float64 = 0.3
+	DEFAULT_DIRICHLET_EPSILON float64 = 0.25
+)
+
+type PUCTPlayerBuilder interface {
+	Net(network.PolicyValueNet) PUCTPlayerBuilder
+	CPuct(float64) PUCTPlayerBuilder
+	Simulations(int) PUCTPlayerBuilder
+	MoveTime(time.Duration) PUCTPlayerBuilder
+	DirichletNoise(alpha, epsilon float64) PUCTPlayerBuilder
+	Temperature(temperature float64, plies int) PUCTPlayerBuilder
+	Build() *PUCTPlayer
+}
+
+/**
+Searches with the PUCT algorithm of AlphaZero: a policy/value network gives every move a prior and every new node a
+value, instead of random playouts, and the tree grows towards the moves with the best value and prior. The most visited
+move is played, or a move is sampled by visits early in self-play games
+*/
+type PUCTPlayer struct {
+	prompt   chan b.Move
+	response chan b.Move
+
+	net   network.PolicyValueNet
+	cpuct float64
+
+	// the search stops after this many simulations, unless it is bounded by time
+	simulations int
+	moveTime    time.Duration
+	clock       *time_control.Clock
+
+	// noise mixed into the priors at the root, disabled with an epsilon of 0
+	dirichletAlpha   float64
+	dirichletEpsilon float64
+
+	// moves are sampled with this temperature during the first plies of the game, then the most visited move is played
+	temperature      float64
+	temperaturePlies int
+}
+
+/**
+The visits of the moves at the root after a search, which are the target policy when training on self-play games
+*/
+type SearchResult struct {
+	Turn   b.Color
+	Moves  []b.Move
+	Visits []float64
+
+	// average value of the root for the side to move
+	Value float64
+}
+
+func New() PUCTPlayerBuilder {
+	return &PUCTPlayer{
+		net:            network.NewUniformNet(),
+		cpuct:          DEFAULT_CPUCT,
+		simulations:    DEFAULT_SIMULATIONS,
+		dirichletAlpha: DEFAULT_DIRICHLET_ALPHA,
+	}
+}
+
+func (pp *PUCTPlayer) Net(net network.PolicyValueNet) PUCTPlayerBuilder {
+	pp.net = net
+	return pp
+}
+
+func (pp *PUCTPlayer) CPuct(cpuct float64) PUCTPlayerBuilder {
+	pp.cpuct = cpuct
+	return pp
+}
+
+func (pp *PUCTPlayer) Simulations(simulations int) PUCTPlayerBuilder {
+	pp.simulations = simulations
+	return pp
+}
+
+/**
+Bounds every search by time instead of simulations, in untimed games
+*/
+func (pp *PUCTPlayer) MoveTime(moveTime time.Duration) PUCTPlayerBuilder {
+	pp.moveTime = moveTime
+	return pp
+}
+
+/**
+Mixes noise into the priors at the root, for self-play. AlphaZero used DEFAULT_DIRICHLET_ALPHA and
+DEFAULT_DIRICHLET_EPSILON
+*/
+func (pp *PUCTPlayer) DirichletNoise(alpha, epsilon float64) PUCTPlayerBuilder {
+	pp.dirichletAlpha = alpha
+	pp.dirichletEpsilon = epsilon
+	return pp
+}
+
+/**
+Samples moves by their visits raised to 1 / temperature until the given ply, for self-play. A temperature of 1 samples
+moves as often as they were visited
+*/
+func (pp *PUCTPlayer) Temperature(temperature float64, plies int) PUCTPlayerBuilder {
+	pp.temperature = temperature
+	pp.temperaturePlies = plies
+	return pp
+}
+
+func (pp *PUCTPlayer) Build() *PUCTPlayer {
+	return pp
+}
+
+func (pp *PUCTPlayer) SetClock(clock time_control.Clock) {
+	pp.clock = &clock
+}
+
+func (pp *PUCTPlayer) Init(prompt chan b.Move, response chan b.Move) {
+	pp.prompt = prompt
+	pp.response = response
+}
+
+func (pp *PUCTPlayer) Start(board b.Board, quit chan bool) {
+	var err error
+
+	for {
+		select {
+		case <-quit:
+			return
+		case move := <-pp.prompt:
+			if err = board.Make(move); err != nil {
+				panic(err)
+			}
+
+			response := pp.SelectMove(pp.Search(board), board.GetPly())
+			board.Make(response)
+			select {
+			case pp.response <- response:
+			case <-quit:
+				return
+			}
+		}
+	}
+}
+
+/**
+Searches the board for as long as the budget allows: the clock in timed games, otherwise the move time or number of
+simulations
+*/
+func (pp *PUCTPlayer) Search(board b.Board) *SearchResult {
+	var timeManager *time_control.TimeManager
+	switch {
+	case pp.clock != nil:
+		timeManager = time_control.NewTimeManager(*pp.clock)
+	case pp.moveTime > 0:
+		timeManager = time_control.NewFixedTimeManager(pp.moveTime)
+	}
+
+	root := pp.newRoot(board)
+
+	for i := 0; ; i++ {
+		if timeManager != nil && (timeManager.Elapsed() >= timeManager.GetSoftLimit() || timeManager.ShouldStop()) {
+			break
+		}
+		if timeManager == nil && i >= pp.simulations {
+			break
+		}
+
+		pp.simulate(root, board)
+	}
+
+	result := &SearchResult{Turn: board.GetTurn(), Value: -root.value / root.visits}
+	for _, child := range root.children {
+		result.Moves = append(result.Moves, child.move)
+		result.Visits = append(result.Visits, child.visits)
+	}
+
+	return result
+}
+
+/**
+Returns the expanded root of a search of the board. The noise is only mixed into the priors of the root's children
+*/
+func (pp *PUCTPlayer) newRoot(board b.Board) *node {
+	root := &node{}
+	root.backpropagate(root.expand(board.Copy(), pp.net))
+	if pp.dirichletEpsilon > 0 && len(root.children) > 0 {
+		root.addNoise(pp.dirichletAlpha, pp.dirichletEpsilon)
+	}
+	return root
+}
+
+/**
+Runs one simulation of the search: selects a path down the tree, expands its leaf and backpropagates the value of the
+leaf given by the network
+*/
+func (pp *PUCTPlayer) simulate(root *node, board b.Board) {
+	var n *node = root
+	var current b.Board = board.Copy()
+
+	for n.expanded && !n.terminal {
+		n = n.selectChild(pp.cpuct)
+		current.Make(n.move)
+	}
+
+	if n.terminal {
+		n.backpropagate(n.result)
+		return
+	}
+
+	n.backpropagate(n.expand(current, pp.net))
+}
+
+/**
+Returns the move to play after a search: sampled by visits with the temperature before the given ply, otherwise the
+most visited move
+*/
+func (pp *PUCTPlayer) SelectMove(result *SearchResult, ply int) b.Move {
+	if len(result.Moves) == 0 {
+		return b.GetEmptyMove()
+	}
+
+	if pp.temperature > 0 && ply < pp.temperaturePlies {
+		return result.Moves[sampleIndex(result.Visits, 1/pp.temperature)]
+	}
+
+	var best []int
+	var bestVisits float64 = -1
+	for i, visits := range result.Visits {
+		switch {
+		case visits > bestVisits:
+			best, bestVisits = []int{i}, visits
+		case visits == bestVisits:
+			best = append(best, i)
+		}
+	}
+
+	return result.Moves[best[rand.Intn(len(best))]]
+}
+
+/**
+Returns the probability of every move index after the search, proportional to the visits of the moves
+*/
+func (sr *SearchResult) GetPolicy() []float64 {
+	policy := make([]float64, network.NUM_MOVES)
+
+	var total float64 = 0
+	for _, visits := range sr.Visits {
+		total += visits
+	}
+	if total == 0 {
+		return policy
+	}
+
+	for i, move := range sr.Moves {
+		policy[network.GetMoveIndex(move, sr.Turn)] = sr.Visits[i] / total
+	}
+
+	return policy
+}
+
+/**
+Returns an index sampled with probability proportional to its weight raised to the given power
+*/
+func sampleIndex(weights []float64, power float64) int {
+	powered := make([]float64, len(weights))
+
+	var total float64 = 0
+	for i, w := range weights {
+		powered[i] = math.Pow(w, power)
+		total += powered[i]
+	}
+
+	if total == 0 || math.IsInf(total, 0) || math.IsNaN(total) {
+		return rand.Intn(len(weights))
+	}
+
+	r := rand.Float64() * total
+	for i, p := range powered {
+		if r < p {
+			return i
+		}
+		r -= p
+	}
+
+	return len(weights) - 1
+}
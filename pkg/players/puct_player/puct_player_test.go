@@ -0,0 +1,81 @@
+package puct_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestFindsMateInOne(t *testing.T) {
+	rand.Seed(1)
+	board, _ := b.FromFEN("6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1")
+
+	// unvisited moves count as draws, so the first move of a won position keeps being searched for about 130 simulations
+	pp := New().Simulations(800).Build()
+	move := pp.SelectMove(pp.Search(board), board.GetPly())
+
+	expected := b.NewMove(b.GetSquareFromString("D1"), b.GetSquareFromString("D8")).Build()
+	if move.String() != expected.String() {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", expected, move)
+	}
+}
+
+func TestSelectChild(t *testing.T) {
+	// averages of 0.5, none and 0.9 after 9 visits of the parent
+	parent := &node{visits: 9}
+	for _, child := range []*node{{prior: 0.5, visits: 4, value: 2}, {prior: 0.3}, {prior: 0.2, visits: 4, value: 3.6}} {
+		child.parent = parent
+		parent.children = append(parent.children, child)
+	}
+
+	// 0.5 + 0.45, 0 + 1.35 and 0.9 + 0.18: the prior of the unvisited child wins
+	if best := parent.selectChild(DEFAULT_CPUCT); best != parent.children[1] {
+		t.Fatalf("\nExpected: \n%v\nActual: \n%v", parent.children[1], best)
+	}
+
+	// 0.5 + 0.03, 0 + 0.09 and 0.9 + 0.012: the best average wins
+	if best := parent.selectChild(0.1); best != parent.children[2] {
+		t.Fatalf("\nExpected: \n%v\nActual: \n%v", parent.children[2], best)
+	}
+}
+
+func TestDirichletNoiseOnlyAtRoot(t *testing.T) {
+	rand.Seed(1)
+	board := b.Standard()
+
+	pp := New().DirichletNoise(DEFAULT_DIRICHLET_ALPHA, DEFAULT_DIRICHLET_EPSILON).Build()
+	root := pp.newRoot(board)
+
+	// the uniform priors of the root are mixed with noise, and still sum to 1
+	var total float64 = 0
+	var noisy bool = false
+	for _, child := range root.children {
+		total += child.prior
+		noisy = noisy || math.Abs(child.prior-1/float64(len(root.children))) > 1e-9
+	}
+	if !noisy || math.Abs(total-1) > 1e-9 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v", "noisy priors summing to 1", total)
+	}
+
+	for i := 0; i < 2*len(root.children); i++ {
+		pp.simulate(root, board)
+	}
+
+	// the children expanded by the search keep the priors of the network
+	for _, child := range root.children {
+		for _, grandchild := range child.children {
+			if expected := 1 / float64(len(child.children)); math.Abs(grandchild.prior-expected) > 1e-9 {
+				t.Fatalf("\nExpected: \n%f\nActual: \n%f", expected, grandchild.prior)
+			}
+		}
+	}
+
+	// without noise, the priors of the root are uniform
+	root = New().Build().newRoot(board)
+	for _, child := range root.children {
+		if expected := 1 / float64(len(root.children)); math.Abs(child.prior-expected) > 1e-9 {
+			t.Fatalf("\nExpected: \n%f\nActual: \n%f", expected, child.prior)
+		}
+	}
+}
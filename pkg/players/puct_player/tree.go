@@ -0,0 +1,102 @@
+package puct_player
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/network"
+	"math"
+)
+
+/**
+A node of the search tree, reached by playing its move from its parent. Values are from the perspective of the player
+who made the move, between -1 and 1
+*/
+type node struct {
+	move     b.Move
+	parent   *node
+	children []*node
+
+	// probability of the move given by the network
+	prior float64
+
+	visits float64
+	value  float64
+
+	expanded bool
+
+	// games which are over always end with the same result, for the player to move at the node
+	terminal bool
+	result   float64
+}
+
+/**
+Returns the child maximizing the PUCT bound: its average value, plus an exploration term which favors the moves the
+network likes and the search visited rarely
+*/
+func (n *node) selectChild(cpuct float64) *node {
+	var best *node
+	var bestValue float64 = math.Inf(-1)
+
+	sqrtVisits := math.Sqrt(n.visits)
+	for _, child := range n.children {
+		var q float64 = 0
+		if child.visits > 0 {
+			q = child.value / child.visits
+		}
+
+		value := q + cpuct*child.prior*sqrtVisits/(1+child.visits)
+		if value > bestValue {
+			best, bestValue = child, value
+		}
+	}
+
+	return best
+}
+
+/**
+Adds a child for every valid move, with its prior from the network, and returns the value of the board for the side to
+move: the network's value, or the result of the game if it is over
+*/
+func (n *node) expand(board b.Board, net network.PolicyValueNet) float64 {
+	n.expanded = true
+
+	moves := board.GetValidMoves()
+	if len(moves) == 0 || board.IsInsufficientMaterial() || board.GetHalfmoveClock() >= 100 {
+		n.terminal = true
+		if len(moves) == 0 && board.IsCheck() {
+			n.result = -1
+		}
+		return n.result
+	}
+
+	policy, value := net.Predict(net.Encode(board))
+	priors := network.GetMovePriors(policy, moves, board.GetTurn())
+
+	n.children = make([]*node, len(moves))
+	for i, move := range moves {
+		n.children[i] = &node{move: move, parent: n, prior: priors[i]}
+	}
+
+	return value
+}
+
+/**
+Adds the value of a new leaf to the node and its ancestors. The value is from the perspective of the player to move at
+the node
+*/
+func (n *node) backpropagate(value float64) {
+	for current := n; current != nil; current = current.parent {
+		value = -value // the perspective of the player who moved into the node
+		current.visits++
+		current.value += value
+	}
+}
+
+/**
+Mixes Dirichlet noise into the priors of the children, so that self-play explores moves the network would overlook
+*/
+func (n *node) addNoise(alpha, epsilon float64) {
+	noise := sampleDirichlet(alpha, len(n.children))
+	for i, child := range n.children {
+		child.prior = (1-epsilon)*child.prior + epsilon*noise[i]
+	}
+}
@@ -13,10 +13,20 @@ func init() {
 type RandomPlayer struct {
 	prompt   chan b.Move
 	response chan b.Move
+
+	// source of the moves, or nil for the global source
+	rand *rand.Rand
 }
 
 func New() *RandomPlayer {
-	return &RandomPlayer{nil, nil}
+	return &RandomPlayer{nil, nil, nil}
+}
+
+/**
+Returns a player drawing its moves from the given source, so that concurrent games can be replayed from their seeds
+*/
+func NewWithRand(r *rand.Rand) *RandomPlayer {
+	return &RandomPlayer{nil, nil, r}
 }
 
 func (rp *RandomPlayer) Init(prompt chan b.Move, response chan b.Move) {
@@ -31,8 +41,7 @@ func (rp *RandomPlayer) Start(board b.Board, quit chan bool) {
 		select {
 		case <-quit:
 			return
-		default:
-			move := <-rp.prompt
+		case move := <-rp.prompt:
 			if err = board.Make(move); err != nil {
 				panic(err)
 			}
@@ -46,13 +55,13 @@ func (rp *RandomPlayer) Start(board b.Board, quit chan bool) {
 }
 
 func (rp *RandomPlayer) getMove(board b.Board) b.Move {
-	srcSquare := b.GetSquareFromCoord(rand.Intn(8), rand.Intn(8))
-	dstSquare := b.GetSquareFromCoord(rand.Intn(8), rand.Intn(8))
+	srcSquare := b.GetSquareFromCoord(rp.intn(8), rp.intn(8))
+	dstSquare := b.GetSquareFromCoord(rp.intn(8), rp.intn(8))
 	move := b.NewMove(srcSquare, dstSquare).Build()
 
 	for board.IsValidMove(move) != nil {
-		srcSquare = b.GetSquareFromCoord(rand.Intn(8), rand.Intn(8))
-		dstSquare = b.GetSquareFromCoord(rand.Intn(8), rand.Intn(8))
+		srcSquare = b.GetSquareFromCoord(rp.intn(8), rp.intn(8))
+		dstSquare = b.GetSquareFromCoord(rp.intn(8), rp.intn(8))
 		move = b.NewMove(srcSquare, dstSquare).Build()
 
 		// add a promotion piece if promoting a pawn
@@ -65,6 +74,13 @@ func (rp *RandomPlayer) getMove(board b.Board) b.Move {
 }
 
 func (rp *RandomPlayer) getRandomPromotionPieceType() b.PieceType {
-	i := rand.Intn(len(b.PROMOTION_PIECE_TYPES))
+	i := rp.intn(len(b.PROMOTION_PIECE_TYPES))
 	return b.PROMOTION_PIECE_TYPES[i]
 }
+
+func (rp *RandomPlayer) intn(n int) int {
+	if rp.rand == nil {
+		return rand.Intn(n)
+	}
+	return rp.rand.Intn(n)
+}
@@ -0,0 +1,174 @@
+package polyglot
+
+import (
+	"encoding/binary"
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+)
+
+// size of a book entry in bytes
+const ENTRY_SIZE int = 16
+
+type Selection int
+
+const (
+	// picks a book move at random, with a probability proportional to its weight
+	WEIGHTED_RANDOM Selection = iota
+
+	// always picks the book move with the highest weight
+	BEST_MOVE
+)
+
+func (s Selection) String() string {
+	switch s {
+	case WEIGHTED_RANDOM:
+		return "Weighted Random"
+	case BEST_MOVE:
+		return "Best Move"
+	}
+
+	panic(fmt.Sprintf("Unhandled switch case: %d", s))
+}
+
+/**
+A single entry of a Polyglot book: a move which can be played in the position with the given key. Entries are stored
+big-endian, sorted by key
+*/
+type Entry struct {
+	Key    uint64
+	Move   uint16
+	Weight uint16
+	Learn  uint32
+}
+
+/**
+A move found in a book, together with its weight
+*/
+type BookMove struct {
+	Move   b.Move
+	Weight uint16
+}
+
+type Book struct {
+	entries []Entry
+}
+
+/**
+Reads a Polyglot book. Entries don't need to be sorted, they are sorted after reading
+*/
+func LoadBook(r io.Reader) (*Book, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data)%ENTRY_SIZE != 0 {
+		return nil, fmt.Errorf("polyglot book size must be a multiple of %d bytes, is %d bytes", ENTRY_SIZE, len(data))
+	}
+
+	entries := make([]Entry, len(data)/ENTRY_SIZE)
+	for i := range entries {
+		e := data[i*ENTRY_SIZE : (i+1)*ENTRY_SIZE]
+		entries[i] = Entry{
+			Key:    binary.BigEndian.Uint64(e[0:8]),
+			Move:   binary.BigEndian.Uint16(e[8:10]),
+			Weight: binary.BigEndian.Uint16(e[10:12]),
+			Learn:  binary.BigEndian.Uint32(e[12:16]),
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	return &Book{entries}, nil
+}
+
+func LoadBookFile(path string) (*Book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadBook(f)
+}
+
+func (bk *Book) Size() int {
+	return len(bk.entries)
+}
+
+/**
+Returns all entries stored under the given key
+*/
+func (bk *Book) GetEntries(key uint64) []Entry {
+	i := sort.Search(len(bk.entries), func(i int) bool {
+		return bk.entries[i].Key >= key
+	})
+
+	j := i
+	for j < len(bk.entries) && bk.entries[j].Key == key {
+		j += 1
+	}
+
+	return bk.entries[i:j]
+}
+
+/**
+Returns the book moves for the position which are valid on the board, ordered by decreasing weight
+*/
+func (bk *Book) GetMoves(board b.Board) []BookMove {
+	var moves []BookMove
+
+	for _, entry := range bk.GetEntries(Hash(board)) {
+		move, err := DecodeMove(board, entry.Move)
+		if err != nil || board.IsValidMove(move) != nil {
+			continue // ignore corrupt entries and hash collisions
+		}
+
+		moves = append(moves, BookMove{move, entry.Weight})
+	}
+
+	sort.SliceStable(moves, func(i, j int) bool {
+		return moves[i].Weight > moves[j].Weight
+	})
+
+	return moves
+}
+
+/**
+Picks a book move for the position, or returns false if the position is not in the book
+*/
+func (bk *Book) Pick(board b.Board, selection Selection) (b.Move, bool) {
+	moves := bk.GetMoves(board)
+	if len(moves) == 0 {
+		return nil, false
+	}
+
+	if selection == BEST_MOVE {
+		return moves[0].Move, true
+	}
+
+	var total int = 0
+	for _, m := range moves {
+		total += int(m.Weight)
+	}
+
+	if total == 0 {
+		return moves[rand.Intn(len(moves))].Move, true // all moves have zero weight, any of them will do
+	}
+
+	r := rand.Intn(total)
+	for _, m := range moves {
+		if r < int(m.Weight) {
+			return m.Move, true
+		}
+		r -= int(m.Weight)
+	}
+
+	return moves[0].Move, true
+}
@@ -0,0 +1,147 @@
+package polyglot
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"math"
+	"sort"
+)
+
+/**
+Statistics of a move played in a position, counted from the point of view of the player making the move
+*/
+type MoveStats struct {
+	Move   uint16
+	Games  int
+	Wins   int
+	Draws  int
+	Losses int
+}
+
+/**
+Returns the average score of the move for the player making it, 1 for a win and 0.5 for a draw
+*/
+func (ms *MoveStats) GetScore() float64 {
+	if ms.Games == 0 {
+		return 0
+	}
+	return (float64(ms.Wins) + 0.5*float64(ms.Draws)) / float64(ms.Games)
+}
+
+/**
+Aggregates the moves played in a collection of games, to build an opening book from them
+*/
+type Builder struct {
+	// only moves up to this ply are counted
+	maxPly int
+
+	positions map[uint64]map[uint16]*MoveStats
+}
+
+func NewBuilder(maxPly int) *Builder {
+	return &Builder{maxPly, make(map[uint64]map[uint16]*MoveStats)}
+}
+
+/**
+Counts the moves of a game played from the given board, with the score of the game for white (1, 0.5 or 0). The moves
+must be valid, and the board is left unchanged
+*/
+func (bb *Builder) AddGame(board b.Board, moves []b.Move, whiteScore float64) {
+	board = board.Copy()
+
+	for _, move := range moves {
+		if board.GetPly() >= bb.maxPly {
+			return
+		}
+
+		key := Hash(board)
+		if bb.positions[key] == nil {
+			bb.positions[key] = make(map[uint16]*MoveStats)
+		}
+
+		raw := EncodeMove(board, move)
+		stats, ok := bb.positions[key][raw]
+		if !ok {
+			stats = &MoveStats{Move: raw}
+			bb.positions[key][raw] = stats
+		}
+
+		score := whiteScore
+		if board.GetTurn() == b.BLACK {
+			score = 1 - whiteScore
+		}
+
+		stats.Games += 1
+		switch score {
+		case 1:
+			stats.Wins += 1
+		case 0:
+			stats.Losses += 1
+		default:
+			stats.Draws += 1
+		}
+
+		board.Make(move)
+	}
+}
+
+func (bb *Builder) GetNumPositions() int {
+	return len(bb.positions)
+}
+
+/**
+Returns the statistics of the moves played in the position which pass the filters, most played first
+*/
+func (bb *Builder) GetMoveStats(board b.Board, minGames int, minScore float64) []*MoveStats {
+	var moves []*MoveStats
+	for _, stats := range bb.positions[Hash(board)] {
+		if stats.Games >= minGames && stats.GetScore() >= minScore {
+			moves = append(moves, stats)
+		}
+	}
+
+	sort.Slice(moves, func(i, j int) bool {
+		if moves[i].Games != moves[j].Games {
+			return moves[i].Games > moves[j].Games
+		}
+		return moves[i].Move < moves[j].Move
+	})
+
+	return moves
+}
+
+/**
+Returns the book entries of all moves played at least minGames times with at least minScore for the player making them.
+Moves are weighted by 2 points per win and 1 per draw, scaled down if needed to fit the 16 bits of a Polyglot weight
+*/
+func (bb *Builder) GetEntries(minGames int, minScore float64) []Entry {
+	var entries []Entry
+	var maxWeight int = 0
+
+	for key, moves := range bb.positions {
+		for _, stats := range moves {
+			if stats.Games < minGames || stats.GetScore() < minScore {
+				continue
+			}
+
+			weight := 2*stats.Wins + stats.Draws
+			if weight > maxWeight {
+				maxWeight = weight
+			}
+			entries = append(entries, Entry{Key: key, Move: stats.Move})
+		}
+	}
+
+	// rescale the weights relative to each other, and keep every move playable
+	for i := range entries {
+		stats := bb.positions[entries[i].Key][entries[i].Move]
+		weight := float64(2*stats.Wins + stats.Draws)
+
+		if maxWeight > math.MaxUint16 {
+			weight = weight * math.MaxUint16 / float64(maxWeight)
+		}
+
+		entries[i].Weight = uint16(math.Max(1, weight))
+	}
+
+	return entries
+}
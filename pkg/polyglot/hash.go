@@ -0,0 +1,79 @@
+package polyglot
+
+import (
+	b "galapb/chess2022/pkg/board"
+)
+
+// offsets of the different kinds of keys in RANDOM64
+const (
+	CASTLING_OFFSET   int = 768
+	EN_PASSENT_OFFSET int = 772
+	TURN_OFFSET       int = 780
+)
+
+// piece types in the order Polyglot numbers them
+var PIECE_TYPES [6]b.PieceType = [6]b.PieceType{b.PAWN, b.KNIGHT, b.BISHOP, b.ROOK, b.QUEEN, b.KING}
+
+/**
+Returns the Polyglot hash of the position, which is the key under which it is stored in opening books
+*/
+func Hash(board b.Board) uint64 {
+	var hash uint64 = 0
+
+	// pieces
+	for i, pieceType := range PIECE_TYPES {
+		for _, c := range []b.Color{b.BLACK, b.WHITE} {
+			kind := 2 * i
+			if c == b.WHITE {
+				kind += 1
+			}
+
+			for _, square := range board.GetPieceBitmap(c, pieceType).GetSquares() {
+				hash ^= RANDOM64[64*kind+square.GetIndex()]
+			}
+		}
+	}
+
+	// castling rights
+	for i, right := range []struct {
+		color    b.Color
+		kingside bool
+	}{{b.WHITE, true}, {b.WHITE, false}, {b.BLACK, true}, {b.BLACK, false}} {
+		if board.CanCastle(right.color, right.kingside) {
+			hash ^= RANDOM64[CASTLING_OFFSET+i]
+		}
+	}
+
+	// en-passent file, only hashed if a pawn of the side to move could actually capture en-passent
+	if square, ok := board.GetEnPassentSquare(); ok && canCaptureEnPassent(board, square) {
+		hash ^= RANDOM64[EN_PASSENT_OFFSET+square.GetFile()-1]
+	}
+
+	// side to move
+	if board.GetTurn() == b.WHITE {
+		hash ^= RANDOM64[TURN_OFFSET]
+	}
+
+	return hash
+}
+
+func canCaptureEnPassent(board b.Board, enPassentSquare b.Square) bool {
+	// the capturing pawns stand next to the pawn that was just pushed, one rank behind the en-passent square
+	var rank int = enPassentSquare.GetRank() - 1
+	if board.GetTurn() == b.BLACK {
+		rank = enPassentSquare.GetRank() + 1
+	}
+
+	pawns := board.GetPieceBitmap(board.GetTurn(), b.PAWN)
+	for _, file := range []int{enPassentSquare.GetFile() - 1, enPassentSquare.GetFile() + 1} {
+		if file < 1 || file > 8 {
+			continue
+		}
+
+		if pawns&b.GetSquareFromRankAndFile(rank, file).ToBitMap() != 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,45 @@
+package polyglot
+
+import (
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+)
+
+// promotion piece types, indexed by the promotion field of a Polyglot move
+var PROMOTION_PIECE_TYPES [5]b.PieceType = [5]b.PieceType{0, b.KNIGHT, b.BISHOP, b.ROOK, b.QUEEN}
+
+/**
+Converts a move from the Polyglot encoding to a move on the given board. The bits of a Polyglot move are, from least to
+most significant: destination file (3), destination rank (3), source file (3), source rank (3) and promotion piece (3).
+Castling is encoded as the king capturing its own rook, and is converted to the king moving two squares
+*/
+func DecodeMove(board b.Board, raw uint16) (b.Move, error) {
+	dstFile := int(raw & 7)
+	dstRank := int((raw >> 3) & 7)
+	srcFile := int((raw >> 6) & 7)
+	srcRank := int((raw >> 9) & 7)
+	promotion := int((raw >> 12) & 7)
+
+	if promotion >= len(PROMOTION_PIECE_TYPES) {
+		return nil, fmt.Errorf("invalid promotion piece in polyglot move: %d", promotion)
+	}
+
+	srcSquare := b.GetSquareFromRankAndFile(srcRank+1, srcFile+1)
+	dstSquare := b.GetSquareFromRankAndFile(dstRank+1, dstFile+1)
+
+	if piece, err := board.GetPieceAt(srcSquare); err == nil && piece.GetPieceType() == b.KING && srcFile == 4 && srcRank == dstRank {
+		switch dstFile {
+		case 7:
+			dstSquare = b.GetSquareFromRankAndFile(dstRank+1, 7) // king side castling, E1H1 becomes E1G1
+		case 0:
+			dstSquare = b.GetSquareFromRankAndFile(dstRank+1, 3) // queen side castling, E1A1 becomes E1C1
+		}
+	}
+
+	builder := b.NewMove(srcSquare, dstSquare)
+	if promotion != 0 {
+		builder = builder.PromotionPieceType(PROMOTION_PIECE_TYPES[promotion])
+	}
+
+	return builder.Build(), nil
+}
@@ -0,0 +1,137 @@
+package polyglot
+
+import (
+	"bytes"
+	b "galapb/chess2022/pkg/board"
+	"strings"
+	"testing"
+)
+
+func makeMoves(t *testing.T, moves string) b.Board {
+	var board b.Board = b.Standard()
+	for _, m := range strings.Fields(moves) {
+		move := b.NewMove(b.GetSquareFromString(strings.ToUpper(m[0:2])), b.GetSquareFromString(strings.ToUpper(m[2:4]))).Build()
+		if err := board.Make(move); err != nil {
+			t.Fatalf("invalid move %s: %s", m, err)
+		}
+	}
+	return board
+}
+
+// reference keys from the Polyglot book format specification
+func TestHash(t *testing.T) {
+	tests := []struct {
+		moves    string
+		expected uint64
+	}{
+		{"", 0x463b96181691fc9c},
+		{"e2e4", 0x823c9b50fd114196},
+		{"e2e4 d7d5", 0x0756b94461c50fb0},
+		{"e2e4 d7d5 e4e5", 0x662fafb965db29d4},
+		{"e2e4 d7d5 e4e5 f7f5", 0x22a48b5a8e47ff78},
+		{"e2e4 d7d5 e4e5 f7f5 e1e2", 0x652a607ca3f242c1},
+		{"e2e4 d7d5 e4e5 f7f5 e1e2 e8f7", 0x00fdd303c946bdd9},
+		{"a2a4 b7b5 h2h4 b5b4 c2c4", 0x3c8123ea7b067637},
+		{"a2a4 b7b5 h2h4 b5b4 c2c4 b4c3 a1a2", 0x5c3f9b829b279560},
+	}
+
+	for _, test := range tests {
+		if actual := Hash(makeMoves(t, test.moves)); actual != test.expected {
+			t.Errorf("\nMoves: %s\nExpected: %016x\nActual: %016x", test.moves, test.expected, actual)
+		}
+	}
+}
+
+func encodeEntry(key uint64, move, weight uint16) []byte {
+	var e []byte = make([]byte, ENTRY_SIZE)
+	for i := 0; i < 8; i++ {
+		e[i] = byte(key >> (56 - 8*i))
+	}
+	e[8], e[9] = byte(move>>8), byte(move)
+	e[10], e[11] = byte(weight>>8), byte(weight)
+	return e
+}
+
+func TestBookPick(t *testing.T) {
+	const e2e4 uint16 = 1<<9 | 4<<6 | 3<<3 | 4
+	const d2d4 uint16 = 1<<9 | 3<<6 | 3<<3 | 3
+
+	var board b.Board = b.Standard()
+	var data []byte
+	data = append(data, encodeEntry(Hash(makeMoves(t, "e2e4")), d2d4, 50)...) // unrelated position, sorted after reading
+	data = append(data, encodeEntry(Hash(board), d2d4, 10)...)
+	data = append(data, encodeEntry(Hash(board), e2e4, 90)...)
+
+	book, err := LoadBook(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "nil-error", err)
+	}
+
+	if moves := book.GetMoves(board); len(moves) != 2 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 2, len(moves))
+	}
+
+	move, ok := book.Pick(board, BEST_MOVE)
+	if !ok || move.String() != b.NewMove(b.GetSquareFromString("E2"), b.GetSquareFromString("E4")).Build().String() {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "E2E4", move)
+	}
+
+	if _, ok := book.Pick(makeMoves(t, "a2a3"), WEIGHTED_RANDOM); ok {
+		t.Fatalf("expected position to be out of book")
+	}
+}
+
+func TestDecodeCastling(t *testing.T) {
+	board, _ := b.FromFEN("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+
+	const e1h1 uint16 = 0<<9 | 4<<6 | 0<<3 | 7
+	move, err := DecodeMove(board, e1h1)
+	if err != nil || move.GetDstSquare() != b.GetSquareFromString("G1") {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "G1", move.GetDstSquare())
+	}
+}
+
+func TestEncodeMove(t *testing.T) {
+	board, _ := b.FromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+
+	for _, move := range board.GetValidMoves() {
+		decoded, err := DecodeMove(board, EncodeMove(board, move))
+		if err != nil || decoded.String() != move.String() {
+			t.Fatalf("\nExpected: \n%s\nActual: \n%s", move, decoded)
+		}
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	builder := NewBuilder(2)
+	for _, game := range []struct {
+		moves string
+		score float64
+	}{{"e2e4 e7e5 g1f3", 1}, {"e2e4 c7c5", 0.5}, {"e2e4 e7e5", 0}, {"d2d4 d7d5", 1}} {
+		var moves []b.Move
+		for _, m := range strings.Fields(game.moves) {
+			moves = append(moves, b.NewMove(b.GetSquareFromString(strings.ToUpper(m[0:2])), b.GetSquareFromString(strings.ToUpper(m[2:4]))).Build())
+		}
+		builder.AddGame(b.Standard(), moves, game.score)
+	}
+
+	var buffer bytes.Buffer
+	if err := WriteBook(&buffer, builder.GetEntries(2, 0)); err != nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "nil-error", err)
+	}
+
+	book, err := LoadBook(&buffer)
+	if err != nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "nil-error", err)
+	}
+
+	// e4 was played 3 times, e5 twice, everything else once or beyond the ply limit
+	if book.Size() != 2 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 2, book.Size())
+	}
+
+	moves := book.GetMoves(b.Standard())
+	if len(moves) != 1 || moves[0].Weight != 3 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v", "e2e4 with weight 3", moves)
+	}
+}
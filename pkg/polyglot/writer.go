@@ -0,0 +1,81 @@
+package polyglot
+
+import (
+	"encoding/binary"
+	b "galapb/chess2022/pkg/board"
+	"io"
+	"os"
+	"sort"
+)
+
+/**
+Converts a valid move on the given board to the Polyglot encoding, the inverse of DecodeMove
+*/
+func EncodeMove(board b.Board, move b.Move) uint16 {
+	srcSquare, dstSquare := move.GetSrcSquare(), move.GetDstSquare()
+	dstFile := dstSquare.GetFile() - 1
+
+	// castling is encoded as the king capturing its own rook
+	if piece, err := board.GetPieceAt(srcSquare); err == nil && piece.GetPieceType() == b.KING && srcSquare.DistanceSquaredTo(dstSquare) == 4 {
+		if dstFile > srcSquare.GetFile()-1 {
+			dstFile = 7
+		} else {
+			dstFile = 0
+		}
+	}
+
+	var promotion uint16 = 0
+	if move.GetPromotionPieceType() != nil {
+		for i, pieceType := range PROMOTION_PIECE_TYPES {
+			if i > 0 && pieceType == *move.GetPromotionPieceType() {
+				promotion = uint16(i)
+			}
+		}
+	}
+
+	return uint16(dstFile) | uint16(dstSquare.GetRank()-1)<<3 | uint16(srcSquare.GetFile()-1)<<6 |
+		uint16(srcSquare.GetRank()-1)<<9 | promotion<<12
+}
+
+/**
+Writes the entries as a Polyglot book, sorted by key and then by decreasing weight as other tools expect
+*/
+func WriteBook(w io.Writer, entries []Entry) error {
+	sorted := make([]Entry, len(entries))
+	copy(sorted, entries)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Key != sorted[j].Key {
+			return sorted[i].Key < sorted[j].Key
+		}
+		return sorted[i].Weight > sorted[j].Weight
+	})
+
+	var e []byte = make([]byte, ENTRY_SIZE)
+	for _, entry := range sorted {
+		binary.BigEndian.PutUint64(e[0:8], entry.Key)
+		binary.BigEndian.PutUint16(e[8:10], entry.Move)
+		binary.BigEndian.PutUint16(e[10:12], entry.Weight)
+		binary.BigEndian.PutUint32(e[12:16], entry.Learn)
+
+		if _, err := w.Write(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func WriteBookFile(path string, entries []Entry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteBook(f, entries); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,496 @@
+package retrograde
+
+import (
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"sort"
+	"strings"
+)
+
+// largest number of pieces, including kings, of a table
+const MAX_PIECES int = 4
+
+// piece types in the order of material signatures, and their letters
+var SIGNATURE_PIECE_TYPES [6]b.PieceType = [6]b.PieceType{b.KING, b.QUEEN, b.ROOK, b.BISHOP, b.KNIGHT, b.PAWN}
+var SIGNATURE_LETTERS string = "KQRBNP"
+
+var (
+	KING_TARGETS   [64][]int
+	KNIGHT_TARGETS [64][]int
+
+	ROOK_DIRECTIONS   [][2]int = [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	BISHOP_DIRECTIONS [][2]int = [][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	QUEEN_DIRECTIONS  [][2]int = append(append([][2]int{}, ROOK_DIRECTIONS...), BISHOP_DIRECTIONS...)
+)
+
+func init() {
+	knightSteps := [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+
+	for s := 0; s < 64; s++ {
+		for _, d := range QUEEN_DIRECTIONS {
+			if t, ok := step(s, d[0], d[1]); ok {
+				KING_TARGETS[s] = append(KING_TARGETS[s], t)
+			}
+		}
+
+		for _, d := range knightSteps {
+			if t, ok := step(s, d[0], d[1]); ok {
+				KNIGHT_TARGETS[s] = append(KNIGHT_TARGETS[s], t)
+			}
+		}
+	}
+}
+
+/**
+Squares are numbered from A1 (0) to H8 (63), rank by rank
+*/
+func getFile(s int) int {
+	return s & 7
+}
+
+func getRank(s int) int {
+	return s >> 3
+}
+
+func step(s, df, dr int) (int, bool) {
+	file, rank := getFile(s)+df, getRank(s)+dr
+	if file < 0 || file > 7 || rank < 0 || rank > 7 {
+		return 0, false
+	}
+	return 8*rank + file, true
+}
+
+func toSquare(s int) b.Square {
+	return b.GetSquareFromRankAndFile(getRank(s)+1, getFile(s)+1)
+}
+
+type piece struct {
+	color     b.Color
+	pieceType b.PieceType
+}
+
+/**
+A position of a table: the squares of its pieces, in the order of the signature, and the side to move
+*/
+type position struct {
+	pieces  []piece
+	squares []int
+	turn    b.Color
+
+	// index of the piece on each square, plus one, or 0 for empty squares
+	occupancy [64]int8
+}
+
+func newPosition(pieces []piece, squares []int, turn b.Color) *position {
+	p := &position{pieces: pieces, squares: squares, turn: turn}
+	for i, s := range squares {
+		p.occupancy[s] = int8(i + 1)
+	}
+	return p
+}
+
+/**
+Returns the pieces of a material signature, such as KQvK, white pieces first
+*/
+func parseSignature(signature string) ([]piece, error) {
+	sides := strings.Split(signature, "v")
+	if len(sides) != 2 {
+		return nil, fmt.Errorf("invalid signature: %s", signature)
+	}
+
+	var pieces []piece
+	for i, side := range sides {
+		color := b.WHITE
+		if i == 1 {
+			color = b.BLACK
+		}
+
+		if !strings.HasPrefix(side, "K") || strings.Count(side, "K") != 1 {
+			return nil, fmt.Errorf("invalid signature: %s", signature)
+		}
+
+		for _, r := range side {
+			j := strings.IndexRune(SIGNATURE_LETTERS, r)
+			if j < 0 {
+				return nil, fmt.Errorf("invalid signature: %s", signature)
+			}
+			pieces = append(pieces, piece{color, SIGNATURE_PIECE_TYPES[j]})
+		}
+	}
+
+	if len(pieces) > MAX_PIECES {
+		return nil, fmt.Errorf("signature %s has more than %d pieces", signature, MAX_PIECES)
+	}
+
+	return pieces, nil
+}
+
+/**
+Sorts the pieces, along with their squares, in signature order, and returns the signature
+*/
+func sortPieces(pieces []piece, squares []int) string {
+	order := func(p piece) int {
+		i := int(p.color) * len(SIGNATURE_PIECE_TYPES)
+		for j, pt := range SIGNATURE_PIECE_TYPES {
+			if pt == p.pieceType {
+				return i + j
+			}
+		}
+		return i
+	}
+
+	idx := make([]int, len(pieces))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return order(pieces[idx[i]]) < order(pieces[idx[j]])
+	})
+
+	sortedPieces := make([]piece, len(pieces))
+	sortedSquares := make([]int, len(squares))
+	for i, j := range idx {
+		sortedPieces[i], sortedSquares[i] = pieces[j], squares[j]
+	}
+	copy(pieces, sortedPieces)
+	copy(squares, sortedSquares)
+
+	var sides [2]strings.Builder
+	for _, p := range pieces {
+		sides[p.color].WriteByte(SIGNATURE_LETTERS[order(piece{b.WHITE, p.pieceType})])
+	}
+
+	return sides[0].String() + "v" + sides[1].String()
+}
+
+func swapSides(signature string) string {
+	sides := strings.Split(signature, "v")
+	return sides[1] + "v" + sides[0]
+}
+
+/**
+Returns the number of positions of a table with the given number of pieces, including illegal ones
+*/
+func getSize(numPieces int) int {
+	return 2 << (6 * numPieces)
+}
+
+func (p *position) getIndex() int {
+	idx := int(p.turn)
+	for _, s := range p.squares {
+		idx = idx<<6 | s
+	}
+	return idx
+}
+
+func fromIndex(pieces []piece, idx int) *position {
+	squares := make([]int, len(pieces))
+	for i := len(pieces) - 1; i >= 0; i-- {
+		squares[i] = idx & 63
+		idx >>= 6
+	}
+	return newPosition(pieces, squares, b.Color(idx))
+}
+
+/**
+Returns true if the pieces are on distinct squares, no pawn is on the first or last rank, and the side which just moved
+is not in check
+*/
+func (p *position) isLegal() bool {
+	for i, s := range p.squares {
+		if int(p.occupancy[s]) != i+1 {
+			return false
+		}
+		if p.pieces[i].pieceType == b.PAWN && (getRank(s) == 0 || getRank(s) == 7) {
+			return false
+		}
+	}
+
+	return !p.isAttacked(p.getKingSquare(p.turn.Opposite()), p.turn)
+}
+
+func (p *position) getKingSquare(c b.Color) int {
+	for i, pc := range p.pieces {
+		if pc.color == c && pc.pieceType == b.KING {
+			return p.squares[i]
+		}
+	}
+	panic(fmt.Sprintf("no %s king", c))
+}
+
+func (p *position) isCheck() bool {
+	return p.isAttacked(p.getKingSquare(p.turn), p.turn.Opposite())
+}
+
+/**
+Returns true if a piece of the given color attacks the square
+*/
+func (p *position) isAttacked(target int, by b.Color) bool {
+	for i, pc := range p.pieces {
+		if pc.color == by && p.attacks(pc, p.squares[i], target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *position) attacks(pc piece, from, to int) bool {
+	df, dr := getFile(to)-getFile(from), getRank(to)-getRank(from)
+
+	switch pc.pieceType {
+	case b.KING:
+		return from != to && abs(df) <= 1 && abs(dr) <= 1
+	case b.KNIGHT:
+		return abs(df*dr) == 2
+	case b.PAWN:
+		forward := 1
+		if pc.color == b.BLACK {
+			forward = -1
+		}
+		return dr == forward && abs(df) == 1
+	case b.ROOK:
+		if df != 0 && dr != 0 {
+			return false
+		}
+	case b.BISHOP:
+		if abs(df) != abs(dr) {
+			return false
+		}
+	case b.QUEEN:
+		if df != 0 && dr != 0 && abs(df) != abs(dr) {
+			return false
+		}
+	}
+
+	if from == to {
+		return false
+	}
+
+	// sliding pieces need every square in between to be empty
+	sf, sr := sign(df), sign(dr)
+	for s, _ := step(from, sf, sr); s != to; s, _ = step(s, sf, sr) {
+		if p.occupancy[s] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type move struct {
+	piece     int
+	from      int
+	to        int
+	captured  int // index of the captured piece, or -1
+	promotion b.PieceType
+}
+
+func (m move) isConversion() bool {
+	return m.captured >= 0 || m.promotion != 0
+}
+
+func (m move) toBoardMove() b.Move {
+	builder := b.NewMove(toSquare(m.from), toSquare(m.to))
+	if m.promotion != 0 {
+		builder = builder.PromotionPieceType(m.promotion)
+	}
+	return builder.Build()
+}
+
+/**
+Returns the legal moves of the side to move. En-passent captures are not generated, since the tables don't store which
+pawn just moved two squares
+*/
+func (p *position) getMoves() []move {
+	var moves []move
+
+	add := func(i, to int) {
+		captured := int(p.occupancy[to]) - 1
+		if captured >= 0 && p.pieces[captured].color == p.turn {
+			return
+		}
+
+		if p.pieces[i].pieceType == b.PAWN && (getRank(to) == 0 || getRank(to) == 7) {
+			for _, pt := range b.PROMOTION_PIECE_TYPES {
+				moves = append(moves, move{i, p.squares[i], to, captured, pt})
+			}
+			return
+		}
+
+		moves = append(moves, move{i, p.squares[i], to, captured, 0})
+	}
+
+	for i, pc := range p.pieces {
+		if pc.color != p.turn {
+			continue
+		}
+
+		s := p.squares[i]
+		switch pc.pieceType {
+		case b.KING:
+			for _, t := range KING_TARGETS[s] {
+				add(i, t)
+			}
+		case b.KNIGHT:
+			for _, t := range KNIGHT_TARGETS[s] {
+				add(i, t)
+			}
+		case b.PAWN:
+			forward, startRank := 1, 1
+			if pc.color == b.BLACK {
+				forward, startRank = -1, 6
+			}
+
+			if t, ok := step(s, 0, forward); ok && p.occupancy[t] == 0 {
+				add(i, t)
+				if t2, ok := step(t, 0, forward); ok && getRank(s) == startRank && p.occupancy[t2] == 0 {
+					add(i, t2)
+				}
+			}
+
+			for _, df := range []int{-1, 1} {
+				if t, ok := step(s, df, forward); ok && p.occupancy[t] != 0 {
+					add(i, t)
+				}
+			}
+		default:
+			for _, t := range p.getSlidingTargets(i) {
+				add(i, t)
+			}
+		}
+	}
+
+	// keep the moves which don't leave the king in check
+	var legal []move
+	for _, m := range moves {
+		next := p.makeMove(m)
+		if !next.isAttacked(next.getKingSquare(p.turn), next.turn) {
+			legal = append(legal, m)
+		}
+	}
+
+	return legal
+}
+
+/**
+Returns the squares a sliding piece reaches, up to and including the first occupied square of each direction
+*/
+func (p *position) getSlidingTargets(i int) []int {
+	directions := QUEEN_DIRECTIONS
+	switch p.pieces[i].pieceType {
+	case b.ROOK:
+		directions = ROOK_DIRECTIONS
+	case b.BISHOP:
+		directions = BISHOP_DIRECTIONS
+	}
+
+	var targets []int
+	for _, d := range directions {
+		for t, ok := step(p.squares[i], d[0], d[1]); ok; t, ok = step(t, d[0], d[1]) {
+			targets = append(targets, t)
+			if p.occupancy[t] != 0 {
+				break
+			}
+		}
+	}
+	return targets
+}
+
+/**
+Returns the position after the move. Captures and promotions change the material, so the pieces of the returned
+position may be in a different order than the signature of any table
+*/
+func (p *position) makeMove(m move) *position {
+	var pieces []piece
+	var squares []int
+
+	for i, pc := range p.pieces {
+		if i == m.captured {
+			continue
+		}
+
+		s := p.squares[i]
+		if i == m.piece {
+			s = m.to
+			if m.promotion != 0 {
+				pc.pieceType = m.promotion
+			}
+		}
+
+		pieces = append(pieces, pc)
+		squares = append(squares, s)
+	}
+
+	return newPosition(pieces, squares, p.turn.Opposite())
+}
+
+/**
+Returns the positions from which the side which just moved could have reached this one, without capturing or
+promoting. Some of them may be illegal
+*/
+func (p *position) getPredecessors() []*position {
+	var predecessors []*position
+
+	add := func(i, from int) {
+		if p.occupancy[from] != 0 {
+			return
+		}
+
+		squares := append([]int{}, p.squares...)
+		squares[i] = from
+		predecessors = append(predecessors, newPosition(p.pieces, squares, p.turn.Opposite()))
+	}
+
+	for i, pc := range p.pieces {
+		if pc.color == p.turn {
+			continue
+		}
+
+		s := p.squares[i]
+		switch pc.pieceType {
+		case b.KING:
+			for _, f := range KING_TARGETS[s] {
+				add(i, f)
+			}
+		case b.KNIGHT:
+			for _, f := range KNIGHT_TARGETS[s] {
+				add(i, f)
+			}
+		case b.PAWN:
+			backward, doublePushRank := -1, 3
+			if pc.color == b.BLACK {
+				backward, doublePushRank = 1, 4
+			}
+
+			if f, ok := step(s, 0, backward); ok && p.occupancy[f] == 0 {
+				add(i, f)
+				if f2, ok := step(f, 0, backward); ok && getRank(s) == doublePushRank {
+					add(i, f2)
+				}
+			}
+		default:
+			// moves of sliding pieces are reversible, except that they can't have passed through a piece
+			for _, f := range p.getSlidingTargets(i) {
+				add(i, f)
+			}
+		}
+	}
+
+	return predecessors
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func sign(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,231 @@
+package retrograde
+
+import (
+	"bytes"
+	b "galapb/chess2022/pkg/board"
+	"testing"
+)
+
+func fromFEN(t *testing.T, fen string) b.Board {
+	board, err := b.FromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return board
+}
+
+// the moves of the solver must match those of the board, which are slower but trusted
+func TestMoveGeneration(t *testing.T) {
+	fens := []string{
+		"8/8/4k3/8/8/8/4K3/7Q w - - 0 1",
+		"8/8/4k3/8/8/8/4K3/7Q b - - 0 1",
+		"7k/8/5K2/8/8/8/8/6R1 w - - 0 1",
+		"8/8/8/8/8/4k3/4P3/4K3 w - - 0 1",
+		"8/3P4/8/8/8/1k6/8/3K4 w - - 0 1",
+		"2k5/8/8/8/8/8/1p6/R3K3 b - - 0 1",
+	}
+
+	for _, fen := range fens {
+		board := fromFEN(t, fen)
+		expected := len(board.GetValidMoves())
+		if actual := len(fromBoard(board).getMoves()); actual != expected {
+			t.Fatalf("\nFEN: %s\nExpected: \n%d\nActual: \n%d", fen, expected, actual)
+		}
+	}
+}
+
+func TestSolve(t *testing.T) {
+	tb := NewTablebase()
+
+	// the longest mates are known: 10 moves for KQvK, 16 moves for KRvK
+	for signature, expected := range map[string]int{"KQvK": 19, "KRvK": 31} {
+		table, err := tb.Solve(signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if longest, _ := table.GetStats(); longest != expected {
+			t.Fatalf("\nSignature: %s\nExpected: \n%d\nActual: \n%d", signature, expected, longest)
+		}
+	}
+
+	tests := []struct {
+		fen      string
+		expected Result
+	}{
+		{"7k/8/6K1/8/8/8/8/R7 w - - 0 1", Result{WIN, 1}},
+		{"R6k/8/6K1/8/8/8/8/8 b - - 0 1", Result{LOSS, 0}},
+		{"k7/8/1Q6/8/8/8/8/7K b - - 0 1", Result{DRAW, 0}}, // stalemate
+		{"r7/8/8/8/8/6k1/8/7K b - - 0 1", Result{WIN, 1}},  // colors swapped
+	}
+
+	for _, test := range tests {
+		if actual, err := tb.Probe(fromFEN(t, test.fen)); err != nil || actual != test.expected {
+			t.Fatalf("\nFEN: %s\nExpected: \n%s\nActual: \n%s (%v)", test.fen, test.expected, actual, err)
+		}
+	}
+
+	moves, _, err := tb.GetBestMoves(fromFEN(t, "7k/8/6K1/8/8/8/8/R7 w - - 0 1"))
+	if err != nil || len(moves) != 1 || moves[0].GetDstSquare() != b.GetSquareFromString("A8") {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%v (%v)", "a1a8", moves, err)
+	}
+
+	// tables are read back as they were written
+	var buf bytes.Buffer
+	table, _ := tb.GetTable("KQvK")
+	if err := table.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadTable(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if read.signature != table.signature || !equal(read.values, table.values) {
+		t.Fatalf("table %s changed after being written", table.signature)
+	}
+}
+
+/**
+Returns the board of a position of a table, through its FEN
+*/
+func toBoard(t *testing.T, p *position) b.Board {
+	var rows [8][8]byte
+	for i, s := range p.squares {
+		letter := SIGNATURE_LETTERS[indexOf(SIGNATURE_PIECE_TYPES[:], p.pieces[i].pieceType)]
+		if p.pieces[i].color == b.BLACK {
+			letter += 'a' - 'A'
+		}
+		rows[7-getRank(s)][getFile(s)] = letter
+	}
+
+	var fen []byte
+	for i, row := range rows {
+		empty := 0
+		for _, c := range row {
+			if c == 0 {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				fen = append(fen, byte('0'+empty))
+				empty = 0
+			}
+			fen = append(fen, c)
+		}
+		if empty > 0 {
+			fen = append(fen, byte('0'+empty))
+		}
+		if i < 7 {
+			fen = append(fen, '/')
+		}
+	}
+
+	turn := " w"
+	if p.turn == b.BLACK {
+		turn = " b"
+	}
+	return fromFEN(t, string(fen)+turn+" - - 0 1")
+}
+
+func indexOf(pieceTypes []b.PieceType, pt b.PieceType) int {
+	for i, other := range pieceTypes {
+		if other == pt {
+			return i
+		}
+	}
+	return -1
+}
+
+// every legal KPvK position of a sample, including the promotions of both colors' pawns
+func TestMoveGenerationKPvK(t *testing.T) {
+	for _, signature := range []string{"KPvK", "KvKP"} {
+		pieces, err := parseSignature(signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for idx := 0; idx < getSize(len(pieces)); idx += 61 {
+			p := fromIndex(pieces, idx)
+			if !p.isLegal() {
+				continue
+			}
+
+			board := toBoard(t, p)
+			expected := len(board.GetValidMoves())
+			if actual := len(p.getMoves()); actual != expected {
+				t.Fatalf("\nFEN: %s\nExpected: \n%d\nActual: \n%d", board.FEN(), expected, actual)
+			}
+		}
+	}
+}
+
+func TestSolveKPvK(t *testing.T) {
+	tb := NewTablebase()
+	if _, err := tb.Solve("KPvK"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		fen      string
+		expected Result
+	}{
+		{"k7/2P5/1K6/8/8/8/8/8 w - - 0 1", Result{WIN, 1}},  // c8=Q# or c8=R#
+		{"k7/2P5/1K6/8/8/8/8/8 b - - 0 1", Result{DRAW, 0}}, // stalemate
+		{"k7/P7/1K6/8/8/8/8/8 b - - 0 1", Result{DRAW, 0}},  // stalemate
+		{"8/8/8/8/8/4k3/4P3/4K3 w - - 0 1", Result{DRAW, 0}},
+		{"k7/8/8/8/8/8/P7/7K w - - 0 1", Result{DRAW, 0}}, // the king holds the corner of the rook pawn
+	}
+
+	for _, test := range tests {
+		if actual, err := tb.Probe(fromFEN(t, test.fen)); err != nil || actual != test.expected {
+			t.Fatalf("\nFEN: %s\nExpected: \n%s\nActual: \n%s (%v)", test.fen, test.expected, actual, err)
+		}
+	}
+
+	// decided games, whose mates go through a promotion into the tables of the promoted piece
+	outcomes := []struct {
+		fen      string
+		expected Outcome
+	}{
+		{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", WIN}, // the king in front of its pawn on the sixth rank wins with either side to move
+		{"4k3/8/4K3/4P3/8/8/8/8 b - - 0 1", LOSS},
+		{"7k/8/8/8/P7/8/8/K7 w - - 0 1", WIN}, // the king is outside the square of the pawn
+	}
+
+	for _, test := range outcomes {
+		board := fromFEN(t, test.fen)
+		actual, err := tb.Probe(board)
+		if err != nil || actual.Outcome != test.expected {
+			t.Fatalf("\nFEN: %s\nExpected: \n%s\nActual: \n%s (%v)", test.fen, test.expected, actual, err)
+		}
+
+		// every best move brings the mate one ply closer, down to the mate
+		for actual.DTM > 0 {
+			moves, result, err := tb.GetBestMoves(board)
+			if err != nil || len(moves) == 0 || result != actual {
+				t.Fatalf("\nFEN: %s\nExpected: \n%s\nActual: \n%s (%v)", board.FEN(), actual, result, err)
+			}
+			board.Make(moves[0])
+
+			next, err := tb.Probe(board)
+			if err != nil || next.previous() != actual {
+				t.Fatalf("\nFEN: %s\nExpected: \n%s\nActual: \n%s (%v)", board.FEN(), actual, next.previous(), err)
+			}
+			actual = next
+		}
+	}
+}
+
+func equal(a, b []int16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,207 @@
+package retrograde
+
+import (
+	b "galapb/chess2022/pkg/board"
+)
+
+// kinds of events of the backward induction
+const (
+	// the position was resolved at the event's ply, so its predecessors are updated
+	RESOLVED uint8 = iota
+	// a capture or promotion of the position mates at the event's ply
+	WINNING_CONVERSION
+	// a capture or promotion of the position loses at the event's ply
+	LOSING_CONVERSION
+)
+
+type event struct {
+	idx  int
+	kind uint8
+}
+
+/**
+Solves the table of the signature, such as KQvK, and adds it to the tablebase. The tables of the material reached by
+captures and promotions are solved first, if the tablebase doesn't have them yet
+*/
+func (tb *Tablebase) Solve(signature string) (*Table, error) {
+	pieces, err := parseSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	if t, ok := tb.tables[signature]; ok {
+		return t, nil
+	}
+
+	for _, sub := range getSubSignatures(pieces) {
+		if !tb.Has(sub) {
+			if _, err := tb.Solve(sub); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	t := &Table{signature, pieces, make([]int16, getSize(len(pieces)))}
+	if err := tb.solve(t); err != nil {
+		return nil, err
+	}
+
+	tb.Add(t)
+	return t, nil
+}
+
+/**
+Returns the signatures reached by captures and promotions, except for bare kings
+*/
+func getSubSignatures(pieces []piece) []string {
+	var signatures []string
+
+	add := func(ps []piece) {
+		if len(ps) <= 2 {
+			return
+		}
+		signature := sortPieces(ps, make([]int, len(ps)))
+		for _, s := range signatures {
+			if s == signature {
+				return
+			}
+		}
+		signatures = append(signatures, signature)
+	}
+
+	remove := func(ps []piece, i int) []piece {
+		return append(append([]piece{}, ps[:i]...), ps[i+1:]...)
+	}
+
+	for i, captured := range pieces {
+		if captured.pieceType != b.KING {
+			add(remove(pieces, i))
+		}
+	}
+
+	for i, pawn := range pieces {
+		if pawn.pieceType != b.PAWN {
+			continue
+		}
+
+		for _, pt := range b.PROMOTION_PIECE_TYPES {
+			promoted := append([]piece{}, pieces...)
+			promoted[i].pieceType = pt
+			add(promoted)
+
+			// capturing while promoting
+			for j, captured := range promoted {
+				if captured.color != pawn.color && captured.pieceType != b.KING {
+					add(remove(promoted, j))
+				}
+			}
+		}
+	}
+
+	return signatures
+}
+
+/**
+Computes the distance to mate of every position by backward induction. Mated positions are lost in 0 plies, positions
+with a move to a position lost in n plies are won in n+1 plies, and positions whose moves all lead to won positions are
+lost one ply after the slowest of them. Positions are resolved ply by ply, so that the first result found is the best
+*/
+func (tb *Tablebase) solve(t *Table) error {
+	var events [][]event
+	schedule := func(ply int, e event) {
+		for len(events) <= ply {
+			events = append(events, nil)
+		}
+		events[ply] = append(events[ply], e)
+	}
+
+	resolved := make([]bool, len(t.values))
+	remaining := make([]uint8, len(t.values)) // moves not yet known to lose
+
+	for idx := range t.values {
+		p := fromIndex(t.pieces, idx)
+		if !p.isLegal() {
+			t.values[idx], resolved[idx] = ILLEGAL, true
+			continue
+		}
+
+		moves := p.getMoves()
+		if len(moves) == 0 {
+			resolved[idx] = true
+			if p.isCheck() {
+				t.values[idx] = encode(Result{LOSS, 0})
+				schedule(0, event{idx, RESOLVED})
+			}
+			continue
+		}
+
+		remaining[idx] = uint8(len(moves))
+
+		// captures and promotions lead to other tables, which are already solved
+		for _, m := range moves {
+			if !m.isConversion() {
+				continue
+			}
+
+			r, err := tb.probe(p.makeMove(m))
+			if err != nil {
+				return err
+			}
+
+			switch r.Outcome {
+			case LOSS:
+				schedule(r.DTM+1, event{idx, WINNING_CONVERSION})
+			case WIN:
+				schedule(r.DTM, event{idx, LOSING_CONVERSION})
+			}
+		}
+	}
+
+	for ply := 0; ply < len(events); ply++ {
+		for i := 0; i < len(events[ply]); i++ {
+			e := events[ply][i]
+
+			switch e.kind {
+			case WINNING_CONVERSION:
+				if !resolved[e.idx] {
+					t.values[e.idx], resolved[e.idx] = encode(Result{WIN, ply}), true
+					schedule(ply, event{e.idx, RESOLVED})
+				}
+			case LOSING_CONVERSION:
+				if !resolved[e.idx] {
+					remaining[e.idx] -= 1
+					if remaining[e.idx] == 0 {
+						t.values[e.idx], resolved[e.idx] = encode(Result{LOSS, ply + 1}), true
+						schedule(ply+1, event{e.idx, RESOLVED})
+					}
+				}
+			case RESOLVED:
+				r := decode(t.values[e.idx])
+
+				for _, prev := range fromIndex(t.pieces, e.idx).getPredecessors() {
+					j := prev.getIndex()
+					if resolved[j] {
+						continue
+					}
+
+					if r.Outcome == LOSS {
+						t.values[j], resolved[j] = encode(Result{WIN, ply + 1}), true
+						schedule(ply+1, event{j, RESOLVED})
+						continue
+					}
+
+					remaining[j] -= 1
+					if remaining[j] == 0 {
+						t.values[j], resolved[j] = encode(Result{LOSS, ply + 1}), true
+						schedule(ply+1, event{j, RESOLVED})
+					}
+				}
+			}
+		}
+
+		events[ply] = nil
+	}
+
+	// positions never resolved can avoid losing forever, so they are drawn
+	return nil
+}
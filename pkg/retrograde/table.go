@@ -0,0 +1,380 @@
+package retrograde
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+const TABLE_SUFFIX string = ".dtm"
+
+// first bytes of every table file
+var MAGIC [4]byte = [4]byte{'D', 'T', 'M', '1'}
+
+var ErrNotFound error = errors.New("no table for the position")
+
+// stored value of positions which can't happen
+const ILLEGAL int16 = math.MinInt16
+
+type Outcome int
+
+const (
+	LOSS Outcome = iota - 1
+	DRAW
+	WIN
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case LOSS:
+		return "Loss"
+	case DRAW:
+		return "Draw"
+	case WIN:
+		return "Win"
+	}
+
+	panic(fmt.Sprintf("Unhandled switch case: %d", o))
+}
+
+/**
+The result of a position for the side to move under perfect play, and the number of plies until mate. The fifty-move
+rule is ignored
+*/
+type Result struct {
+	Outcome Outcome
+	DTM     int
+}
+
+func (r Result) String() string {
+	if r.Outcome == DRAW {
+		return r.Outcome.String()
+	}
+	return fmt.Sprintf("%s in %d plies", r.Outcome, r.DTM)
+}
+
+/**
+Returns the result from the perspective of the other side, one ply earlier
+*/
+func (r Result) previous() Result {
+	if r.Outcome == DRAW {
+		return r
+	}
+	return Result{-r.Outcome, r.DTM + 1}
+}
+
+/**
+Results are stored as wins in n plies as n, losses in n plies as -n-1 and draws as 0
+*/
+func encode(r Result) int16 {
+	switch r.Outcome {
+	case WIN:
+		return int16(r.DTM)
+	case LOSS:
+		return int16(-r.DTM - 1)
+	}
+	return 0
+}
+
+func decode(v int16) Result {
+	switch {
+	case v > 0:
+		return Result{WIN, int(v)}
+	case v < 0:
+		return Result{LOSS, int(-v - 1)}
+	}
+	return Result{DRAW, 0}
+}
+
+/**
+The results of every position of a material signature, indexed by side to move and squares of the pieces
+*/
+type Table struct {
+	signature string
+	pieces    []piece
+	values    []int16
+}
+
+func (t *Table) GetSignature() string {
+	return t.signature
+}
+
+/**
+Returns the longest distance to mate of the table, and the number of won, drawn and lost positions
+*/
+func (t *Table) GetStats() (int, [3]int) {
+	var longest int
+	var counts [3]int
+
+	for _, v := range t.values {
+		if v == ILLEGAL {
+			continue
+		}
+
+		r := decode(v)
+		counts[r.Outcome+1] += 1
+		if r.Outcome == WIN && r.DTM > longest {
+			longest = r.DTM
+		}
+	}
+
+	return longest, counts
+}
+
+/**
+Writes the table, compressed, since most values of a table are repeated
+*/
+func (t *Table) Write(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	bw := bufio.NewWriter(gz)
+
+	bw.Write(MAGIC[:])
+	bw.WriteByte(byte(len(t.signature)))
+	bw.WriteString(t.signature)
+
+	var buf [2]byte
+	for _, v := range t.values {
+		binary.LittleEndian.PutUint16(buf[:], uint16(v))
+		bw.Write(buf[:])
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (t *Table) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := t.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func ReadTable(r io.Reader) (*Table, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(gz)
+
+	var magic [4]byte
+	if _, err := io.ReadFull(br, magic[:]); err != nil || magic != MAGIC {
+		return nil, fmt.Errorf("not a dtm table")
+	}
+
+	length, err := br.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	signature := make([]byte, length)
+	if _, err := io.ReadFull(br, signature); err != nil {
+		return nil, err
+	}
+
+	pieces, err := parseSignature(string(signature))
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Table{string(signature), pieces, make([]int16, getSize(len(pieces)))}
+
+	var buf [2]byte
+	for i := range t.values {
+		if _, err := io.ReadFull(br, buf[:]); err != nil {
+			return nil, fmt.Errorf("truncated table %s: %s", t.signature, err)
+		}
+		t.values[i] = int16(binary.LittleEndian.Uint16(buf[:]))
+	}
+
+	return t, nil
+}
+
+func ReadTableFile(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadTable(f)
+}
+
+/**
+A set of tables. Positions are looked up in the table of their signature, or in the table with the colors swapped
+*/
+type Tablebase struct {
+	tables map[string]*Table
+}
+
+func NewTablebase() *Tablebase {
+	return &Tablebase{make(map[string]*Table)}
+}
+
+/**
+Reads all tables of a directory
+*/
+func LoadTablebase(dir string) (*Tablebase, error) {
+	tb := NewTablebase()
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+TABLE_SUFFIX))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		t, err := ReadTableFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %s", path, err)
+		}
+		tb.Add(t)
+	}
+
+	return tb, nil
+}
+
+/**
+Writes every table to the directory, one file per signature
+*/
+func (tb *Tablebase) Save(dir string) error {
+	for signature, t := range tb.tables {
+		if err := t.WriteFile(filepath.Join(dir, signature+TABLE_SUFFIX)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tb *Tablebase) Add(t *Table) {
+	tb.tables[t.signature] = t
+}
+
+func (tb *Tablebase) GetTable(signature string) (*Table, bool) {
+	t, ok := tb.tables[signature]
+	return t, ok
+}
+
+func (tb *Tablebase) GetSignatures() []string {
+	var signatures []string
+	for signature := range tb.tables {
+		signatures = append(signatures, signature)
+	}
+	return signatures
+}
+
+/**
+Returns true if the table of the signature, or of the signature with the colors swapped, is in the tablebase
+*/
+func (tb *Tablebase) Has(signature string) bool {
+	_, ok := tb.tables[signature]
+	_, swapped := tb.tables[swapSides(signature)]
+	return ok || swapped
+}
+
+/**
+Returns the result of the position for the side to move. Castling rights and en-passent squares are ignored
+*/
+func (tb *Tablebase) Probe(board b.Board) (Result, error) {
+	return tb.probe(fromBoard(board))
+}
+
+/**
+Returns the moves leading to the best result of the position, along with that result
+*/
+func (tb *Tablebase) GetBestMoves(board b.Board) ([]b.Move, Result, error) {
+	p := fromBoard(board)
+
+	best, err := tb.probe(p)
+	if err != nil {
+		return nil, best, err
+	}
+
+	var moves []b.Move
+	for _, m := range p.getMoves() {
+		r, err := tb.probe(p.makeMove(m))
+		if err != nil {
+			return nil, best, err
+		}
+
+		if r.previous() == best {
+			moves = append(moves, m.toBoardMove())
+		}
+	}
+
+	return moves, best, nil
+}
+
+/**
+Looks the position up, whatever the order of its pieces
+*/
+func (tb *Tablebase) probe(p *position) (Result, error) {
+	if len(p.pieces) == 2 {
+		return Result{DRAW, 0}, nil // only kings
+	}
+
+	pieces := append([]piece{}, p.pieces...)
+	squares := append([]int{}, p.squares...)
+	turn := p.turn
+
+	signature := sortPieces(pieces, squares)
+	t, ok := tb.tables[signature]
+
+	if !ok {
+		// mirror the position so that the colors match the table
+		if t, ok = tb.tables[swapSides(signature)]; !ok {
+			return Result{}, ErrNotFound
+		}
+
+		for i := range pieces {
+			pieces[i].color = pieces[i].color.Opposite()
+			squares[i] ^= 56
+		}
+		turn = turn.Opposite()
+		sortPieces(pieces, squares)
+	}
+
+	v := t.values[newPosition(pieces, squares, turn).getIndex()]
+	if v == ILLEGAL {
+		return Result{}, fmt.Errorf("illegal position for table %s", t.signature)
+	}
+
+	return decode(v), nil
+}
+
+func fromBoard(board b.Board) *position {
+	var pieces []piece
+	var squares []int
+
+	for _, c := range []b.Color{b.WHITE, b.BLACK} {
+		for _, pt := range SIGNATURE_PIECE_TYPES {
+			for _, s := range board.GetPieceBitmap(c, pt).GetSquares() {
+				pieces = append(pieces, piece{c, pt})
+				squares = append(squares, s.GetIndex())
+			}
+		}
+	}
+
+	return newPosition(pieces, squares, board.GetTurn())
+}
+
+/**
+Returns the signature of the position, such as KQvK
+*/
+func GetSignature(board b.Board) string {
+	p := fromBoard(board)
+	return sortPieces(p.pieces, p.squares)
+}
@@ -0,0 +1,180 @@
+package syzygy
+
+// maximum number of pieces in a table, including kings
+const TB_PIECES int = 7
+
+var (
+	// BINOMIAL[k][n] is the number of ways to choose k elements from a set of n elements
+	BINOMIAL [6][64]uint64
+
+	// encodes the squares of the a1-d1-d4 triangle to 0..9, squares below the a1-h8 diagonal first
+	MAP_A1D1D4 [64]int
+
+	// encodes the squares below the a1-h8 diagonal to 0..27
+	MAP_B1H1H7 [64]int
+
+	// encodes the 462 legal placements of two kings, where the first is in the a1-d1-d4 triangle
+	MAP_KK [10][64]int
+
+	// encodes the squares a2-h7 to 0..47, the pawn with the highest value being the leading pawn
+	MAP_PAWNS [64]int
+
+	// index of the leading pawn group, by number of leading pawns and square of the first leading pawn
+	LEAD_PAWN_IDX [6][64]uint64
+
+	// size of the leading pawn group, by number of leading pawns and file of the first leading pawn
+	LEAD_PAWNS_SIZE [6][4]uint64
+)
+
+func init() {
+	// Pascal's rule
+	BINOMIAL[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < 6 && k <= n; k++ {
+			if k > 0 {
+				BINOMIAL[k][n] += BINOMIAL[k-1][n-1]
+			}
+			if k < n {
+				BINOMIAL[k][n] += BINOMIAL[k][n-1]
+			}
+		}
+	}
+
+	code := 0
+	for s := 0; s < 64; s++ {
+		if offDiagonal(s) < 0 {
+			MAP_B1H1H7[s] = code
+			code += 1
+		}
+	}
+
+	code = 0
+	for s := 0; s <= 27; s++ {
+		if offDiagonal(s) < 0 && getFile(s) <= 3 {
+			MAP_A1D1D4[s] = code
+			code += 1
+		}
+	}
+	for s := 0; s <= 27; s++ {
+		if offDiagonal(s) == 0 && getFile(s) <= 3 {
+			MAP_A1D1D4[s] = code // diagonal squares are encoded last
+			code += 1
+		}
+	}
+
+	initKingPairs()
+	initPawns()
+}
+
+func initKingPairs() {
+	type pair struct {
+		idx    int
+		square int
+	}
+
+	var bothOnDiagonal []pair
+	code := 0
+
+	for i := range MAP_KK {
+		for s := range MAP_KK[i] {
+			MAP_KK[i][s] = -1
+		}
+	}
+
+	for idx := 0; idx < 10; idx++ {
+		for s1 := 0; s1 <= 27; s1++ {
+			if MAP_A1D1D4[s1] != idx || (idx == 0 && s1 != 1) {
+				continue // squares outside the triangle are also 0, but only B1 is really mapped to it
+			}
+
+			for s2 := 0; s2 < 64; s2++ {
+				switch {
+				case isAdjacentOrEqual(s1, s2):
+					continue // illegal position
+				case offDiagonal(s1) == 0 && offDiagonal(s2) > 0:
+					continue // first on the diagonal, second above
+				case offDiagonal(s1) == 0 && offDiagonal(s2) == 0:
+					bothOnDiagonal = append(bothOnDiagonal, pair{idx, s2})
+				default:
+					MAP_KK[idx][s2] = code
+					code += 1
+				}
+			}
+		}
+	}
+
+	// legal positions with both kings on the diagonal are encoded last
+	for _, p := range bothOnDiagonal {
+		MAP_KK[p.idx][p.square] = code
+		code += 1
+	}
+}
+
+func initPawns() {
+	availableSquares := 47 // available squares when the leading pawn is on a2
+
+	for leadPawns := 1; leadPawns <= 5; leadPawns++ {
+		for file := 0; file <= 3; file++ {
+			// the index restarts at every file, because tables are split by file of the leading pawn
+			var idx uint64 = 0
+
+			for rank := 1; rank <= 6; rank++ {
+				s := 8*rank + file
+
+				// the leading pawn is the one nearest to the edge and, among those, the one with the lowest rank
+				if leadPawns == 1 {
+					MAP_PAWNS[s] = availableSquares
+					availableSquares -= 1
+					MAP_PAWNS[flipFile(s)] = availableSquares
+					availableSquares -= 1
+				}
+
+				LEAD_PAWN_IDX[leadPawns][s] = idx
+				idx += BINOMIAL[leadPawns-1][MAP_PAWNS[s]]
+			}
+
+			LEAD_PAWNS_SIZE[leadPawns][file] = idx
+		}
+	}
+}
+
+/**
+Squares are numbered from A1 (0) to H8 (63), rank by rank
+*/
+func getFile(s int) int {
+	return s & 7
+}
+
+func getRank(s int) int {
+	return s >> 3
+}
+
+func flipFile(s int) int {
+	return s ^ 7
+}
+
+func flipRank(s int) int {
+	return s ^ 56
+}
+
+func flipDiagonal(s int) int {
+	return ((s >> 3) | (s << 3)) & 63
+}
+
+/**
+Returns a negative number for squares below the a1-h8 diagonal, 0 on it and a positive number above it
+*/
+func offDiagonal(s int) int {
+	return getRank(s) - getFile(s)
+}
+
+func isAdjacentOrEqual(s1, s2 int) bool {
+	return abs(getFile(s1)-getFile(s2)) <= 1 && abs(getRank(s1)-getRank(s2)) <= 1
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,391 @@
+package syzygy
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"sort"
+	"strings"
+)
+
+// piece types in the order of material signatures, and their letters
+var SIGNATURE_PIECE_TYPES [6]b.PieceType = [6]b.PieceType{b.KING, b.QUEEN, b.ROOK, b.BISHOP, b.KNIGHT, b.PAWN}
+var SIGNATURE_LETTERS string = "KQRBNP"
+
+// table piece codes, by board piece type
+var TB_PIECE_TYPES map[b.PieceType]int = map[b.PieceType]int{
+	b.PAWN:   TB_PAWN,
+	b.KNIGHT: TB_KNIGHT,
+	b.BISHOP: TB_BISHOP,
+	b.ROOK:   TB_ROOK,
+	b.QUEEN:  TB_QUEEN,
+	b.KING:   TB_KING,
+}
+
+/**
+Returns the material signature of the position, white pieces first, such as KRPvKR
+*/
+func getSignature(board b.Board) string {
+	var sides [2]string
+
+	for i, c := range []b.Color{b.WHITE, b.BLACK} {
+		for j, pt := range SIGNATURE_PIECE_TYPES {
+			sides[i] += strings.Repeat(string(SIGNATURE_LETTERS[j]), board.GetNumOf(c, pt))
+		}
+	}
+
+	return sides[0] + "v" + sides[1]
+}
+
+/**
+Splits a signature into the pieces of white and black
+*/
+func splitSides(signature string) (string, string) {
+	i := strings.Index(signature, "v")
+	if i < 0 {
+		return signature, ""
+	}
+	return signature[:i], signature[i+1:]
+}
+
+func swapSides(signature string) string {
+	white, black := splitSides(signature)
+	return black + "v" + white
+}
+
+func countPieces(side string) map[rune]int {
+	counts := make(map[rune]int)
+	for _, r := range side {
+		counts[r] += 1
+	}
+	return counts
+}
+
+/**
+Returns true if the signature has a single king per side followed by pieces in signature order, such as KRPvKR
+*/
+func isValidSignature(signature string) bool {
+	if strings.Count(signature, "v") != 1 {
+		return false
+	}
+
+	white, black := splitSides(signature)
+	for _, side := range []string{white, black} {
+		if !strings.HasPrefix(side, "K") || strings.Count(side, "K") != 1 {
+			return false
+		}
+
+		last := 0
+		for _, r := range side {
+			i := strings.IndexRune(SIGNATURE_LETTERS, r)
+			if i < last {
+				return false
+			}
+			last = i
+		}
+	}
+
+	return true
+}
+
+func countAllPieces(board b.Board) int {
+	count := 0
+	for _, c := range []b.Color{b.WHITE, b.BLACK} {
+		for _, pt := range SIGNATURE_PIECE_TYPES {
+			count += board.GetNumOf(c, pt)
+		}
+	}
+	return count
+}
+
+func isPawnMove(board b.Board, move b.Move) bool {
+	piece, err := board.GetPieceAt(move.GetSrcSquare())
+	return err == nil && piece.GetPieceType() == b.PAWN
+}
+
+/**
+Returns true if the move captures a piece, including en-passent captures
+*/
+func isCapture(board b.Board, move b.Move) bool {
+	if _, err := board.GetPieceAt(move.GetDstSquare()); err == nil {
+		return true
+	}
+	return isPawnMove(board, move) && move.GetSrcSquare().GetFile() != move.GetDstSquare().GetFile()
+}
+
+/**
+Returns true if the move resets the fifty-move rule
+*/
+func isZeroing(board b.Board, move b.Move) bool {
+	return isCapture(board, move) || isPawnMove(board, move)
+}
+
+/**
+Returns the squares of the pieces of the given color and piece type, in ascending order
+*/
+func getSquares(board b.Board, c b.Color, pt b.PieceType) []int {
+	var squares []int
+	for _, square := range board.GetPieceBitmap(c, pt).GetSquares() {
+		squares = append(squares, square.GetIndex())
+	}
+	sort.Ints(squares)
+	return squares
+}
+
+/**
+Looks the position up in the table. For DTZ tables, returns true if the table only stores the other side to move
+*/
+func (t *table) probe(board b.Board, signature string, wdl WDL) (int, bool, error) {
+	d, idx, changeSides := t.getIndex(board, signature)
+	if changeSides {
+		return 0, true, nil
+	}
+
+	value, err := t.decompress(d, idx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if !t.isDTZ {
+		return value - 2, false, nil
+	}
+
+	return t.mapScore(d, value, wdl), false, nil
+}
+
+/**
+Returns the pairs data of the position and its index in them. The position is mirrored so that the stronger side is
+white, and then so that the leading piece is in the a1-d1-d4 triangle, or the leading pawn on files A to D, before being
+encoded as an index. For DTZ tables, returns true if the table only stores the other side to move, without an index
+*/
+func (t *table) getIndex(board b.Board, signature string) (*pairsData, uint64, bool) {
+	var squares [TB_PIECES]int
+	var pieces [TB_PIECES]int
+	var size, leadPawnsCount, tbFile int
+	var idx uint64
+
+	// tables of symmetric material only store white to move, and all tables store white as the stronger side
+	symmetricBlackToMove := t.key == t.key2 && board.GetTurn() == b.BLACK
+	blackStronger := signature != t.key
+
+	flip := symmetricBlackToMove || blackStronger
+	flipColor, flipSquares, stm := 0, 0, 0
+	if flip {
+		flipColor, flipSquares, stm = TB_BLACK, 56, 1
+	}
+	if board.GetTurn() == b.BLACK {
+		stm ^= 1
+	}
+
+	pawnsComp := func(s []int) func(i, j int) bool {
+		return func(i, j int) bool {
+			return MAP_PAWNS[s[i]] < MAP_PAWNS[s[j]]
+		}
+	}
+
+	var leadColor b.Color = b.WHITE
+	if t.hasPawns {
+		// pawns of the leading color come first in every table
+		if (t.get(0, 0).pieces[0]^flipColor)&TB_BLACK != 0 {
+			leadColor = b.BLACK
+		}
+
+		for _, s := range getSquares(board, leadColor, b.PAWN) {
+			squares[size] = s ^ flipSquares
+			size += 1
+		}
+		leadPawnsCount = size
+
+		// the leading pawn is the one nearest to the edge
+		best := 0
+		for i := 1; i < leadPawnsCount; i++ {
+			if MAP_PAWNS[squares[i]] > MAP_PAWNS[squares[best]] {
+				best = i
+			}
+		}
+		squares[0], squares[best] = squares[best], squares[0]
+
+		tbFile = getFile(squares[0])
+		if tbFile > 3 {
+			tbFile = 7 - tbFile
+		}
+	}
+
+	if t.isDTZ {
+		flags := t.get(stm, tbFile).flags
+		if int(flags&FLAG_STM) != stm && !(t.key == t.key2 && !t.hasPawns) {
+			return nil, 0, true
+		}
+	}
+
+	// the remaining pieces, by ascending square
+	var others [][2]int
+	for _, c := range []b.Color{b.WHITE, b.BLACK} {
+		for _, pt := range SIGNATURE_PIECE_TYPES {
+			if t.hasPawns && c == leadColor && pt == b.PAWN {
+				continue
+			}
+
+			code := TB_PIECE_TYPES[pt]
+			if c == b.BLACK {
+				code |= TB_BLACK
+			}
+
+			for _, s := range getSquares(board, c, pt) {
+				others = append(others, [2]int{s, code})
+			}
+		}
+	}
+	sort.Slice(others, func(i, j int) bool {
+		return others[i][0] < others[j][0]
+	})
+
+	for _, o := range others {
+		squares[size] = o[0] ^ flipSquares
+		pieces[size] = o[1] ^ flipColor
+		size += 1
+	}
+
+	d := t.get(stm, tbFile)
+
+	// reorder the pieces to the order they are encoded in
+	for i := leadPawnsCount; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	if getFile(squares[0]) > 3 {
+		for i := 0; i < size; i++ {
+			squares[i] = flipFile(squares[i])
+		}
+	}
+
+	if t.hasPawns {
+		idx = LEAD_PAWN_IDX[leadPawnsCount][squares[0]]
+
+		lead := squares[1:leadPawnsCount]
+		sort.SliceStable(lead, pawnsComp(lead))
+
+		for i := 1; i < leadPawnsCount; i++ {
+			idx += BINOMIAL[i][MAP_PAWNS[squares[i]]]
+		}
+	} else {
+		if getRank(squares[0]) > 3 {
+			for i := 0; i < size; i++ {
+				squares[i] = flipRank(squares[i])
+			}
+		}
+
+		// the first piece of the leading group off the diagonal must be below it
+		for i := 0; i < d.groupLen[0]; i++ {
+			if offDiagonal(squares[i]) == 0 {
+				continue
+			}
+
+			if offDiagonal(squares[i]) > 0 {
+				for j := i; j < size; j++ {
+					squares[j] = flipDiagonal(squares[j])
+				}
+			}
+			break
+		}
+
+		idx = encodeLeadingPieces(squares, t.hasUniquePieces)
+	}
+
+	// encode the remaining groups, mapping squares down past the squares of the previous groups
+	idx *= d.groupIdx[0]
+	start := d.groupLen[0]
+	remainingPawns := t.hasPawns && t.pawnCount[1] > 0
+
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[start : start+d.groupLen[next]]
+		sort.Ints(group)
+
+		var n uint64 = 0
+		for i, s := range group {
+			adjust := 0
+			for _, prev := range squares[:start] {
+				if s > prev {
+					adjust += 1
+				}
+			}
+
+			if remainingPawns {
+				adjust += 8
+			}
+
+			n += BINOMIAL[i+1][s-adjust]
+		}
+
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		start += d.groupLen[next]
+	}
+
+	return d, idx, false
+}
+
+/**
+Encodes the leading group of a pawnless table: its first 3 pieces if the table has unique pieces, or the two kings
+*/
+func encodeLeadingPieces(squares [TB_PIECES]int, hasUniquePieces bool) uint64 {
+	if !hasUniquePieces {
+		return uint64(MAP_KK[MAP_A1D1D4[squares[0]]][squares[1]])
+	}
+
+	adjust1, adjust2 := 0, 0
+	if squares[1] > squares[0] {
+		adjust1 += 1
+	}
+	if squares[2] > squares[0] {
+		adjust2 += 1
+	}
+	if squares[2] > squares[1] {
+		adjust2 += 1
+	}
+
+	switch {
+	case offDiagonal(squares[0]) != 0:
+		// first piece below the diagonal
+		return uint64((MAP_A1D1D4[squares[0]]*63+squares[1]-adjust1)*62 + squares[2] - adjust2)
+	case offDiagonal(squares[1]) != 0:
+		// first piece on the diagonal, second below
+		return uint64((6*63+getRank(squares[0])*28+MAP_B1H1H7[squares[1]])*62 + squares[2] - adjust2)
+	case offDiagonal(squares[2]) != 0:
+		// first two pieces on the diagonal, third below
+		return uint64(6*63*62 + 4*28*62 + getRank(squares[0])*7*28 + (getRank(squares[1])-adjust1)*28 + MAP_B1H1H7[squares[2]])
+	}
+
+	// all three pieces on the diagonal
+	return uint64(6*63*62 + 4*28*62 + 4*7*28 + getRank(squares[0])*7*6 + (getRank(squares[1])-adjust1)*6 + getRank(squares[2]) - adjust2)
+}
+
+/**
+Converts a stored DTZ value to plies, through the value map of the table if it has one
+*/
+func (t *table) mapScore(d *pairsData, value int, wdl WDL) int {
+	if d.flags&FLAG_MAPPED != 0 {
+		mapIdx := d.mapIdx[[5]int{1, 3, 0, 2, 0}[wdl+2]]
+		r := &cursor{file: t.file}
+
+		if d.flags&FLAG_WIDE != 0 {
+			r.offset = mapIdx + 2*int64(value)
+			value = int(r.u16())
+		} else {
+			r.offset = mapIdx + int64(value)
+			value = int(r.u8())
+		}
+	}
+
+	// tables store moves rather than plies when it doesn't change the result
+	if (wdl == WIN && d.flags&FLAG_WIN_PLIES == 0) || (wdl == LOSS && d.flags&FLAG_LOSS_PLIES == 0) ||
+		wdl == CURSED_WIN || wdl == BLESSED_LOSS {
+		value *= 2
+	}
+
+	return value + 1
+}
@@ -0,0 +1,410 @@
+package syzygy
+
+import (
+	"errors"
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// larger than any distance to zero, used to rank root moves
+const MAX_DTZ int = 1 << 18
+
+const (
+	WDL_SUFFIX string = ".rtbw"
+	DTZ_SUFFIX string = ".rtbz"
+)
+
+var ErrNotFound error = errors.New("no table for the position")
+
+/**
+The result of a position for the side to move. Cursed wins and blessed losses are wins and losses which are drawn by the
+fifty-move rule
+*/
+type WDL int
+
+const (
+	LOSS WDL = iota - 2
+	BLESSED_LOSS
+	DRAW
+	CURSED_WIN
+	WIN
+)
+
+func (wdl WDL) String() string {
+	switch wdl {
+	case LOSS:
+		return "Loss"
+	case BLESSED_LOSS:
+		return "Blessed Loss"
+	case DRAW:
+		return "Draw"
+	case CURSED_WIN:
+		return "Cursed Win"
+	case WIN:
+		return "Win"
+	}
+
+	panic(fmt.Sprintf("Unhandled switch case: %d", wdl))
+}
+
+/**
+A set of Syzygy tables, found in one or more directories
+*/
+type Tablebase struct {
+	wdl       map[string]*table
+	dtz       map[string]*table
+	maxPieces int
+}
+
+/**
+Finds the WDL (.rtbw) and DTZ (.rtbz) tables of the given directories. Tables are only read when first probed
+*/
+func Open(dirs ...string) (*Tablebase, error) {
+	tb := &Tablebase{make(map[string]*table), make(map[string]*table), 0}
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			ext := filepath.Ext(name)
+			signature := strings.TrimSuffix(name, ext)
+
+			if entry.IsDir() || (ext != WDL_SUFFIX && ext != DTZ_SUFFIX) || !isValidSignature(signature) {
+				continue
+			}
+
+			t := newTable(filepath.Join(dir, name), signature, ext == DTZ_SUFFIX)
+			if t.pieceCount > TB_PIECES {
+				continue
+			}
+
+			tables := tb.wdl
+			if t.isDTZ {
+				tables = tb.dtz
+			}
+			tables[t.key] = t
+			tables[t.key2] = t
+
+			if t.pieceCount > tb.maxPieces {
+				tb.maxPieces = t.pieceCount
+			}
+		}
+	}
+
+	return tb, nil
+}
+
+/**
+Returns the largest number of pieces, including kings, of the tables found
+*/
+func (tb *Tablebase) GetMaxPieces() int {
+	return tb.maxPieces
+}
+
+func (tb *Tablebase) GetNumTables() int {
+	tables := make(map[*table]bool)
+	for _, t := range tb.wdl {
+		tables[t] = true
+	}
+	for _, t := range tb.dtz {
+		tables[t] = true
+	}
+	return len(tables)
+}
+
+/**
+Returns true if the position has few enough pieces to be in the tables. Positions with castling rights never are
+*/
+func (tb *Tablebase) CanProbe(board b.Board) bool {
+	for _, c := range []b.Color{b.WHITE, b.BLACK} {
+		if board.CanCastle(c, true) || board.CanCastle(c, false) {
+			return false
+		}
+	}
+
+	return countAllPieces(board) <= tb.maxPieces
+}
+
+/**
+Returns the result of the position for the side to move, assuming both sides play perfectly
+*/
+func (tb *Tablebase) ProbeWDL(board b.Board) (WDL, error) {
+	wdl, _, err := tb.search(board, false)
+	return wdl, err
+}
+
+/**
+Returns the distance to zero of the position: the number of plies to the next capture or pawn move which keeps the
+result, positive when the side to move wins and negative when it loses. Cursed wins and blessed losses are offset by 100
+plies. Draws have a distance of 0
+*/
+func (tb *Tablebase) ProbeDTZ(board b.Board) (int, error) {
+	wdl, zeroing, err := tb.search(board, true)
+	if err != nil || wdl == DRAW {
+		return 0, err // dtz tables don't store draws
+	}
+
+	if zeroing {
+		// the best move is a capture or pawn move, so the table stores an arbitrary value
+		return getDTZBeforeZeroing(wdl), nil
+	}
+
+	dtz, changeSides, err := tb.probeTable(board, true, wdl)
+	if err != nil {
+		return 0, err
+	}
+
+	if !changeSides {
+		if wdl == CURSED_WIN || wdl == BLESSED_LOSS {
+			dtz += 100
+		}
+		return dtz * sign(int(wdl)), nil
+	}
+
+	// the table only stores the other side to move, so search one ply for the move which keeps the result the fastest
+	var minDTZ int = 0xFFFF
+
+	for _, move := range board.GetValidMoves() {
+		zeroing := isZeroing(board, move)
+
+		bCopy := board.Copy()
+		bCopy.Make(move)
+
+		if zeroing {
+			w, _, err := tb.search(bCopy, false)
+			if err != nil {
+				return 0, err
+			}
+			dtz = -getDTZBeforeZeroing(w)
+		} else {
+			if dtz, err = tb.ProbeDTZ(bCopy); err != nil {
+				return 0, err
+			}
+			dtz = -dtz
+		}
+
+		if dtz == 1 && bCopy.IsCheckmate() {
+			minDTZ = 1 // mating moves have the shortest distance
+		}
+
+		if !zeroing {
+			dtz += sign(dtz)
+		}
+
+		if dtz < minDTZ && sign(dtz) == sign(int(wdl)) {
+			minDTZ = dtz
+		}
+	}
+
+	if minDTZ == 0xFFFF {
+		return -1, nil // no valid moves, the side to move is mated
+	}
+
+	return minDTZ, nil
+}
+
+/**
+Returns the moves which keep the best result of the position, along with that result. Winning moves are those with the
+shortest distance to zero, so that playing them is guaranteed to win within the fifty-move rule when the position allows
+it. Losing moves are those delaying the loss the longest
+*/
+func (tb *Tablebase) FilterRootMoves(board b.Board, moves []b.Move) ([]b.Move, WDL, error) {
+	type rankedMove struct {
+		move b.Move
+		rank int
+	}
+
+	var ranked []rankedMove
+	var best WDL = LOSS
+
+	for _, move := range moves {
+		bCopy := board.Copy()
+		bCopy.Make(move)
+
+		var dtz int
+		if bCopy.GetHalfmoveClock() == 0 {
+			wdl, err := tb.ProbeWDL(bCopy)
+			if err != nil {
+				return nil, DRAW, err
+			}
+			dtz = getDTZBeforeZeroing(-wdl)
+		} else {
+			d, err := tb.ProbeDTZ(bCopy)
+			if err != nil {
+				return nil, DRAW, err
+			}
+			dtz = -d + sign(-d)
+		}
+
+		if dtz == 2 && bCopy.IsCheckmate() {
+			dtz = 1
+		}
+
+		wdl := getWDLFromDTZ(dtz, board.GetHalfmoveClock())
+		if wdl > best {
+			best = wdl
+		}
+
+		// shorter wins are better, and longer losses are better
+		rank := 0
+		switch {
+		case dtz > 0:
+			rank = MAX_DTZ - dtz
+		case dtz < 0:
+			rank = -MAX_DTZ - dtz
+		}
+
+		ranked = append(ranked, rankedMove{move, rank})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].rank > ranked[j].rank
+	})
+
+	var filtered []b.Move
+	for _, m := range ranked {
+		if m.rank == ranked[0].rank {
+			filtered = append(filtered, m.move)
+		}
+	}
+
+	return filtered, best, nil
+}
+
+/**
+Probes the position and its captures, since tables may store any value for positions where a capture is the best move.
+When checkZeroing is true, pawn moves are searched too, since DTZ tables don't store positions where they are the best
+move. Returns true if the best move is a capture or pawn move
+*/
+func (tb *Tablebase) search(board b.Board, checkZeroing bool) (WDL, bool, error) {
+	var bestValue WDL = LOSS
+	var moveCount int = 0
+
+	moves := board.GetValidMoves()
+	for _, move := range moves {
+		if !isCapture(board, move) && (!checkZeroing || !isPawnMove(board, move)) {
+			continue
+		}
+
+		moveCount += 1
+
+		bCopy := board.Copy()
+		bCopy.Make(move)
+
+		value, _, err := tb.search(bCopy, false)
+		if err != nil {
+			return DRAW, false, err
+		}
+		value = -value
+
+		if value > bestValue {
+			bestValue = value
+			if value >= WIN {
+				return value, true, nil
+			}
+		}
+	}
+
+	// if all moves were searched the tables aren't needed, which matters since they don't know about en-passent
+	noMoreMoves := moveCount > 0 && moveCount == len(moves)
+
+	var value WDL
+	if noMoreMoves {
+		value = bestValue
+	} else if len(moves) == 0 {
+		if board.IsCheck() {
+			return LOSS, false, nil
+		}
+		return DRAW, false, nil
+	} else {
+		v, _, err := tb.probeTable(board, false, DRAW)
+		if err != nil {
+			return DRAW, false, err
+		}
+		value = WDL(v)
+	}
+
+	if bestValue >= value {
+		return bestValue, bestValue > DRAW || noMoreMoves, nil
+	}
+
+	return value, false, nil
+}
+
+/**
+Probes the WDL or DTZ table of the position. For DTZ tables, returns true if the table only stores the other side to
+move
+*/
+func (tb *Tablebase) probeTable(board b.Board, isDTZ bool, wdl WDL) (int, bool, error) {
+	if countAllPieces(board) == 2 {
+		return int(DRAW), false, nil // only kings
+	}
+
+	tables := tb.wdl
+	if isDTZ {
+		tables = tb.dtz
+	}
+
+	signature := getSignature(board)
+	t, ok := tables[signature]
+	if !ok {
+		return 0, false, ErrNotFound
+	}
+
+	if err := t.init(); err != nil {
+		return 0, false, err
+	}
+
+	return t.probe(board, signature, wdl)
+}
+
+func getDTZBeforeZeroing(wdl WDL) int {
+	switch wdl {
+	case WIN:
+		return 1
+	case CURSED_WIN:
+		return 101
+	case BLESSED_LOSS:
+		return -101
+	case LOSS:
+		return -1
+	}
+
+	return 0
+}
+
+/**
+Returns the result of a distance to zero, given the number of plies already played towards the fifty-move rule
+*/
+func getWDLFromDTZ(dtz, halfmoveClock int) WDL {
+	switch {
+	case dtz > 0 && dtz+halfmoveClock <= 100:
+		return WIN
+	case dtz > 0:
+		return CURSED_WIN
+	case dtz < 0 && -dtz+halfmoveClock <= 100:
+		return LOSS
+	case dtz < 0:
+		return BLESSED_LOSS
+	}
+
+	return DRAW
+}
+
+func sign(x int) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,163 @@
+package syzygy
+
+import (
+	b "galapb/chess2022/pkg/board"
+	"testing"
+)
+
+func TestKingPairs(t *testing.T) {
+	seen := make(map[int]bool)
+	for i := range MAP_KK {
+		for _, code := range MAP_KK[i] {
+			if code < 0 {
+				continue
+			}
+			if seen[code] {
+				t.Fatalf("code %d is used twice", code)
+			}
+			seen[code] = true
+		}
+	}
+
+	if len(seen) != 462 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 462, len(seen))
+	}
+	for code := 0; code < 462; code++ {
+		if !seen[code] {
+			t.Fatalf("code %d is not used", code)
+		}
+	}
+}
+
+// every normalized placement of three unique pieces must get its own index below 31332
+func TestUniquePiecesEncoding(t *testing.T) {
+	seen := make(map[uint64]bool)
+
+	for s0 := 0; s0 < 64; s0++ {
+		if getFile(s0) > 3 || getRank(s0) > 3 || offDiagonal(s0) > 0 {
+			continue // the first piece is in the a1-d1-d4 triangle
+		}
+
+		for s1 := 0; s1 < 64; s1++ {
+			for s2 := 0; s2 < 64; s2++ {
+				if s1 == s0 || s2 == s0 || s2 == s1 {
+					continue
+				}
+
+				// the first piece off the diagonal is below it
+				normalized := true
+				for _, s := range []int{s0, s1, s2} {
+					if offDiagonal(s) != 0 {
+						normalized = offDiagonal(s) < 0
+						break
+					}
+				}
+				if !normalized {
+					continue
+				}
+
+				idx := encodeLeadingPieces([TB_PIECES]int{s0, s1, s2}, true)
+				if idx >= 31332 || seen[idx] {
+					t.Fatalf("invalid index %d for squares %d %d %d", idx, s0, s1, s2)
+				}
+				seen[idx] = true
+			}
+		}
+	}
+
+	if len(seen) != 31332 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 31332, len(seen))
+	}
+}
+
+func TestSignature(t *testing.T) {
+	board, err := b.FromFEN("8/8/4k3/8/2r5/8/3PK3/4R3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := getSignature(board); actual != "KRPvKR" {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "KRPvKR", actual)
+	}
+
+	if actual := swapSides("KRPvKR"); actual != "KRvKRP" {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "KRvKRP", actual)
+	}
+
+	for signature, expected := range map[string]bool{"KRPvKR": true, "KvK": true, "KPRvK": false, "KRvR": false, "KRK": false} {
+		if actual := isValidSignature(signature); actual != expected {
+			t.Fatalf("\nSignature: %s\nExpected: \n%t\nActual: \n%t", signature, expected, actual)
+		}
+	}
+}
+
+func TestProbeWithoutTables(t *testing.T) {
+	tb, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// bare kings are drawn without needing a table
+	board, _ := b.FromFEN("8/8/4k3/8/8/8/4K3/8 w - - 0 1")
+	if wdl, err := tb.ProbeWDL(board); err != nil || wdl != DRAW {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s (%v)", DRAW, wdl, err)
+	}
+
+	board, _ = b.FromFEN("8/8/4k3/8/8/8/4K3/7Q w - - 0 1")
+	if _, err := tb.ProbeWDL(board); err != ErrNotFound {
+		t.Fatalf("\nExpected: \n%v\nActual: \n%v", ErrNotFound, err)
+	}
+
+	if tb.CanProbe(board) {
+		t.Fatalf("positions can't be probed without tables")
+	}
+}
+
+/**
+Probes known positions in the KQvK and KRvK tables of testdata, written by TestWriteTestTables
+*/
+func TestProbeKnownPositions(t *testing.T) {
+	tb, err := Open("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tb.GetNumTables() != 2*len(TEST_TABLES) || tb.GetMaxPieces() != 3 {
+		t.Fatalf("\nExpected: \n%d tables of 3 pieces\nActual: \n%d tables of %d pieces", 2*len(TEST_TABLES), tb.GetNumTables(), tb.GetMaxPieces())
+	}
+
+	tests := []struct {
+		fen string
+		wdl WDL
+
+		// the exact distance to zero, or 0 to only check its sign
+		dtz int
+	}{
+		{"8/8/8/4k3/8/8/8/3QK3 w - - 0 1", WIN, 0},
+		{"8/8/8/4k3/8/8/8/3QK3 b - - 0 1", LOSS, 0},
+		{"k7/8/1K6/8/8/8/8/6Q1 w - - 0 1", WIN, 1}, // mate in one
+		{"8/8/8/8/8/8/3kQ3/7K b - - 0 1", DRAW, 0}, // the queen is lost
+		{"8/8/8/4k3/8/8/8/R3K3 w - - 0 1", WIN, 0},
+		{"k1K5/7R/8/8/8/8/8/8 b - - 0 1", DRAW, 0}, // stalemate
+	}
+
+	for _, test := range tests {
+		board, _ := b.FromFEN(test.fen)
+
+		wdl, err := tb.ProbeWDL(board)
+		if err != nil || wdl != test.wdl {
+			t.Fatalf("\nFEN: %s\nExpected: \n%s\nActual: \n%s (%v)", test.fen, test.wdl, wdl, err)
+		}
+
+		dtz, err := tb.ProbeDTZ(board)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case test.dtz != 0 && dtz != test.dtz,
+			test.wdl == WIN && dtz <= 0,
+			test.wdl == LOSS && dtz >= 0,
+			test.wdl == DRAW && dtz != 0:
+			t.Fatalf("\nFEN: %s\nExpected: \n%s with dtz %d\nActual: \n%d", test.fen, test.wdl, test.dtz, dtz)
+		}
+	}
+}
@@ -0,0 +1,549 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// first bytes of every table file
+var (
+	WDL_MAGIC [4]byte = [4]byte{0x71, 0xE8, 0x23, 0x5D}
+	DTZ_MAGIC [4]byte = [4]byte{0xD7, 0x66, 0x0C, 0xA5}
+)
+
+// flags of the pairs data of a table
+const (
+	FLAG_STM          byte = 1
+	FLAG_MAPPED       byte = 2
+	FLAG_WIN_PLIES    byte = 4
+	FLAG_LOSS_PLIES   byte = 8
+	FLAG_WIDE         byte = 16
+	FLAG_SINGLE_VALUE byte = 128
+)
+
+// piece codes used by the tables: white pieces are 1 to 6, black pieces are 9 to 14
+const (
+	TB_PAWN   int = 1
+	TB_KNIGHT int = 2
+	TB_BISHOP int = 3
+	TB_ROOK   int = 4
+	TB_QUEEN  int = 5
+	TB_KING   int = 6
+	TB_BLACK  int = 8
+)
+
+/**
+The compressed values of one side to move and one file of the leading pawn. Values are compressed by recursive pairing,
+and the resulting symbols are stored with a canonical Huffman code in blocks of fixed size
+*/
+type pairsData struct {
+	flags     byte
+	pieces    [TB_PIECES]int
+	groupLen  [TB_PIECES + 1]int
+	groupIdx  [TB_PIECES + 1]uint64
+	minSymLen int
+	maxSymLen int
+
+	blockSize       uint64
+	span            uint64
+	numBlocks       uint64
+	blockLengthSize uint64
+	sparseIndexSize uint64
+
+	lowestSym []uint64
+	base64    []uint64
+	symLen    []int
+	btree     []byte
+
+	// offsets in the table file
+	sparseIndexOffset int64
+	blockLengthOffset int64
+	dataOffset        int64
+
+	// offsets of the dtz value maps for wins, losses, cursed wins and blessed losses
+	mapIdx [4]int64
+}
+
+/**
+A WDL or DTZ table for one material signature, such as KRvK. Tables are read lazily, on their first probe
+*/
+type table struct {
+	path  string
+	isDTZ bool
+
+	// material signature with the stronger side as white, and with the colors swapped
+	key  string
+	key2 string
+
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool
+	pawnCount       [2]int
+
+	once  sync.Once
+	err   error
+	file  *os.File
+	items [2][4]*pairsData
+}
+
+func newTable(path, signature string, isDTZ bool) *table {
+	t := &table{path: path, isDTZ: isDTZ, key: signature, key2: swapSides(signature)}
+
+	white, black := splitSides(signature)
+	t.pieceCount = len(white) + len(black)
+
+	var counts [2]map[rune]int = [2]map[rune]int{countPieces(white), countPieces(black)}
+	t.hasPawns = counts[0]['P']+counts[1]['P'] > 0
+
+	for _, c := range counts {
+		for _, pt := range "QRBNP" {
+			if c[pt] == 1 {
+				t.hasUniquePieces = true
+			}
+		}
+	}
+
+	// the leading color is the side with fewer pawns, because this compresses better
+	if counts[1]['P'] == 0 || (counts[0]['P'] > 0 && counts[1]['P'] >= counts[0]['P']) {
+		t.pawnCount = [2]int{counts[0]['P'], counts[1]['P']}
+	} else {
+		t.pawnCount = [2]int{counts[1]['P'], counts[0]['P']}
+	}
+
+	return t
+}
+
+/**
+Returns the pairs data for the side to move and file of the leading pawn. DTZ tables only store one side
+*/
+func (t *table) get(stm, file int) *pairsData {
+	if t.isDTZ {
+		stm = 0
+	}
+	if !t.hasPawns {
+		file = 0
+	}
+	return t.items[stm][file]
+}
+
+/**
+Opens and reads the headers of the table, the first time it is needed
+*/
+func (t *table) init() error {
+	t.once.Do(func() {
+		t.err = t.read()
+		if t.err != nil && t.file != nil {
+			t.file.Close()
+		}
+	})
+
+	return t.err
+}
+
+func (t *table) read() error {
+	var err error
+	if t.file, err = os.Open(t.path); err != nil {
+		return err
+	}
+
+	var magic [4]byte
+	if _, err = t.file.ReadAt(magic[:], 0); err != nil {
+		return fmt.Errorf("failed to read table %s: %s", t.path, err)
+	}
+
+	if (t.isDTZ && magic != DTZ_MAGIC) || (!t.isDTZ && magic != WDL_MAGIC) {
+		return fmt.Errorf("corrupted table %s", t.path)
+	}
+
+	r := &cursor{file: t.file, offset: 4}
+
+	sides := 1
+	if !t.isDTZ && t.key != t.key2 {
+		sides = 2
+	}
+
+	maxFile := 0
+	if t.hasPawns {
+		maxFile = 3
+	}
+
+	// pawns on both sides
+	pp := t.hasPawns && t.pawnCount[1] > 0
+
+	r.u8() // flags, already known from the signature
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			t.items[i][f] = &pairsData{}
+		}
+
+		first := r.u8()
+		second := byte(0xFF)
+		if pp {
+			second = r.u8()
+		}
+
+		order := [2][2]int{{int(first & 0xF), int(second & 0xF)}, {int(first >> 4), int(second >> 4)}}
+
+		for k := 0; k < t.pieceCount; k++ {
+			piece := r.u8()
+			for i := 0; i < sides; i++ {
+				if i == 0 {
+					t.items[i][f].pieces[k] = int(piece & 0xF)
+				} else {
+					t.items[i][f].pieces[k] = int(piece >> 4)
+				}
+			}
+		}
+
+		for i := 0; i < sides; i++ {
+			t.setGroups(t.items[i][f], order[i], f)
+		}
+	}
+
+	r.align(2)
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			t.items[i][f].setSizes(r)
+		}
+	}
+
+	if t.isDTZ {
+		t.setDTZMap(r, maxFile)
+	}
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			t.items[i][f].sparseIndexOffset = r.offset
+			r.offset += int64(t.items[i][f].sparseIndexSize) * 6
+		}
+	}
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			t.items[i][f].blockLengthOffset = r.offset
+			r.offset += int64(t.items[i][f].blockLengthSize) * 2
+		}
+	}
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			r.align(64)
+			t.items[i][f].dataOffset = r.offset
+			r.offset += int64(t.items[i][f].numBlocks * t.items[i][f].blockSize)
+		}
+	}
+
+	return r.err
+}
+
+/**
+Splits the pieces into the groups they are encoded by. The first group is made of the leading pawns, or of the first
+2 or 3 pieces, and the following groups of identical pieces. Groups are encoded in the given order
+*/
+func (t *table) setGroups(d *pairsData, order [2]int, file int) {
+	n := 0
+	firstLen := 2
+	if t.hasPawns {
+		firstLen = 0
+	} else if t.hasUniquePieces {
+		firstLen = 3
+	}
+
+	d.groupLen[n] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen -= 1
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n] += 1
+		} else {
+			n += 1
+			d.groupLen[n] = 1
+		}
+	}
+	n += 1
+	d.groupLen[n] = 0
+
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next := 1
+	freeSquares := 64 - d.groupLen[0]
+	if pp {
+		next = 2
+		freeSquares -= d.groupLen[1]
+	}
+
+	var idx uint64 = 1
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		switch {
+		case k == order[0]:
+			// leading pawns or pieces
+			d.groupIdx[0] = idx
+			switch {
+			case t.hasPawns:
+				idx *= LEAD_PAWNS_SIZE[d.groupLen[0]][file]
+			case t.hasUniquePieces:
+				idx *= 31332
+			default:
+				idx *= 462
+			}
+		case k == order[1]:
+			// remaining pawns
+			d.groupIdx[1] = idx
+			idx *= BINOMIAL[d.groupLen[1]][48-d.groupLen[0]]
+		default:
+			// remaining pieces
+			d.groupIdx[next] = idx
+			idx *= BINOMIAL[d.groupLen[next]][freeSquares]
+			freeSquares -= d.groupLen[next]
+			next += 1
+		}
+	}
+
+	d.groupIdx[n] = idx
+}
+
+/**
+Reads the sizes of the compressed data and the canonical Huffman code of its symbols
+*/
+func (d *pairsData) setSizes(r *cursor) {
+	d.flags = r.u8()
+
+	if d.flags&FLAG_SINGLE_VALUE != 0 {
+		d.minSymLen = int(r.u8()) // the single value of the table
+		return
+	}
+
+	// the size of the table is the index of the last group
+	n := 0
+	for d.groupLen[n] != 0 {
+		n += 1
+	}
+	tbSize := d.groupIdx[n]
+
+	d.blockSize = 1 << r.u8()
+	d.span = 1 << r.u8()
+	d.sparseIndexSize = (tbSize + d.span - 1) / d.span
+	padding := uint64(r.u8())
+	d.numBlocks = uint64(r.u32())
+	d.blockLengthSize = d.numBlocks + padding
+	d.maxSymLen = int(r.u8())
+	d.minSymLen = int(r.u8())
+
+	// longer codes have lower values, so the lowest code of each length is found by halving the one of the next length
+	numLengths := d.maxSymLen - d.minSymLen + 1
+	d.lowestSym = make([]uint64, numLengths)
+	for i := range d.lowestSym {
+		d.lowestSym[i] = uint64(r.u16())
+	}
+
+	d.base64 = make([]uint64, numLengths)
+	for i := numLengths - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + d.lowestSym[i] - d.lowestSym[i+1]) / 2
+	}
+
+	// left-align the codes to 64 bits
+	for i := range d.base64 {
+		d.base64[i] <<= uint(64 - i - d.minSymLen)
+	}
+
+	numSyms := int(r.u16())
+	d.btree = r.bytes(3 * numSyms)
+	if numSyms&1 != 0 {
+		r.offset += 1
+	}
+
+	d.symLen = make([]int, numSyms)
+	visited := make([]bool, numSyms)
+	for sym := 0; sym < numSyms; sym++ {
+		if !visited[sym] {
+			d.symLen[sym] = d.setSymLen(sym, visited)
+		}
+	}
+}
+
+/**
+Returns the number of values, minus one, that a symbol expands to
+*/
+func (d *pairsData) setSymLen(sym int, visited []bool) int {
+	visited[sym] = true
+
+	left, right := d.getChildren(sym)
+	if right == 0xFFF {
+		return 0
+	}
+
+	if !visited[left] {
+		d.symLen[left] = d.setSymLen(left, visited)
+	}
+
+	if !visited[right] {
+		d.symLen[right] = d.setSymLen(right, visited)
+	}
+
+	return d.symLen[left] + d.symLen[right] + 1
+}
+
+/**
+Returns the pair of symbols that a symbol expands to. For leaf symbols, the left child is the stored value
+*/
+func (d *pairsData) getChildren(sym int) (int, int) {
+	lr := d.btree[3*sym : 3*sym+3]
+	return int(lr[1]&0xF)<<8 | int(lr[0]), int(lr[2])<<4 | int(lr[1]>>4)
+}
+
+/**
+Reads the maps from the stored values of DTZ tables to their real values, which are stored by decreasing frequency
+*/
+func (t *table) setDTZMap(r *cursor, maxFile int) {
+	for f := 0; f <= maxFile; f++ {
+		d := t.items[0][f]
+		if d.flags&FLAG_MAPPED == 0 {
+			continue
+		}
+
+		if d.flags&FLAG_WIDE != 0 {
+			r.align(2)
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = r.offset + 2 // skip the length of the map
+				r.offset += 2 * int64(r.u16())
+			}
+		} else {
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = r.offset + 1 // skip the length of the map
+				r.offset += int64(r.u8())
+			}
+		}
+	}
+
+	r.align(2)
+}
+
+/**
+Returns the value stored at the given index
+*/
+func (t *table) decompress(d *pairsData, idx uint64) (int, error) {
+	if d.flags&FLAG_SINGLE_VALUE != 0 {
+		return d.minSymLen, nil
+	}
+
+	r := &cursor{file: t.file}
+
+	// find the block holding the value, starting from the nearest entry of the sparse index
+	k := idx / d.span
+	r.offset = d.sparseIndexOffset + int64(k)*6
+	block := int64(r.u32())
+	offset := int(r.u16())
+
+	offset += int(idx%d.span) - int(d.span/2)
+
+	for offset < 0 {
+		block -= 1
+		offset += t.getBlockLength(d, block) + 1
+	}
+
+	for offset > t.getBlockLength(d, block) {
+		offset -= t.getBlockLength(d, block) + 1
+		block += 1
+	}
+
+	// decode the symbols of the block until the one holding the value
+	r.offset = d.dataOffset + block*int64(d.blockSize)
+	data := r.bytes(int(d.blockSize) + 8)
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	buf64 := binary.BigEndian.Uint64(data)
+	pos := 8
+	buf64Size := 64
+	var sym int
+
+	for {
+		length := 0
+		for buf64 < d.base64[length] {
+			length += 1
+		}
+
+		sym = int((buf64-d.base64[length])>>uint(64-length-d.minSymLen)) + int(d.lowestSym[length])
+
+		if offset < d.symLen[sym]+1 {
+			break
+		}
+
+		offset -= d.symLen[sym] + 1
+		length += d.minSymLen
+		buf64 <<= uint(length)
+		buf64Size -= length
+
+		if buf64Size <= 32 {
+			if pos+4 > len(data) {
+				return 0, fmt.Errorf("corrupted block %d in table %s", block, t.path)
+			}
+			buf64Size += 32
+			buf64 |= uint64(binary.BigEndian.Uint32(data[pos:])) << uint(64-buf64Size)
+			pos += 4
+		}
+	}
+
+	// expand the symbol into its pairs until reaching the value
+	for d.symLen[sym] != 0 {
+		left, right := d.getChildren(sym)
+		if offset < d.symLen[left]+1 {
+			sym = left
+		} else {
+			offset -= d.symLen[left] + 1
+			sym = right
+		}
+	}
+
+	left, _ := d.getChildren(sym)
+	return left, nil
+}
+
+func (t *table) getBlockLength(d *pairsData, block int64) int {
+	r := &cursor{file: t.file, offset: d.blockLengthOffset + 2*block}
+	return int(r.u16())
+}
+
+/**
+Reads little-endian values from a file, remembering the first error
+*/
+type cursor struct {
+	file   io.ReaderAt
+	offset int64
+	err    error
+}
+
+func (c *cursor) bytes(n int) []byte {
+	buf := make([]byte, n)
+	if c.err != nil {
+		return buf
+	}
+
+	read, err := c.file.ReadAt(buf, c.offset)
+	if err != nil && !(err == io.EOF && read > 0) {
+		c.err = err // the last block may be shorter than a full block, the rest is left as zeros
+	}
+
+	c.offset += int64(n)
+	return buf
+}
+
+func (c *cursor) u8() byte {
+	return c.bytes(1)[0]
+}
+
+func (c *cursor) u16() uint16 {
+	return binary.LittleEndian.Uint16(c.bytes(2))
+}
+
+func (c *cursor) u32() uint32 {
+	return binary.LittleEndian.Uint32(c.bytes(4))
+}
+
+func (c *cursor) align(n int64) {
+	c.offset = (c.offset + n - 1) / n * n
+}
@@ -0,0 +1,566 @@
+package syzygy
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"io"
+	"sort"
+	"strings"
+)
+
+// flags of the header of a table file
+const (
+	FLAG_SPLIT     byte = 1
+	FLAG_HAS_PAWNS byte = 2
+)
+
+// layout of the compressed values written: bytes per block and values per entry of the sparse index, as powers of 2
+const (
+	WRITE_BLOCK_SIZE_LOG int = 6
+	WRITE_SPAN_LOG       int = 10
+
+	// values of a block, and symbols made by pairing, at most
+	WRITE_MAX_BLOCK_VALUES int = 1 << 15
+	WRITE_MAX_SYMBOLS      int = 4000
+
+	// pairs of symbols are replaced by a new symbol while they occur at least this often
+	WRITE_MIN_PAIR_COUNT int = 8
+)
+
+var ErrUnsupportedTable error = errors.New("only pawnless tables of 3 pieces can be written")
+
+/**
+Returns the result of a legal position for the side to move and its distance to zero in plies, positive when the side
+to move wins and negative when it loses
+*/
+type PositionSolver func(board b.Board) (WDL, int)
+
+/**
+Writes the WDL or DTZ table of the signature, with the results given by the solver for every legal position, in the
+format of the Syzygy tables. Only pawnless tables of 3 pieces can be written, such as KQvK and KRvK, whose 2 sides to
+move are enumerated with boards. DTZ tables store white to move, in plies, and positions whose results are drawn by the
+fifty-move rule can't be written
+*/
+func WriteTable(w io.Writer, signature string, isDTZ bool, solver PositionSolver) error {
+	if !isValidSignature(signature) {
+		return fmt.Errorf("invalid signature %s", signature)
+	}
+
+	t := newTable("", signature, isDTZ)
+	if t.hasPawns || t.pieceCount != 3 {
+		return ErrUnsupportedTable
+	}
+
+	// the white pieces in signature order, then the black pieces
+	var pieces [TB_PIECES]int
+	white, black := splitSides(signature)
+	for i, r := range white + black {
+		pieces[i] = TB_PIECE_TYPES[SIGNATURE_PIECE_TYPES[strings.IndexRune(SIGNATURE_LETTERS, r)]]
+		if i >= len(white) {
+			pieces[i] |= TB_BLACK
+		}
+	}
+
+	sides := 2
+	if isDTZ || t.key == t.key2 {
+		sides = 1
+	}
+
+	values := make([][]int, sides)
+	for i := 0; i < sides; i++ {
+		d := &pairsData{pieces: pieces}
+		t.setGroups(d, [2]int{0, 0xF}, 0)
+		if isDTZ {
+			d.flags = FLAG_WIN_PLIES | FLAG_LOSS_PLIES
+		}
+		t.items[i][0] = d
+
+		values[i] = make([]int, d.groupIdx[1])
+		for idx := range values[i] {
+			values[i][idx] = -1
+		}
+	}
+
+	err := forEachPosition(white, black, func(board b.Board) error {
+		d, idx, changeSides := t.getIndex(board, signature)
+		if changeSides {
+			return nil
+		}
+
+		side := 0
+		if d != t.items[0][0] {
+			side = 1
+		}
+
+		wdl, dtz := solver(board)
+		value := int(wdl) + 2
+		if isDTZ {
+			switch wdl {
+			case DRAW:
+				return nil // dtz tables don't store draws
+			case CURSED_WIN, BLESSED_LOSS:
+				return fmt.Errorf("the result of %s is drawn by the fifty-move rule", board.FEN())
+			}
+			value = sign(dtz)*dtz - 1
+		}
+
+		if v := values[side][idx]; v >= 0 && v != value {
+			return fmt.Errorf("positions of index %d have the values %d and %d", idx, v, value)
+		}
+		values[side][idx] = value
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var compressed []*compressedValues
+	for i := 0; i < sides; i++ {
+		compressed = append(compressed, compress(values[i]))
+	}
+
+	return writeTable(w, t, compressed)
+}
+
+/**
+Calls the function with every legal position of the white and black pieces, with both sides to move
+*/
+func forEachPosition(white, black string, f func(board b.Board) error) error {
+	letters := white + strings.ToLower(black)
+	squares := make([]int, len(letters))
+
+	var place func(i int) error
+	place = func(i int) error {
+		if i == len(letters) {
+			return forEachSideToMove(letters, squares, f)
+		}
+
+		for s := 0; s < 64; s++ {
+			occupied := false
+			for _, prev := range squares[:i] {
+				occupied = occupied || prev == s
+			}
+			if occupied {
+				continue
+			}
+
+			squares[i] = s
+			if err := place(i + 1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return place(0)
+}
+
+func forEachSideToMove(letters string, squares []int, f func(board b.Board) error) error {
+	var rows [8][8]byte
+	for i, s := range squares {
+		rows[7-getRank(s)][getFile(s)] = letters[i]
+	}
+
+	var fen strings.Builder
+	for r, row := range rows {
+		empty := 0
+		for _, c := range row {
+			if c == 0 {
+				empty += 1
+				continue
+			}
+			if empty > 0 {
+				fen.WriteString(fmt.Sprint(empty))
+				empty = 0
+			}
+			fen.WriteByte(c)
+		}
+		if empty > 0 {
+			fen.WriteString(fmt.Sprint(empty))
+		}
+		if r < 7 {
+			fen.WriteByte('/')
+		}
+	}
+
+	var boards [2]b.Board
+	for i, turn := range []string{"w", "b"} {
+		board, err := b.FromFEN(fen.String() + " " + turn + " - - 0 1")
+		if err != nil {
+			return err
+		}
+		boards[i] = board
+	}
+
+	// the side to move can't capture the other king
+	for i := range boards {
+		if !boards[1-i].IsCheck() {
+			if err := f(boards[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/**
+The values of one side to move of a table, compressed by pairing and a canonical Huffman code
+*/
+type compressedValues struct {
+	// the value of every position, when they are all the same
+	single bool
+	value  int
+
+	minSymLen int
+	maxSymLen int
+	lowestSym []int
+
+	// children of the pair symbols, or the value of the leaf symbols and 0xFFF
+	btree [][2]int
+
+	sparseIndex  [][2]int
+	blockLengths []int
+	blocks       [][]byte
+}
+
+/**
+Compresses the values, where the values of illegal positions are negative and can take any value. They take the value
+of the previous position, which makes longer runs of the same value for pairing
+*/
+func compress(values []int) *compressedValues {
+	first := 0
+	for _, v := range values {
+		if v >= 0 {
+			first = v
+			break
+		}
+	}
+
+	sequence := make([]int, len(values))
+	distinct := make(map[int]bool)
+	previous := first
+	for i, v := range values {
+		if v < 0 {
+			v = previous
+		}
+		sequence[i] = v
+		distinct[v] = true
+		previous = v
+	}
+
+	if len(distinct) == 1 {
+		return &compressedValues{single: true, value: first}
+	}
+
+	// symbols are first the values, then pairs of symbols, until no pair is frequent enough
+	var symbols [][2]int
+	symbolOf := make(map[int]int)
+	for v := 0; v <= 0xFF; v++ {
+		if distinct[v] {
+			symbolOf[v] = len(symbols)
+			symbols = append(symbols, [2]int{v, 0xFFF})
+		}
+	}
+	for i, v := range sequence {
+		sequence[i] = symbolOf[v]
+	}
+
+	expansion := make([]int, len(symbols))
+	for i := range expansion {
+		expansion[i] = 1
+	}
+
+	for len(symbols) < WRITE_MAX_SYMBOLS {
+		counts := make(map[[2]int]int)
+		for i := 0; i+1 < len(sequence); i++ {
+			counts[[2]int{sequence[i], sequence[i+1]}] += 1
+		}
+
+		var best [2]int
+		bestCount := 0
+		for pair, count := range counts {
+			if count > bestCount || (count == bestCount && (pair[0] < best[0] || pair[0] == best[0] && pair[1] < best[1])) {
+				best, bestCount = pair, count
+			}
+		}
+		if bestCount < WRITE_MIN_PAIR_COUNT || expansion[best[0]]+expansion[best[1]] > WRITE_MAX_BLOCK_VALUES/64 {
+			break
+		}
+
+		sym := len(symbols)
+		symbols = append(symbols, best)
+		expansion = append(expansion, expansion[best[0]]+expansion[best[1]])
+
+		var next []int
+		for i := 0; i < len(sequence); i++ {
+			if i+1 < len(sequence) && sequence[i] == best[0] && sequence[i+1] == best[1] {
+				next = append(next, sym)
+				i += 1
+			} else {
+				next = append(next, sequence[i])
+			}
+		}
+		sequence = next
+	}
+
+	frequencies := make([]int, len(symbols))
+	for _, sym := range sequence {
+		frequencies[sym] += 1
+	}
+	lengths := getCodeLengths(frequencies)
+
+	c := &compressedValues{minSymLen: 64}
+	for _, length := range lengths {
+		if length > 0 && length < c.minSymLen {
+			c.minSymLen = length
+		}
+		if length > c.maxSymLen {
+			c.maxSymLen = length
+		}
+	}
+
+	// canonical code: symbols with longer codes are numbered first and have lower codes, and symbols without codes last
+	order := make([]int, len(symbols))
+	for i := range order {
+		order[i] = i
+	}
+	rank := func(sym int) int {
+		if lengths[sym] == 0 {
+			return 0
+		}
+		return 64 - lengths[sym] + 1
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return rank(order[i]) > 0 && (rank(order[j]) == 0 || rank(order[i]) < rank(order[j]))
+	})
+
+	number := make([]int, len(symbols))
+	for n, sym := range order {
+		number[sym] = n
+	}
+
+	numLengths := c.maxSymLen - c.minSymLen + 1
+	counts := make([]int, numLengths)
+	for _, length := range lengths {
+		if length > 0 {
+			counts[length-c.minSymLen] += 1
+		}
+	}
+
+	c.lowestSym = make([]int, numLengths)
+	base := make([]uint64, numLengths)
+	for i := numLengths - 2; i >= 0; i-- {
+		c.lowestSym[i] = c.lowestSym[i+1] + counts[i+1]
+		base[i] = (base[i+1] + uint64(counts[i+1])) / 2
+	}
+
+	codes := make([]uint64, len(symbols))
+	for sym, length := range lengths {
+		if length > 0 {
+			i := length - c.minSymLen
+			codes[sym] = base[i] + uint64(number[sym]-c.lowestSym[i])
+		}
+	}
+
+	c.btree = make([][2]int, len(symbols))
+	for sym, children := range symbols {
+		if children[1] == 0xFFF {
+			c.btree[number[sym]] = children
+		} else {
+			c.btree[number[sym]] = [2]int{number[children[0]], number[children[1]]}
+		}
+	}
+
+	// symbols are packed into blocks, starting at the most significant bit, and never span two blocks
+	blockBits := 8 << WRITE_BLOCK_SIZE_LOG
+	var starts []int
+	var block []byte
+	bits, blockValues, position := blockBits, 0, 0
+	for _, sym := range sequence {
+		length := lengths[sym]
+		if bits+length > blockBits || blockValues+expansion[sym] > WRITE_MAX_BLOCK_VALUES {
+			if block != nil {
+				c.blocks = append(c.blocks, block)
+				c.blockLengths = append(c.blockLengths, blockValues-1)
+			}
+			block = make([]byte, 1<<WRITE_BLOCK_SIZE_LOG)
+			starts = append(starts, position)
+			bits, blockValues = 0, 0
+		}
+
+		for k := length - 1; k >= 0; k-- {
+			if codes[sym]>>uint(k)&1 != 0 {
+				block[bits/8] |= 0x80 >> uint(bits%8)
+			}
+			bits += 1
+		}
+		blockValues += expansion[sym]
+		position += expansion[sym]
+	}
+	c.blocks = append(c.blocks, block)
+	c.blockLengths = append(c.blockLengths, blockValues-1)
+
+	// every entry points to the value in the middle of its span, past the last block for the last entry
+	span := 1 << WRITE_SPAN_LOG
+	for k := 0; k*span < len(values); k++ {
+		m := k*span + span/2
+		blockIdx := sort.Search(len(starts), func(i int) bool { return starts[i] > m }) - 1
+		c.sparseIndex = append(c.sparseIndex, [2]int{blockIdx, m - starts[blockIdx]})
+	}
+
+	return c
+}
+
+/**
+Returns the lengths of the Huffman codes of the symbols, or 0 for symbols which don't occur. A single symbol has a code
+of length 1
+*/
+func getCodeLengths(frequencies []int) []int {
+	lengths := make([]int, len(frequencies))
+
+	h := &nodeHeap{}
+	var parents []int
+	for _, frequency := range frequencies {
+		if frequency > 0 {
+			heap.Push(h, huffmanNode{frequency, len(parents)})
+			parents = append(parents, -1)
+		}
+	}
+	leaves := len(parents)
+
+	for h.Len() > 1 {
+		n1, n2 := heap.Pop(h).(huffmanNode), heap.Pop(h).(huffmanNode)
+		parents[n1.id], parents[n2.id] = len(parents), len(parents)
+		heap.Push(h, huffmanNode{n1.frequency + n2.frequency, len(parents)})
+		parents = append(parents, -1)
+	}
+
+	leaf := 0
+	for sym, frequency := range frequencies {
+		if frequency == 0 {
+			continue
+		}
+		for n := leaf; parents[n] >= 0; n = parents[n] {
+			lengths[sym] += 1
+		}
+		if leaves == 1 {
+			lengths[sym] = 1
+		}
+		leaf += 1
+	}
+
+	return lengths
+}
+
+type huffmanNode struct {
+	frequency int
+	id        int
+}
+
+type nodeHeap []huffmanNode
+
+func (h nodeHeap) Len() int { return len(h) }
+func (h nodeHeap) Less(i, j int) bool {
+	return h[i].frequency < h[j].frequency || h[i].frequency == h[j].frequency && h[i].id < h[j].id
+}
+func (h nodeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *nodeHeap) Push(x interface{}) { *h = append(*h, x.(huffmanNode)) }
+func (h *nodeHeap) Pop() interface{} {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+/**
+Writes the header of the table and the compressed values of its sides to move, in the order the reader expects them
+*/
+func writeTable(w io.Writer, t *table, compressed []*compressedValues) error {
+	var buf []byte
+	u16 := func(x int) {
+		buf = append(buf, 0, 0)
+		binary.LittleEndian.PutUint16(buf[len(buf)-2:], uint16(x))
+	}
+	u32 := func(x int) {
+		buf = append(buf, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(buf[len(buf)-4:], uint32(x))
+	}
+	align := func(n int) {
+		for len(buf)%n != 0 {
+			buf = append(buf, 0)
+		}
+	}
+
+	magic := WDL_MAGIC
+	if t.isDTZ {
+		magic = DTZ_MAGIC
+	}
+	buf = append(buf, magic[:]...)
+
+	var flags byte = 0
+	if t.key != t.key2 {
+		flags |= FLAG_SPLIT
+	}
+	buf = append(buf, flags)
+
+	// the leading group is encoded first for both sides to move, and the pieces are in the same order
+	buf = append(buf, 0)
+	for k := 0; k < t.pieceCount; k++ {
+		piece := t.items[0][0].pieces[k]
+		buf = append(buf, byte(piece|piece<<4))
+	}
+	align(2)
+
+	for i, c := range compressed {
+		flags := t.items[i][0].flags
+		if c.single {
+			buf = append(buf, flags|FLAG_SINGLE_VALUE, byte(c.value))
+			continue
+		}
+
+		buf = append(buf, flags, byte(WRITE_BLOCK_SIZE_LOG), byte(WRITE_SPAN_LOG), 0)
+		u32(len(c.blocks))
+		buf = append(buf, byte(c.maxSymLen), byte(c.minSymLen))
+		for _, sym := range c.lowestSym {
+			u16(sym)
+		}
+
+		u16(len(c.btree))
+		for _, children := range c.btree {
+			buf = append(buf, byte(children[0]), byte(children[0]>>8&0xF|children[1]<<4), byte(children[1]>>4))
+		}
+		if len(c.btree)&1 != 0 {
+			buf = append(buf, 0)
+		}
+	}
+
+	if t.isDTZ {
+		align(2) // no value maps, since values are stored in plies
+	}
+
+	for _, c := range compressed {
+		for _, entry := range c.sparseIndex {
+			u32(entry[0])
+			u16(entry[1])
+		}
+	}
+
+	for _, c := range compressed {
+		for _, length := range c.blockLengths {
+			u16(length)
+		}
+	}
+
+	for _, c := range compressed {
+		align(64)
+		for _, block := range c.blocks {
+			buf = append(buf, block...)
+		}
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
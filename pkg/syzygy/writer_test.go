@@ -0,0 +1,200 @@
+package syzygy
+
+import (
+	"bytes"
+	b "galapb/chess2022/pkg/board"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// the tables of testdata, written again by TestWriteTestTables
+var TEST_TABLES []string = []string{"KQvK", "KRvK"}
+
+/**
+Returns the placement of the pieces and the side to move of the board, which identify positions without pawns
+*/
+func getPositionKey(board b.Board) string {
+	fields := strings.Fields(board.FEN())
+	return fields[0] + " " + fields[1]
+}
+
+/**
+Solves every position of the signature by retrograde analysis on boards, slowly, and returns a solver for WriteTable.
+Captures leave bare kings, which are drawn
+*/
+func solve(t *testing.T, signature string) PositionSolver {
+	type position struct {
+		successors []string
+		mated      bool
+	}
+	positions := make(map[string]*position)
+
+	white, black := splitSides(signature)
+	err := forEachPosition(white, black, func(board b.Board) error {
+		p := &position{}
+		for _, move := range board.GetValidMoves() {
+			bCopy := board.Copy()
+			bCopy.Make(move)
+			if countAllPieces(bCopy) < countAllPieces(board) {
+				p.successors = append(p.successors, "")
+			} else {
+				p.successors = append(p.successors, getPositionKey(bCopy))
+			}
+		}
+		p.mated = len(p.successors) == 0 && board.IsCheck()
+		positions[getPositionKey(board)] = p
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// plies to mate of the positions solved so far, and whether the side to move is the one mated
+	dtm := make(map[string]int)
+	lost := make(map[string]bool)
+	for key, p := range positions {
+		if p.mated {
+			dtm[key], lost[key] = 0, true
+		}
+	}
+
+	for n := 1; ; n++ {
+		solved := make(map[string]int)
+		for key, p := range positions {
+			if _, ok := dtm[key]; ok || len(p.successors) == 0 {
+				continue
+			}
+
+			won, allWon, longest := false, true, 0
+			for _, s := range p.successors {
+				d, ok := dtm[s]
+				if ok && lost[s] && d == n-1 {
+					won = true
+				}
+				if !ok || lost[s] {
+					allWon = false
+				} else if d > longest {
+					longest = d
+				}
+			}
+
+			if won {
+				solved[key] = n
+			} else if allWon && longest == n-1 {
+				solved[key] = -n
+			}
+		}
+
+		if len(solved) == 0 {
+			break
+		}
+		for key, d := range solved {
+			if d < 0 {
+				dtm[key], lost[key] = -d, true
+			} else {
+				dtm[key] = d
+			}
+		}
+	}
+
+	return func(board b.Board) (WDL, int) {
+		key := getPositionKey(board)
+		d, ok := dtm[key]
+		switch {
+		case !ok:
+			return DRAW, 0
+		case lost[key] && d == 0:
+			return LOSS, -1
+		case lost[key]:
+			return LOSS, -d
+		}
+		return WIN, d
+	}
+}
+
+/**
+Writes the tables of testdata again when the SYZYGY_WRITE_TESTDATA environment variable is set, which takes minutes
+*/
+func TestWriteTestTables(t *testing.T) {
+	if os.Getenv("SYZYGY_WRITE_TESTDATA") == "" {
+		t.Skip("SYZYGY_WRITE_TESTDATA must be set to write the tables of testdata")
+	}
+
+	for _, signature := range TEST_TABLES {
+		solver := solve(t, signature)
+		for _, suffix := range []string{WDL_SUFFIX, DTZ_SUFFIX} {
+			var buf bytes.Buffer
+			if err := WriteTable(&buf, signature, suffix == DTZ_SUFFIX, solver); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join("testdata", signature+suffix), buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestWriteUnsupportedTables(t *testing.T) {
+	solver := func(board b.Board) (WDL, int) { return DRAW, 0 }
+	for _, signature := range []string{"KPvK", "KQvKR", "KvK"} {
+		if err := WriteTable(&bytes.Buffer{}, signature, false, solver); err != ErrUnsupportedTable {
+			t.Fatalf("\nExpected: \n%v\nActual: \n%v", ErrUnsupportedTable, err)
+		}
+	}
+}
+
+/**
+Writes varied values in tables of both kinds, and reads every one of them back
+*/
+func TestWriteValuesRoundTrip(t *testing.T) {
+	for _, isDTZ := range []bool{false, true} {
+		w := newTable("", "KQvK", isDTZ)
+		d := &pairsData{pieces: [TB_PIECES]int{TB_KING, TB_QUEEN, TB_KING | TB_BLACK}}
+		w.setGroups(d, [2]int{0, 0xF}, 0)
+		if isDTZ {
+			d.flags = FLAG_WIN_PLIES | FLAG_LOSS_PLIES
+		}
+		w.items[0][0], w.items[1][0] = d, d
+
+		values := make([]int, d.groupIdx[1])
+		for i := range values {
+			values[i] = (i / 7) % 5
+			if isDTZ {
+				values[i] = (i*i/13 + i/50) % 20
+			}
+		}
+
+		sides := []*compressedValues{compress(values), compress(values)}
+		if isDTZ {
+			sides = sides[:1]
+		}
+
+		path := filepath.Join(t.TempDir(), "KQvK")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = writeTable(f, w, sides)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := newTable(path, "KQvK", isDTZ)
+		if err := r.init(); err != nil {
+			t.Fatal(err)
+		}
+		defer r.file.Close()
+
+		for side := range sides {
+			for idx, expected := range values {
+				value, err := r.decompress(r.items[side][0], uint64(idx))
+				if err != nil || value != expected {
+					t.Fatalf("\nExpected: \n%d at index %d\nActual: \n%d (%v)", expected, idx, value, err)
+				}
+			}
+		}
+	}
+}
@@ -1,12 +1,18 @@
 package time_control
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type TimeControl interface {
 	GetHours() uint64
 	GetMinutes() uint64
 	GetSeconds() uint64
 	GetIncrement() uint64
+	GetDuration() time.Duration
+	GetIncrementDuration() time.Duration
+	IsUnlimited() bool
 }
 
 type timeControl struct {
@@ -60,6 +66,27 @@ func (tc *timeControl) GetIncrement() uint64 {
 	return tc.increment
 }
 
+/**
+Returns the time each player starts with
+*/
+func (tc *timeControl) GetDuration() time.Duration {
+	return time.Duration(tc.hours)*time.Hour + time.Duration(tc.minutes)*time.Minute + time.Duration(tc.seconds)*time.Second
+}
+
+/**
+Returns the time added to a player's clock after each of their moves, the increment being in seconds
+*/
+func (tc *timeControl) GetIncrementDuration() time.Duration {
+	return time.Duration(tc.increment) * time.Second
+}
+
+/**
+Returns true if the time control has no time at all, in which case clocks are not kept
+*/
+func (tc *timeControl) IsUnlimited() bool {
+	return tc.GetDuration() == 0
+}
+
 func (tc *timeControl) Build() TimeControl {
 	return tc
 }
@@ -0,0 +1,178 @@
+package time_control
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// time kept in reserve for communication with the game, per move
+	MOVE_OVERHEAD time.Duration = 30 * time.Millisecond
+
+	// smallest budget allocated to a move
+	MIN_MOVE_TIME time.Duration = 5 * time.Millisecond
+
+	// number of moves the remaining time is spread over in sudden death time controls
+	DEFAULT_MOVES_TO_GO int = 30
+
+	// the hard budget is at most this many times the soft budget...
+	HARD_LIMIT_FACTOR float64 = 5.0
+
+	// ...and at most this fraction of the remaining time
+	MAX_TIME_FRACTION float64 = 0.5
+
+	// an iteration is only started if it is likely to finish within the soft budget, the next iteration typically
+	// taking at least as long as all previous ones
+	ITERATION_START_FRACTION float64 = 0.5
+
+	// the soft budget grows by these factors when the best move changes between iterations, or when the score drops
+	BEST_MOVE_CHANGE_EXTENSION float64 = 1.3
+	FAIL_LOW_EXTENSION         float64 = 1.5
+)
+
+/**
+The state of a player's clock when they are asked for a move
+*/
+type Clock struct {
+	Remaining time.Duration
+	Increment time.Duration
+
+	// moves to play before the next time control, or 0 if the remaining time must last the rest of the game
+	MovesToGo int
+}
+
+/**
+Decides how long a search may think about a move. The soft limit is the time the search is expected to use, and may be
+extended when the search is unstable; the hard limit must never be exceeded
+*/
+type TimeManager struct {
+	start     time.Time
+	unlimited bool
+
+	softLimit time.Duration
+	hardLimit time.Duration
+	baseSoft  time.Duration
+
+	stopped int32
+}
+
+func NewTimeManager(clock Clock) *TimeManager {
+	movesToGo := clock.MovesToGo
+	if movesToGo <= 0 || movesToGo > DEFAULT_MOVES_TO_GO {
+		movesToGo = DEFAULT_MOVES_TO_GO
+	}
+
+	available := clock.Remaining - MOVE_OVERHEAD
+	if available < MIN_MOVE_TIME {
+		available = MIN_MOVE_TIME
+	}
+
+	soft := available/time.Duration(movesToGo) + clock.Increment*3/4
+
+	hard := time.Duration(float64(soft) * HARD_LIMIT_FACTOR)
+	if maxHard := time.Duration(float64(available) * MAX_TIME_FRACTION); hard > maxHard {
+		hard = maxHard
+	}
+
+	if movesToGo == 1 {
+		hard = available // last move before the time control, the whole remaining time can be used
+	}
+
+	if hard < MIN_MOVE_TIME {
+		hard = MIN_MOVE_TIME
+	}
+
+	if soft > hard {
+		soft = hard
+	}
+
+	return &TimeManager{
+		start:     time.Now(),
+		softLimit: soft,
+		hardLimit: hard,
+		baseSoft:  soft,
+	}
+}
+
+/**
+Returns a time manager which never runs out of time, and only stops when told to
+*/
+func NewUnlimitedTimeManager() *TimeManager {
+	return &TimeManager{start: time.Now(), unlimited: true}
+}
+
+/**
+Returns a time manager which uses exactly the given time
+*/
+func NewFixedTimeManager(moveTime time.Duration) *TimeManager {
+	return &TimeManager{start: time.Now(), softLimit: moveTime, hardLimit: moveTime, baseSoft: moveTime}
+}
+
+func (tm *TimeManager) IsUnlimited() bool {
+	return tm.unlimited
+}
+
+func (tm *TimeManager) GetSoftLimit() time.Duration {
+	return tm.softLimit
+}
+
+func (tm *TimeManager) GetHardLimit() time.Duration {
+	return tm.hardLimit
+}
+
+func (tm *TimeManager) Elapsed() time.Duration {
+	return time.Since(tm.start)
+}
+
+/**
+Stops the search as soon as possible, regardless of the remaining budget. Safe to call from any goroutine
+*/
+func (tm *TimeManager) Stop() {
+	atomic.StoreInt32(&tm.stopped, 1)
+}
+
+/**
+Returns true if the search must stop immediately, even in the middle of an iteration
+*/
+func (tm *TimeManager) ShouldStop() bool {
+	if atomic.LoadInt32(&tm.stopped) == 1 {
+		return true
+	}
+
+	return !tm.unlimited && tm.Elapsed() >= tm.hardLimit
+}
+
+/**
+Returns true if there is enough time left to start another iteration of iterative deepening
+*/
+func (tm *TimeManager) ShouldStartIteration() bool {
+	if tm.ShouldStop() {
+		return false
+	}
+
+	return tm.unlimited || tm.Elapsed() < time.Duration(float64(tm.softLimit)*ITERATION_START_FRACTION)
+}
+
+/**
+Updates the soft limit after an iteration of iterative deepening: an unstable best move or a dropping score earns the
+search more time, up to the hard limit, while a stable search returns to the original budget
+*/
+func (tm *TimeManager) OnIteration(bestMoveChanged, failedLow bool) {
+	if tm.unlimited {
+		return
+	}
+
+	soft := float64(tm.baseSoft)
+	if bestMoveChanged {
+		soft *= BEST_MOVE_CHANGE_EXTENSION
+	}
+
+	if failedLow {
+		soft *= FAIL_LOW_EXTENSION
+	}
+
+	tm.softLimit = time.Duration(soft)
+	if tm.softLimit > tm.hardLimit {
+		tm.softLimit = tm.hardLimit
+	}
+}
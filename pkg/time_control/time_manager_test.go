@@ -0,0 +1,45 @@
+package time_control
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeManagerBudgets(t *testing.T) {
+	tm := NewTimeManager(Clock{Remaining: 3 * time.Minute, Increment: 2 * time.Second})
+
+	if tm.GetSoftLimit() <= 0 || tm.GetSoftLimit() > tm.GetHardLimit() {
+		t.Fatalf("\nExpected: \n0 < soft <= hard\nActual: \nsoft %s, hard %s", tm.GetSoftLimit(), tm.GetHardLimit())
+	}
+
+	if tm.GetHardLimit() > 90*time.Second {
+		t.Fatalf("\nExpected: \n<= %s\nActual: \n%s", 90*time.Second, tm.GetHardLimit())
+	}
+}
+
+func TestTimeManagerExtendsOnInstability(t *testing.T) {
+	tm := NewTimeManager(Clock{Remaining: time.Minute})
+	base := tm.GetSoftLimit()
+
+	tm.OnIteration(true, true)
+	if tm.GetSoftLimit() <= base {
+		t.Fatalf("\nExpected: \n> %s\nActual: \n%s", base, tm.GetSoftLimit())
+	}
+
+	tm.OnIteration(false, false)
+	if tm.GetSoftLimit() != base {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", base, tm.GetSoftLimit())
+	}
+}
+
+func TestTimeManagerStop(t *testing.T) {
+	tm := NewUnlimitedTimeManager()
+	if tm.ShouldStop() {
+		t.Fatalf("\nExpected: \n%t\nActual: \n%t", false, true)
+	}
+
+	tm.Stop()
+	if !tm.ShouldStop() {
+		t.Fatalf("\nExpected: \n%t\nActual: \n%t", true, false)
+	}
+}
@@ -0,0 +1,105 @@
+package tuning
+
+import (
+	"bufio"
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type Position struct {
+	Board b.Board
+
+	// result of the game the position was taken from: 1 if white won, 0.5 if drawn, 0 if black won
+	Result float64
+}
+
+/**
+Reads labelled positions, one per line: a FEN (or its first 4 fields, as in EPD) followed by the game result. The result
+may be written as 1-0, 1/2-1/2 or 0-1, or as 1.0, 0.5 or 0.0, optionally wrapped in brackets or quotes, and optionally
+preceded by an EPD "c9" opcode. Empty lines and lines starting with # are skipped
+*/
+func LoadPositions(r io.Reader) ([]Position, error) {
+	var positions []Position = make([]Position, 0)
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber += 1
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		position, err := parsePosition(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNumber, err)
+		}
+
+		positions = append(positions, position)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return positions, nil
+}
+
+func LoadPositionsFile(path string) ([]Position, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadPositions(f)
+}
+
+func parsePosition(line string) (Position, error) {
+	fields := strings.Fields(strings.ReplaceAll(line, ";", " "))
+	if len(fields) < 5 {
+		return Position{}, fmt.Errorf("expected a fen and a result: %s", line)
+	}
+
+	result, err := ParseResult(fields[len(fields)-1])
+	if err != nil {
+		return Position{}, err
+	}
+
+	fenFields := fields[:len(fields)-1]
+	if fenFields[len(fenFields)-1] == "c9" {
+		fenFields = fenFields[:len(fenFields)-1]
+	}
+
+	board, err := b.FromFEN(strings.Join(fenFields, " "))
+	if err != nil {
+		return Position{}, err
+	}
+
+	return Position{board, result}, nil
+}
+
+func ParseResult(s string) (float64, error) {
+	s = strings.Trim(s, "[]\"")
+
+	switch s {
+	case "1-0":
+		return 1.0, nil
+	case "0-1":
+		return 0.0, nil
+	case "1/2-1/2":
+		return 0.5, nil
+	}
+
+	result, err := strconv.ParseFloat(s, 64)
+	if err != nil || (result != 0.0 && result != 0.5 && result != 1.0) {
+		return 0, fmt.Errorf("invalid game result: %s", s)
+	}
+
+	return result, nil
+}
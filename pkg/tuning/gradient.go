@@ -0,0 +1,130 @@
+package tuning
+
+import (
+	"math"
+	"sync"
+)
+
+// Adam optimizer constants
+const (
+	ADAM_BETA1   float64 = 0.9
+	ADAM_BETA2   float64 = 0.999
+	ADAM_EPSILON float64 = 1e-8
+)
+
+// a non-zero coefficient of a weight in the evaluation of a position
+type feature struct {
+	param int
+	value float64
+}
+
+/**
+Runs gradient descent on the weights, using the Adam optimizer with the given learning rate (in centipawns). The
+positional evaluation is linear in its weights, so each position is reduced once to the coefficient of every weight, and
+the gradient is computed exactly from those coefficients. The progress callback, if not nil, is called after every
+iteration with the iteration number and the current error. Returns the final error
+*/
+func (t *Tuner) GradientDescent(learningRate float64, iterations int, progress func(int, float64)) float64 {
+	features := t.extractFeatures()
+
+	var m []float64 = make([]float64, len(t.params))
+	var v []float64 = make([]float64, len(t.params))
+	var e float64
+
+	for iteration := 1; iteration <= iterations; iteration++ {
+		var gradient []float64
+		gradient, e = t.gradient(features)
+
+		for i, param := range t.params {
+			m[i] = ADAM_BETA1*m[i] + (1-ADAM_BETA1)*gradient[i]
+			v[i] = ADAM_BETA2*v[i] + (1-ADAM_BETA2)*gradient[i]*gradient[i]
+
+			mHat := m[i] / (1 - math.Pow(ADAM_BETA1, float64(iteration)))
+			vHat := v[i] / (1 - math.Pow(ADAM_BETA2, float64(iteration)))
+
+			*param -= learningRate * mHat / (math.Sqrt(vHat) + ADAM_EPSILON)
+		}
+
+		if progress != nil {
+			progress(iteration, e)
+		}
+	}
+
+	return t.Error()
+}
+
+/**
+Returns, for every position, the change in its evaluation (in centipawns) caused by raising each weight by one
+*/
+func (t *Tuner) extractFeatures() [][]feature {
+	var features [][]feature = make([][]feature, len(t.positions))
+	var base []float64 = make([]float64, len(t.positions))
+
+	for i, position := range t.positions {
+		base[i] = 100 * t.evaluator.Evaluate(position.Board)
+	}
+
+	for p, param := range t.params {
+		*param += 1
+
+		for i, position := range t.positions {
+			if value := 100*t.evaluator.Evaluate(position.Board) - base[i]; math.Abs(value) > 1e-9 {
+				features[i] = append(features[i], feature{p, value})
+			}
+		}
+
+		*param -= 1
+	}
+
+	return features
+}
+
+/**
+Returns the gradient of the mean squared error with respect to every weight, along with the error itself
+*/
+func (t *Tuner) gradient(features [][]feature) ([]float64, float64) {
+	var wg sync.WaitGroup
+	var gradients [][]float64 = make([][]float64, t.workers)
+	var errors []float64 = make([]float64, t.workers)
+
+	for w := 0; w < t.workers; w++ {
+		start, end := t.getChunk(w)
+
+		gradients[w] = make([]float64, len(t.params))
+
+		wg.Add(1)
+		go func(w, start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				var centipawns float64 = 0
+				for _, f := range features[i] {
+					centipawns += f.value * *t.params[f.param]
+				}
+
+				s := Sigmoid(t.k, centipawns)
+				diff := s - t.positions[i].Result
+				errors[w] += diff * diff
+
+				// derivative of the squared error with respect to the evaluation
+				dE := 2 * diff * s * (1 - s) * t.k * math.Ln10 / 400
+				for _, f := range features[i] {
+					gradients[w][f.param] += dE * f.value
+				}
+			}
+		}(w, start, end)
+	}
+	wg.Wait()
+
+	var gradient []float64 = make([]float64, len(t.params))
+	var e float64 = 0
+	n := float64(len(t.positions))
+
+	for w := 0; w < t.workers; w++ {
+		e += errors[w]
+		for p := range gradient {
+			gradient[p] += gradients[w][p] / n
+		}
+	}
+
+	return gradient, e / n
+}
@@ -0,0 +1,174 @@
+package tuning
+
+import (
+	"errors"
+	"galapb/chess2022/pkg/evaluation"
+	"math"
+	"sync"
+)
+
+// bounds of the search for the sigmoid scaling constant
+const (
+	MIN_K float64 = 0.0
+	MAX_K float64 = 10.0
+)
+
+/**
+Tunes evaluation weights by minimizing the mean squared error between the game results of labelled positions and the
+evaluation of those positions, mapped to an expected score by a sigmoid (Texel's tuning method)
+*/
+type Tuner struct {
+	positions []Position
+	weights   *evaluation.Weights
+	params    []*float64
+	evaluator evaluation.Evaluator
+	workers   int
+
+	// scales evaluations before the sigmoid, so that the sigmoid of an evaluation predicts the game result
+	k float64
+}
+
+/**
+Returns a tuner of the weights over the positions, split between at most the given number of workers
+*/
+func New(positions []Position, weights *evaluation.Weights, workers int) (*Tuner, error) {
+	if len(positions) == 0 {
+		return nil, errors.New("no positions to tune the weights on")
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(positions) {
+		workers = len(positions)
+	}
+
+	return &Tuner{
+		positions: positions,
+		weights:   weights,
+		params:    weights.Params(),
+		evaluator: evaluation.NewPositionalEvaluator(weights),
+		workers:   workers,
+		k:         1.0,
+	}, nil
+}
+
+func (t *Tuner) GetK() float64 {
+	return t.k
+}
+
+func (t *Tuner) SetK(k float64) {
+	t.k = k
+}
+
+/**
+Returns the expected score of white for an evaluation in centipawns
+*/
+func Sigmoid(k, centipawns float64) float64 {
+	return 1.0 / (1.0 + math.Pow(10, -k*centipawns/400))
+}
+
+/**
+Returns the mean squared error of the evaluator over all positions, using the current weights
+*/
+func (t *Tuner) Error() float64 {
+	var wg sync.WaitGroup
+	var sums []float64 = make([]float64, t.workers)
+
+	for w := 0; w < t.workers; w++ {
+		start, end := t.getChunk(w)
+
+		wg.Add(1)
+		go func(w, start, end int) {
+			defer wg.Done()
+			for _, position := range t.positions[start:end] {
+				centipawns := 100 * t.evaluator.Evaluate(position.Board)
+				sums[w] += math.Pow(position.Result-Sigmoid(t.k, centipawns), 2)
+			}
+		}(w, start, end)
+	}
+	wg.Wait()
+
+	var sum float64 = 0
+	for _, s := range sums {
+		sum += s
+	}
+
+	return sum / float64(len(t.positions))
+}
+
+/**
+Returns the range of the positions of a worker. The positions are split evenly, so that every worker has at least one
+*/
+func (t *Tuner) getChunk(w int) (int, int) {
+	return w * len(t.positions) / t.workers, (w + 1) * len(t.positions) / t.workers
+}
+
+/**
+Finds the sigmoid scaling constant which minimizes the error for the current weights, using a ternary search, and keeps it
+*/
+func (t *Tuner) FitK() float64 {
+	lo, hi := MIN_K, MAX_K
+
+	for hi-lo > 1e-4 {
+		m1 := lo + (hi-lo)/3
+		m2 := hi - (hi-lo)/3
+
+		t.k = m1
+		e1 := t.Error()
+		t.k = m2
+		e2 := t.Error()
+
+		if e1 < e2 {
+			hi = m2
+		} else {
+			lo = m1
+		}
+	}
+
+	t.k = (lo + hi) / 2
+	return t.k
+}
+
+/**
+Nudges each weight up and down by the step, keeping every change which lowers the error, until a full pass over the
+weights makes no improvement or the iteration limit is reached. The progress callback, if not nil, is called after every
+pass with the iteration number and the current error. Returns the final error
+*/
+func (t *Tuner) LocalSearch(step float64, maxIterations int, progress func(int, float64)) float64 {
+	bestError := t.Error()
+
+	for iteration := 1; iteration <= maxIterations; iteration++ {
+		improved := false
+
+		for _, param := range t.params {
+			original := *param
+
+			*param = original + step
+			if e := t.Error(); e < bestError {
+				bestError = e
+				improved = true
+				continue
+			}
+
+			*param = original - step
+			if e := t.Error(); e < bestError {
+				bestError = e
+				improved = true
+				continue
+			}
+
+			*param = original
+		}
+
+		if progress != nil {
+			progress(iteration, bestError)
+		}
+
+		if !improved {
+			break
+		}
+	}
+
+	return bestError
+}
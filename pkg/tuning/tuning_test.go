@@ -0,0 +1,70 @@
+package tuning
+
+import (
+	"galapb/chess2022/pkg/evaluation"
+	"math"
+	"strings"
+	"testing"
+)
+
+const POSITIONS string = "" +
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 [0.5]" + "\n" +
+	"4k3/8/8/8/8/8/4P3/4K2Q w - - c9 \"1-0\";" + "\n" +
+	"# comment" + "\n" +
+	"4k2q/4p3/8/8/8/8/8/4K3 b - - 0 40 0-1"
+
+func TestLoadPositions(t *testing.T) {
+	positions, err := LoadPositions(strings.NewReader(POSITIONS))
+	if err != nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "nil-error", err)
+	}
+
+	expected := []float64{0.5, 1.0, 0.0}
+	if len(positions) != len(expected) {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", len(expected), len(positions))
+	}
+
+	for i, position := range positions {
+		if position.Result != expected[i] {
+			t.Fatalf("\nExpected: \n%f\nActual: \n%f", expected[i], position.Result)
+		}
+	}
+}
+
+func TestGradientDescentLowersError(t *testing.T) {
+	positions, _ := LoadPositions(strings.NewReader(POSITIONS))
+	tuner, err := New(positions, evaluation.DefaultWeights(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initialError := tuner.Error()
+	finalError := tuner.GradientDescent(1.0, 5, nil)
+
+	if finalError >= initialError {
+		t.Fatalf("\nExpected: \n< %f\nActual: \n%f", initialError, finalError)
+	}
+}
+
+func TestErrorWithMoreWorkersThanPositions(t *testing.T) {
+	positions, _ := LoadPositions(strings.NewReader(POSITIONS))
+
+	single, err := New(positions, evaluation.DefaultWeights(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{2, 3, 5, 16} {
+		tuner, err := New(positions, evaluation.DefaultWeights(), workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e := tuner.Error(); math.Abs(e-single.Error()) > 1e-12 {
+			t.Fatalf("\nExpected: \n%f\nActual: \n%f", single.Error(), e)
+		}
+	}
+
+	if _, err := New(nil, evaluation.DefaultWeights(), 2); err == nil {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "error for an empty set", "nil-error")
+	}
+}
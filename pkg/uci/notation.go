@@ -0,0 +1,72 @@
+package uci
+
+import (
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"strings"
+)
+
+/**
+Returns the move in the long algebraic notation of UCI, like e2e4 or e7e8q
+*/
+func FormatMove(move b.Move) string {
+	if move == nil || move.IsEmpty() {
+		return "0000"
+	}
+
+	s := strings.ToLower(move.GetSrcSquare().GetName() + move.GetDstSquare().GetName())
+	if promotion := move.GetPromotionPieceType(); promotion != nil {
+		s += strings.ToLower(getPieceLetter(*promotion))
+	}
+
+	return s
+}
+
+/**
+Parses a move in the long algebraic notation of UCI, and checks that it is valid on the board
+*/
+func ParseMove(board b.Board, s string) (b.Move, error) {
+	if len(s) != 4 && len(s) != 5 {
+		return nil, fmt.Errorf("invalid move: %s", s)
+	}
+
+	src, ok := b.GetSquareFromStringNotExistsOkay(strings.ToUpper(s[0:2]))
+	if !ok {
+		return nil, fmt.Errorf("invalid square in move: %s", s)
+	}
+
+	dst, ok := b.GetSquareFromStringNotExistsOkay(strings.ToUpper(s[2:4]))
+	if !ok {
+		return nil, fmt.Errorf("invalid square in move: %s", s)
+	}
+
+	builder := b.NewMove(src, dst)
+	if len(s) == 5 {
+		promotion, err := b.NewPieceTypeFromString(strings.ToUpper(s[4:]))
+		if err != nil || !promotion.IsValidPromotionPiece() {
+			return nil, fmt.Errorf("invalid promotion in move: %s", s)
+		}
+		builder = builder.PromotionPieceType(promotion)
+	}
+
+	move := builder.Build()
+	if err := board.IsValidMove(move); err != nil {
+		return nil, fmt.Errorf("illegal move %s: %s", s, err)
+	}
+
+	return move, nil
+}
+
+func getPieceLetter(pt b.PieceType) string {
+	switch pt {
+	case b.QUEEN:
+		return "Q"
+	case b.ROOK:
+		return "R"
+	case b.BISHOP:
+		return "B"
+	case b.KNIGHT:
+		return "N"
+	}
+	return ""
+}
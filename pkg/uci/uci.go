@@ -0,0 +1,450 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/evaluation"
+	"galapb/chess2022/pkg/players/minimax_player"
+	"galapb/chess2022/pkg/players/player"
+	"galapb/chess2022/pkg/time_control"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ENGINE_NAME   string = "Chess2022"
+	ENGINE_AUTHOR string = "the Chess2022 authors"
+
+	MAX_THREADS  int = 64
+	MAX_MULTI_PV int = 64
+)
+
+/**
+Plays and analyses with the minimax player through the Universal Chess Interface, reading commands from a GUI and
+writing responses to it
+*/
+type Engine struct {
+	out      io.Writer
+	outMutex sync.Mutex
+
+	player *minimax_player.MiniMaxPlayer
+	board  b.Board
+
+	// options set by the GUI
+	threads int
+	multiPV int
+
+	// the player is weakened to the skill level, or to the rating if the strength is limited
+	skillLevel    int
+	limitStrength bool
+	elo           int
+
+	// weights of the evaluation, replaced by the ones of the EvalFile option
+	weights *evaluation.Weights
+
+	// the search running in the background, if any
+	current *search
+}
+
+type search struct {
+	limits minimax_player.Limits
+
+	// searches started with "go infinite" or "go ponder" only report their best move once released, with true, or
+	// quietly end when released with false
+	waitForRelease bool
+	release        chan bool
+
+	// limits of the search to run on a ponder hit, if the search is pondering
+	ponderLimits *minimax_player.Limits
+
+	// closed once the search has ended
+	done chan bool
+}
+
+func NewEngine(out io.Writer) *Engine {
+	e := &Engine{
+		out:        out,
+		board:      b.Standard(),
+		threads:    1,
+		multiPV:    1,
+		skillLevel: minimax_player.MAX_SKILL_LEVEL,
+		elo:        minimax_player.MAX_ELO,
+		weights:    evaluation.DefaultWeights(),
+	}
+	e.newPlayer()
+	return e
+}
+
+/**
+Makes the player evaluate positions with the given weights, such as the ones written by cmd/tune
+*/
+func (e *Engine) SetWeights(weights *evaluation.Weights) {
+	e.weights = weights
+	e.newPlayer()
+}
+
+func (e *Engine) newPlayer() {
+	e.player = minimax_player.NewWithEvaluator(evaluation.NewPositionalEvaluator(e.weights))
+	e.player.SetThreads(e.threads)
+	e.setStrength()
+	e.player.SetInfoHandler(func(info player.SearchInfo) {
+		e.printf("info %s", formatInfo(info))
+	})
+}
+
+/**
+Reads commands until "quit" or the end of the input
+*/
+func (e *Engine) Run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "quit" {
+			break
+		}
+
+		if err := e.Handle(fields[0], fields[1:]); err != nil {
+			e.printf("info string %s", err)
+		}
+	}
+
+	e.stopSearch(true)
+	return scanner.Err()
+}
+
+/**
+Handles one command, unknown commands being ignored as the protocol requires
+*/
+func (e *Engine) Handle(command string, args []string) error {
+	switch command {
+	case "uci":
+		e.printf("id name %s", ENGINE_NAME)
+		e.printf("id author %s", ENGINE_AUTHOR)
+		e.printf("option name Threads type spin default 1 min 1 max %d", MAX_THREADS)
+		e.printf("option name MultiPV type spin default 1 min 1 max %d", MAX_MULTI_PV)
+		e.printf("option name Ponder type check default false")
+		e.printf("option name EvalFile type string default <empty>")
+		e.printf("option name Skill Level type spin default %d min %d max %d", minimax_player.MAX_SKILL_LEVEL,
+			minimax_player.MIN_SKILL_LEVEL, minimax_player.MAX_SKILL_LEVEL)
+		e.printf("option name UCI_LimitStrength type check default false")
+		e.printf("option name UCI_Elo type spin default %d min %d max %d", minimax_player.MAX_ELO, minimax_player.MIN_ELO,
+			minimax_player.MAX_ELO)
+		e.printf("uciok")
+	case "isready":
+		e.printf("readyok")
+	case "ucinewgame":
+		e.stopSearch(false)
+		e.newPlayer()
+		e.board = b.Standard()
+	case "setoption":
+		e.stopSearch(false)
+		return e.setOption(args)
+	case "position":
+		e.stopSearch(false)
+		return e.setPosition(args)
+	case "go":
+		e.stopSearch(false)
+		return e.startSearch(args)
+	case "stop":
+		e.stopSearch(true)
+	case "ponderhit":
+		return e.ponderHit()
+	}
+
+	return nil
+}
+
+func (e *Engine) setOption(args []string) error {
+	var name, value []string
+	var current *[]string
+
+	for _, arg := range args {
+		switch arg {
+		case "name":
+			current = &name
+		case "value":
+			current = &value
+		default:
+			if current != nil {
+				*current = append(*current, arg)
+			}
+		}
+	}
+
+	switch strings.ToLower(strings.Join(name, " ")) {
+	case "threads":
+		threads, err := parseSpin(value, 1, MAX_THREADS)
+		if err != nil {
+			return err
+		}
+		e.threads = threads
+		e.player.SetThreads(threads)
+	case "multipv":
+		multiPV, err := parseSpin(value, 1, MAX_MULTI_PV)
+		if err != nil {
+			return err
+		}
+		e.multiPV = multiPV
+	case "ponder":
+		// the GUI decides when to ponder, with "go ponder"
+	case "evalfile":
+		path := strings.Join(value, " ")
+		if path == "" || path == "<empty>" {
+			e.SetWeights(evaluation.DefaultWeights())
+			return nil
+		}
+
+		weights, err := evaluation.LoadWeightsFile(path)
+		if err != nil {
+			return err
+		}
+		e.SetWeights(weights)
+	case "skill level":
+		level, err := parseSpin(value, minimax_player.MIN_SKILL_LEVEL, minimax_player.MAX_SKILL_LEVEL)
+		if err != nil {
+			return err
+		}
+		e.skillLevel = level
+		e.setStrength()
+	case "uci_limitstrength":
+		limitStrength, err := parseCheck(value)
+		if err != nil {
+			return err
+		}
+		e.limitStrength = limitStrength
+		e.setStrength()
+	case "uci_elo":
+		elo, err := parseSpin(value, minimax_player.MIN_ELO, minimax_player.MAX_ELO)
+		if err != nil {
+			return err
+		}
+		e.elo = elo
+		e.setStrength()
+	default:
+		return fmt.Errorf("unknown option: %s", strings.Join(name, " "))
+	}
+
+	return nil
+}
+
+/**
+Weakens the player to the rating if the strength is limited, as the protocol requires, or else to the skill level
+*/
+func (e *Engine) setStrength() {
+	if e.limitStrength {
+		e.player.SetElo(e.elo)
+	} else {
+		e.player.SetSkillLevel(e.skillLevel)
+	}
+}
+
+func parseCheck(value []string) (bool, error) {
+	if len(value) != 1 || (value[0] != "true" && value[0] != "false") {
+		return false, fmt.Errorf("value must be true or false: %s", strings.Join(value, " "))
+	}
+	return value[0] == "true", nil
+}
+
+func parseSpin(value []string, min, max int) (int, error) {
+	if len(value) != 1 {
+		return 0, fmt.Errorf("invalid value: %s", strings.Join(value, " "))
+	}
+
+	v, err := strconv.Atoi(value[0])
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("value must be between %d and %d: %s", min, max, value[0])
+	}
+	return v, nil
+}
+
+/**
+Sets up the board from "position startpos [moves ...]" or "position fen <fen> [moves ...]"
+*/
+func (e *Engine) setPosition(args []string) error {
+	var board b.Board
+	var err error
+
+	if len(args) == 0 {
+		return fmt.Errorf("missing position")
+	}
+
+	i := 1
+	switch args[0] {
+	case "startpos":
+		board = b.Standard()
+	case "fen":
+		for i < len(args) && args[i] != "moves" {
+			i++
+		}
+		if board, err = b.FromFEN(strings.Join(args[1:i], " ")); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid position: %s", args[0])
+	}
+
+	if i < len(args) && args[i] == "moves" {
+		for _, s := range args[i+1:] {
+			move, err := ParseMove(board, s)
+			if err != nil {
+				return err
+			}
+			board.Make(move)
+		}
+	}
+
+	e.board = board
+	return nil
+}
+
+/**
+Starts searching the current position in the background, with the limits of the "go" command
+*/
+func (e *Engine) startSearch(args []string) error {
+	var clock time_control.Clock
+	var timed, ponder bool
+
+	s := &search{release: make(chan bool, 1), done: make(chan bool)}
+	s.limits.MultiPV = e.multiPV
+	s.limits.Stop = make(chan bool)
+
+	for i := 0; i < len(args); i++ {
+		var value int
+		if i+1 < len(args) {
+			value, _ = strconv.Atoi(args[i+1])
+		}
+
+		switch args[i] {
+		case "infinite":
+			s.waitForRelease = true
+			continue
+		case "ponder":
+			ponder = true
+			continue
+		case "depth":
+			s.limits.Depth = value
+		case "movetime":
+			s.limits.MoveTime = time.Duration(value) * time.Millisecond
+		case "movestogo":
+			clock.MovesToGo = value
+		case "wtime", "btime":
+			if (args[i] == "wtime") == (e.board.GetTurn() == b.WHITE) {
+				clock.Remaining, timed = time.Duration(value)*time.Millisecond, true
+			}
+		case "winc", "binc":
+			if (args[i] == "winc") == (e.board.GetTurn() == b.WHITE) {
+				clock.Increment = time.Duration(value) * time.Millisecond
+			}
+		default:
+			continue // unsupported limits, like nodes or mate, are ignored
+		}
+		i++
+	}
+
+	if timed {
+		s.limits.Clock = &clock
+	}
+
+	if ponder {
+		// the time limits only apply once the opponent plays the expected move, until then the search is unbounded
+		limits := s.limits
+		s.ponderLimits = &limits
+		s.limits.Clock, s.limits.MoveTime = nil, 0
+		s.waitForRelease = true
+	}
+
+	e.current = s
+	go e.run(s, e.board.Copy())
+	return nil
+}
+
+func (e *Engine) run(s *search, board b.Board) {
+	defer close(s.done)
+
+	lines := e.player.Analyze(board, s.limits)
+
+	if s.waitForRelease && !<-s.release {
+		return
+	}
+
+	switch {
+	case len(lines) == 0:
+		e.printf("bestmove 0000")
+	case len(lines[0].Moves) > 1:
+		e.printf("bestmove %s ponder %s", FormatMove(lines[0].Moves[0]), FormatMove(lines[0].Moves[1]))
+	default:
+		e.printf("bestmove %s", FormatMove(lines[0].Moves[0]))
+	}
+}
+
+/**
+Stops the running search, if any, and waits for it to end. Its best move is reported if report is true
+*/
+func (e *Engine) stopSearch(report bool) {
+	s := e.current
+	if s == nil {
+		return
+	}
+
+	e.current = nil
+	close(s.limits.Stop)
+	s.release <- report
+	<-s.done
+}
+
+/**
+The opponent played the expected move: pondering ends quietly, and the position is searched again with the time
+limits of the "go ponder" command, quickly getting back to the depth pondering reached through the transposition table
+*/
+func (e *Engine) ponderHit() error {
+	s := e.current
+	if s == nil || s.ponderLimits == nil {
+		return fmt.Errorf("not pondering")
+	}
+
+	e.stopSearch(false)
+
+	next := &search{limits: *s.ponderLimits, release: make(chan bool, 1), done: make(chan bool)}
+	next.limits.Stop = make(chan bool)
+
+	e.current = next
+	go e.run(next, e.board.Copy())
+	return nil
+}
+
+/**
+Returns the info of a search in the format of an "info" command
+*/
+func formatInfo(info player.SearchInfo) string {
+	score := fmt.Sprintf("cp %d", info.Score)
+	if info.Mate != 0 {
+		score = fmt.Sprintf("mate %d", info.Mate)
+	}
+
+	moves := make([]string, len(info.PV))
+	for i, move := range info.PV {
+		moves[i] = FormatMove(move)
+	}
+
+	return fmt.Sprintf(
+		"depth %d seldepth %d multipv %d score %s nodes %d nps %d hashfull %d time %d pv %s",
+		info.Depth, info.SelDepth, info.MultiPV, score, info.Nodes, info.GetNPS(), info.HashFull, info.Time.Milliseconds(),
+		strings.Join(moves, " "),
+	)
+}
+
+func (e *Engine) printf(format string, args ...interface{}) {
+	e.outMutex.Lock()
+	defer e.outMutex.Unlock()
+
+	fmt.Fprintf(e.out, format+"\n", args...)
+}
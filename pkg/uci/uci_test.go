@@ -0,0 +1,155 @@
+package uci
+
+import (
+	"bytes"
+	b "galapb/chess2022/pkg/board"
+	"galapb/chess2022/pkg/evaluation"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseMoveRoundTrip(t *testing.T) {
+	board, err := b.FromFEN("4k3/1P6/8/8/8/8/8/R3K3 w Q - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"e1c1", "b7b8q", "b7b8n", "a1a8"} {
+		move, err := ParseMove(board, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual := FormatMove(move); actual != s {
+			t.Fatalf("\nExpected: \n%s\nActual: \n%s", s, actual)
+		}
+	}
+
+	if _, err := ParseMove(board, "e1e3"); err == nil {
+		t.Fatalf("illegal move e1e3 was parsed")
+	}
+}
+
+func TestMultiPV(t *testing.T) {
+	var out bytes.Buffer
+	e := NewEngine(&out)
+
+	for _, command := range []string{
+		"setoption name MultiPV value 3",
+		"position fen 7k/8/6K1/8/8/8/8/R7 b - - 0 1 moves h8g8 a1a7 g8h8",
+		"go depth 2",
+	} {
+		fields := strings.Fields(command)
+		if err := e.Handle(fields[0], fields[1:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-e.current.done
+
+	// every iteration reports the three best moves, the best of which mates at once
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("\nExpected: \n%d lines\nActual: \n%s", 7, out.String())
+	}
+
+	for _, expected := range []string{"info depth 2 ", "multipv 1 score mate 1 ", "pv a7a8"} {
+		if !strings.Contains(lines[3], expected) {
+			t.Fatalf("\nExpected: \n%s\nActual: \n%s", expected, lines[3])
+		}
+	}
+
+	if !strings.Contains(lines[5], "multipv 3") || lines[6] != "bestmove a7a8" {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%s", "bestmove a7a8", out.String())
+	}
+}
+
+func TestEvalFileOption(t *testing.T) {
+	weights := evaluation.DefaultWeights()
+	weights.MaterialMg.Queen = 1234
+
+	data, err := yaml.Marshal(weights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "weights.yml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewEngine(&bytes.Buffer{})
+	if err := e.Handle("setoption", []string{"name", "EvalFile", "value", path}); err != nil {
+		t.Fatal(err)
+	}
+	if e.weights.MaterialMg.Queen != 1234 {
+		t.Fatalf("\nExpected: \n%f\nActual: \n%f", 1234.0, e.weights.MaterialMg.Queen)
+	}
+
+	if err := e.Handle("setoption", []string{"name", "EvalFile", "value", filepath.Join(t.TempDir(), "missing.yml")}); err == nil {
+		t.Fatalf("missing weights file was loaded")
+	}
+
+	if err := e.Handle("setoption", []string{"name", "EvalFile", "value", "<empty>"}); err != nil {
+		t.Fatal(err)
+	}
+	if e.weights.MaterialMg.Queen == 1234 {
+		t.Fatalf("\nExpected: \n%s\nActual: \n%f", "default queen value", e.weights.MaterialMg.Queen)
+	}
+}
+
+/**
+Searches with the commands and returns the deepest iteration reported
+*/
+func getSearchDepth(t *testing.T, e *Engine, out *bytes.Buffer, commands ...string) int {
+	out.Reset()
+	for _, command := range commands {
+		fields := strings.Fields(command)
+		if err := e.Handle(fields[0], fields[1:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-e.current.done
+
+	depth := 0
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 2 && fields[0] == "info" && fields[1] == "depth" {
+			depth, _ = strconv.Atoi(fields[2])
+		}
+	}
+	return depth
+}
+
+func TestStrengthOptions(t *testing.T) {
+	var out bytes.Buffer
+	e := NewEngine(&out)
+
+	// the weakest level searches a single ply, even after a new game
+	depth := getSearchDepth(t, e, &out, "setoption name Skill Level value 0", "ucinewgame", "go depth 3")
+	if depth != 1 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 1, depth)
+	}
+
+	// the rating replaces the skill level while the strength is limited
+	depth = getSearchDepth(t, e, &out, "setoption name UCI_Elo value 2000", "setoption name UCI_LimitStrength value true",
+		"go depth 3")
+	if depth != 3 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 3, depth)
+	}
+
+	depth = getSearchDepth(t, e, &out, "setoption name UCI_LimitStrength value false", "go depth 3")
+	if depth != 1 {
+		t.Fatalf("\nExpected: \n%d\nActual: \n%d", 1, depth)
+	}
+
+	invalid := []string{"name Skill Level value 21", "name UCI_LimitStrength value yes", "name UCI_Elo value 100"}
+	for _, option := range invalid {
+		if err := e.Handle("setoption", strings.Fields(option)); err == nil {
+			t.Fatalf("invalid option was set: %s", option)
+		}
+	}
+}